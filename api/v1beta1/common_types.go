@@ -521,7 +521,7 @@ type ResourceReport struct {
 	Resource Resource `json:"resource"`
 
 	// Action represent the type of operation on the Kubernetes resource.
-	// +kubebuilder:validation:Enum=No Action;Create;Update;Delete;Conflict;Error
+	// +kubebuilder:validation:Enum=No Action;Create;Update;Recreate;Delete;Orphan;Conflict;Error
 	Action string `json:"action,omitempty"`
 
 	// Message is for any message that needs to added to better
@@ -537,7 +537,9 @@ const (
 	NoResourceAction       ResourceAction = "No Action"
 	CreateResourceAction   ResourceAction = "Create"
 	UpdateResourceAction   ResourceAction = "Update"
+	RecreateResourceAction ResourceAction = "Recreate"
 	DeleteResourceAction   ResourceAction = "Delete"
+	OrphanResourceAction   ResourceAction = "Orphan"
 	ConflictResourceAction ResourceAction = "Conflict"
 	ErrorResourceAction    ResourceAction = "Error"
 )
@@ -25,13 +25,39 @@ const (
 	ConfigurationBundleKind = "ConfigurationBundle"
 )
 
+// +kubebuilder:validation:Enum:=None;GzipBase64
+type BundleEncoding string
+
+const (
+	// BundleEncodingNone indicates each entry in Resources is a plain YAML or JSON resource
+	BundleEncodingNone = BundleEncoding("None")
+
+	// BundleEncodingGzipBase64 indicates Resources contains a single entry: the JSON list of
+	// all resources, gzip compressed and base64 encoded
+	BundleEncodingGzipBase64 = BundleEncoding("GzipBase64")
+)
+
 type ConfigurationBundleSpec struct {
 	// Resources contains all resources that need to be deployed.
-	// Content is either YAML or JSON
+	// Content is either YAML or JSON, unless Encoding says otherwise
 	// +listType=atomic
 	// +optional
 	Resources []string `json:"resources,omitempty"`
 
+	// Encoding indicates how Resources is encoded. When not set or set to None, each
+	// entry is a plain resource. When set to GzipBase64, Resources contains a single
+	// compressed entry. Use it for large content (for instance big helm charts) which would
+	// otherwise exceed the maximum object size accepted by the API server.
+	// +optional
+	Encoding BundleEncoding `json:"encoding,omitempty"`
+
+	// EncryptedResources lists the indexes, within the (decoded) Resources, of the entries
+	// which are encrypted with the public key registered by the agent in the target SveltosCluster.
+	// Only Secrets are encrypted.
+	// +listType=atomic
+	// +optional
+	EncryptedResources []int `json:"encryptedResources,omitempty"`
+
 	// NotTracked, when true, signifies that the resources managed by the
 	// ConfigurationBundles should not be tracked for conflicts
 	// with other configurations and will not be automatically removed when the
@@ -127,6 +153,14 @@ type ConfigurationBundleStatus struct {
 	// the ConfigurationBundle
 	// +optional
 	Hash []byte `json:"hash,omitempty"`
+
+	// ReferencedBy lists the requestors whose ConfigurationGroups reference this ConfigurationBundle.
+	// It is set only for shared ConfigurationBundles, whose content is stored once and referenced by
+	// the ConfigurationGroups of multiple clusters. A shared ConfigurationBundle is deleted when
+	// no requestor references it anymore.
+	// +listType=set
+	// +optional
+	ReferencedBy []string `json:"referencedBy,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -38,6 +38,22 @@ type ConfigurationItem struct {
 	Hash []byte `json:"hash,omitempty"`
 }
 
+// RequestorReference identifies the ConfigurationGroup created, for the same cluster,
+// by a given requestor
+type RequestorReference struct {
+	// RequestorKind is the kind of the component which created the ConfigurationGroup
+	// (e.g., ClusterSummary)
+	RequestorKind string `json:"requestorKind"`
+
+	// RequestorName is the name of the component which created the ConfigurationGroup
+	RequestorName string `json:"requestorName"`
+
+	// RequestorFeature is the feature within the component which created the ConfigurationGroup
+	// (e.g., helm, kustomize, policyrefs)
+	// +optional
+	RequestorFeature string `json:"requestorFeature,omitempty"`
+}
+
 // +kubebuilder:validation:Enum:=Deploy;Remove
 type Action string
 
@@ -263,6 +279,47 @@ type ConfigurationGroupSpec struct {
 	// The ServiceAccount must exist in the managed cluster.
 	// +optional
 	ServiceAccountNamespace string `json:"serviceAccountNamespace,omitempty"`
+
+	// DependsOn lists the ConfigurationGroups, for the same cluster, whose content must be
+	// processed before the content of this ConfigurationGroup. For instance, a ConfigurationGroup
+	// deploying CustomResources depends on the ConfigurationGroup deploying the corresponding CRDs.
+	// +listType=atomic
+	// +optional
+	DependsOn []RequestorReference `json:"dependsOn,omitempty"`
+
+	// Signature is the Ed25519 signature, produced by the management cluster components,
+	// of this spec (Signature excluded) and of the ordered hashes of all referenced
+	// ConfigurationBundles. When set, agent can verify content was produced by Sveltos and
+	// not modified afterwards.
+	// +optional
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// ConfigurationItemStatus reports the outcome of deploying the content referenced by
+// a single ConfigurationItem
+type ConfigurationItemStatus struct {
+	// ContentRef references the content this status refers to. It matches the ContentRef
+	// of one of the ConfigurationItems.
+	ContentRef corev1.ObjectReference `json:"contentRef"`
+
+	// Hash is the hash of the content this status refers to
+	// +optional
+	Hash []byte `json:"hash,omitempty"`
+
+	// Status represents the state of the content in the workload cluster
+	// +optional
+	DeploymentStatus *FeatureStatus `json:"status,omitempty"`
+
+	// FailureMessage provides more information about the error.
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// ResourceReports contains the outcome for each resource contained in the
+	// referenced content. When ConfigurationGroup is in DryRun mode, reports describe
+	// what would happen and Message contains the diff, if any, the change would cause.
+	// +listType=atomic
+	// +optional
+	ResourceReports []ResourceReport `json:"resourceReports,omitempty"`
 }
 
 type ConfigurationGroupStatus struct {
@@ -284,6 +341,19 @@ type ConfigurationGroupStatus struct {
 	// +optional
 	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
 
+	// ItemStatuses contains, for each ConfigurationItem, the deployment outcome
+	// reported by the agent
+	// +listType=atomic
+	// +optional
+	ItemStatuses []ConfigurationItemStatus `json:"itemStatuses,omitempty"`
+
+	// StaleResourceReports contains, in DryRun mode only, the reports for resources
+	// previously deployed because of this ConfigurationGroup and not referenced anymore.
+	// Action is either Delete or, when LeavePolicies is set, Orphan.
+	// +listType=atomic
+	// +optional
+	StaleResourceReports []ResourceReport `json:"staleResourceReports,omitempty"`
+
 	// ObservedGeneration reflects the generation of the most recently observed ConfigurationGroup.
 	// When this value matches the ConfigurationGroup's metadata.generation, it indicates that the
 	// status reflects the latest desired specification. If observedGeneration is less than generation,
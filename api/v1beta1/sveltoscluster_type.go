@@ -107,13 +107,15 @@ type TokenRequestRenewalOption struct {
 }
 
 // WorkloadIdentityProvider identifies the cloud provider for workload identity.
-// +kubebuilder:validation:Enum=AWS;GCP;Azure
+// +kubebuilder:validation:Enum=AWS;GCP;Azure;OIDC;Exec
 type WorkloadIdentityProvider string
 
 const (
 	WorkloadIdentityProviderAWS   WorkloadIdentityProvider = "AWS"
 	WorkloadIdentityProviderGCP   WorkloadIdentityProvider = "GCP"
 	WorkloadIdentityProviderAzure WorkloadIdentityProvider = "Azure"
+	WorkloadIdentityProviderOIDC  WorkloadIdentityProvider = "OIDC"
+	WorkloadIdentityProviderExec  WorkloadIdentityProvider = "Exec"
 )
 
 // AWSWorkloadIdentityConfig holds AWS-specific workload identity configuration.
@@ -178,12 +180,105 @@ type AzureWorkloadIdentityConfig struct {
 	ClusterName string `json:"clusterName,omitempty"`
 }
 
+// OIDCWorkloadIdentityConfig holds configuration for a generic OIDC provider (e.g. Keycloak, Dex).
+// A service account token projected in the Sveltos pod, with the OIDC provider as audience, is
+// exchanged at the token endpoint, using OAuth 2.0 Token Exchange (RFC 8693), for a token accepted
+// by the managed cluster API server. The path of the projected token is configured by the Sveltos
+// administrator for the whole component, not per SveltosCluster.
+type OIDCWorkloadIdentityConfig struct {
+	// TokenEndpoint is the URL of the OIDC provider token endpoint
+	// (e.g. https://keycloak.example.com/realms/sveltos/protocol/openid-connect/token).
+	// It must be one of the token endpoints allowed by the Sveltos administrator.
+	// +kubebuilder:validation:MinLength=1
+	TokenEndpoint string `json:"tokenEndpoint"`
+
+	// ClientID is the OAuth client Sveltos authenticates as when calling the token endpoint.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+
+	// ClientSecretRef references a key in a Secret, in the SveltosCluster namespace, containing
+	// the OAuth client secret. If not set, Sveltos authenticates as a public client.
+	// +optional
+	ClientSecretRef *corev1.SecretKeySelector `json:"clientSecretRef,omitempty"`
+
+	// Audience is the logical name of the managed cluster the requested token is for.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+
+	// Scopes are the scopes requested for the issued token.
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+
+	// SubjectTokenType is the type of the token presented to the token endpoint.
+	// Defaults to urn:ietf:params:oauth:token-type:jwt.
+	// +optional
+	SubjectTokenType string `json:"subjectTokenType,omitempty"`
+
+	// RequestedTokenType is the type of the token requested to the token endpoint.
+	// Defaults to urn:ietf:params:oauth:token-type:access_token.
+	// +optional
+	RequestedTokenType string `json:"requestedTokenType,omitempty"`
+
+	// TokenEndpointCASecretRef references a Secret in the management cluster containing the
+	// CA certificate of the token endpoint under the key "ca.crt".
+	// If not set, the system certificate pool is used.
+	// +optional
+	TokenEndpointCASecretRef *corev1.LocalObjectReference `json:"tokenEndpointCASecretRef,omitempty"`
+}
+
+// ExecEnvVar is an environment variable set when running an exec credential plugin.
+type ExecEnvVar struct {
+	// Name of the environment variable.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Value of the environment variable.
+	Value string `json:"value"`
+}
+
+// ExecWorkloadIdentityConfig holds configuration for an exec credential plugin
+// (e.g. a vendor CLI or kubelogin) run by Sveltos using the client.authentication.k8s.io
+// exec credential protocol. Command, Args and Env names must be allowed by the Sveltos
+// controller configuration.
+type ExecWorkloadIdentityConfig struct {
+	// Command is the plugin executable. It must match one of the commands allowed
+	// in the Sveltos controller configuration.
+	// +kubebuilder:validation:MinLength=1
+	Command string `json:"command"`
+
+	// Args are the arguments passed to the plugin. They must match exactly, and in order,
+	// the arguments allowed for Command in the Sveltos controller configuration.
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env are the environment variables set when running the plugin. Their names must be
+	// allowed for Command in the Sveltos controller configuration. The plugin does not
+	// inherit the Sveltos environment, other than PATH and HOME.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Env []ExecEnvVar `json:"env,omitempty"`
+
+	// APIVersion is the version of the client.authentication.k8s.io ExecCredential
+	// the plugin understands. Only client.authentication.k8s.io/v1 is supported.
+	// +kubebuilder:validation:Enum=client.authentication.k8s.io/v1
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// ProvideClusterInfo, when true, passes the managed cluster endpoint and CA to the
+	// plugin via the KUBERNETES_EXEC_INFO environment variable.
+	// +optional
+	ProvideClusterInfo bool `json:"provideClusterInfo,omitempty"`
+}
+
 // WorkloadIdentityConfig specifies how Sveltos authenticates to the managed
 // cluster using the cloud provider's workload identity mechanism instead of a
 // static kubeconfig Secret.
 // +kubebuilder:validation:XValidation:rule="(self.provider == 'AWS') == has(self.aws)",message="aws must be set if and only if provider is AWS"
 // +kubebuilder:validation:XValidation:rule="(self.provider == 'GCP') == has(self.gcp)",message="gcp must be set if and only if provider is GCP"
 // +kubebuilder:validation:XValidation:rule="(self.provider == 'Azure') == has(self.azure)",message="azure must be set if and only if provider is Azure"
+// +kubebuilder:validation:XValidation:rule="(self.provider == 'OIDC') == has(self.oidc)",message="oidc must be set if and only if provider is OIDC"
+// +kubebuilder:validation:XValidation:rule="(self.provider == 'Exec') == has(self.exec)",message="exec must be set if and only if provider is Exec"
 type WorkloadIdentityConfig struct {
 	// Provider is the cloud provider implementing the workload identity mechanism.
 	// +kubebuilder:validation:Required
@@ -213,6 +308,16 @@ type WorkloadIdentityConfig struct {
 	// Required when Provider is Azure.
 	// +optional
 	Azure *AzureWorkloadIdentityConfig `json:"azure,omitempty"`
+
+	// OIDC contains configuration specific to a generic OIDC provider.
+	// Required when Provider is OIDC.
+	// +optional
+	OIDC *OIDCWorkloadIdentityConfig `json:"oidc,omitempty"`
+
+	// Exec contains configuration specific to an exec credential plugin.
+	// Required when Provider is Exec.
+	// +optional
+	Exec *ExecWorkloadIdentityConfig `json:"exec,omitempty"`
 }
 
 // SveltosClusterSpec defines the desired state of SveltosCluster
@@ -273,6 +378,12 @@ type SveltosClusterSpec struct {
 	// +optional
 	PullMode bool `json:"pullMode,omitempty"`
 
+	// AgentHeartbeatMaxAge is the maximum age of Status.AgentLastReportTime before the
+	// Sveltos agent in the managed cluster is considered late. If not set, a default is used.
+	// This field is used exclusively when Sveltos operates in pull mode.
+	// +optional
+	AgentHeartbeatMaxAge *metav1.Duration `json:"agentHeartbeatMaxAge,omitempty"`
+
 	// WorkloadIdentity configures authentication to the managed cluster via the
 	// cloud provider's workload identity mechanism. When set, Sveltos does not
 	// read a kubeconfig Secret; instead it obtains short-lived credentials from
@@ -314,6 +425,13 @@ type SveltosClusterStatus struct {
 	// +optional
 	AgentLastReportTime *metav1.Time `json:"agentLastReportTime,omitempty"`
 
+	// AgentEncryptionKey is the X25519 public key registered by the Sveltos agent in the
+	// managed cluster. When set, Secrets sent to this cluster in pull mode can be encrypted
+	// so that only the agent, holding the matching private key, can read them.
+	// This field is used exclusively when Sveltos operates in pull mode.
+	// +optional
+	AgentEncryptionKey []byte `json:"agentEncryptionKey,omitempty"`
+
 	// Information when next unpause cluster is scheduled
 	// +optional
 	NextUnpause *metav1.Time `json:"nextUnpause,omitempty"`
@@ -333,6 +451,12 @@ type SveltosClusterStatus struct {
 	// +kubebuilder:default:=0
 	// +optional
 	ConnectionFailures int `json:"connectionFailures,omitempty"`
+
+	// Conditions contains the latest observations of the SveltosCluster state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
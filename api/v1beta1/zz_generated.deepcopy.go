@@ -791,6 +791,11 @@ func (in *ConfigurationBundleSpec) DeepCopyInto(out *ConfigurationBundleSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.EncryptedResources != nil {
+		in, out := &in.EncryptedResources, &out.EncryptedResources
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
 	if in.Timeout != nil {
 		in, out := &in.Timeout, &out.Timeout
 		*out = new(metav1.Duration)
@@ -816,6 +821,11 @@ func (in *ConfigurationBundleStatus) DeepCopyInto(out *ConfigurationBundleStatus
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.ReferencedBy != nil {
+		in, out := &in.ReferencedBy, &out.ReferencedBy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationBundleStatus.
@@ -980,6 +990,16 @@ func (in *ConfigurationGroupSpec) DeepCopyInto(out *ConfigurationGroupSpec) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]RequestorReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationGroupSpec.
@@ -1014,6 +1034,20 @@ func (in *ConfigurationGroupStatus) DeepCopyInto(out *ConfigurationGroupStatus)
 		in, out := &in.LastAppliedTime, &out.LastAppliedTime
 		*out = (*in).DeepCopy()
 	}
+	if in.ItemStatuses != nil {
+		in, out := &in.ItemStatuses, &out.ItemStatuses
+		*out = make([]ConfigurationItemStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StaleResourceReports != nil {
+		in, out := &in.StaleResourceReports, &out.StaleResourceReports
+		*out = make([]ResourceReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.ObservedRequestorHash != nil {
 		in, out := &in.ObservedRequestorHash, &out.ObservedRequestorHash
 		*out = make([]byte, len(*in))
@@ -1056,6 +1090,44 @@ func (in *ConfigurationItem) DeepCopy() *ConfigurationItem {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigurationItemStatus) DeepCopyInto(out *ConfigurationItemStatus) {
+	*out = *in
+	out.ContentRef = in.ContentRef
+	if in.Hash != nil {
+		in, out := &in.Hash, &out.Hash
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeploymentStatus != nil {
+		in, out := &in.DeploymentStatus, &out.DeploymentStatus
+		*out = new(FeatureStatus)
+		**out = **in
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+	if in.ResourceReports != nil {
+		in, out := &in.ResourceReports, &out.ResourceReports
+		*out = make([]ResourceReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationItemStatus.
+func (in *ConfigurationItemStatus) DeepCopy() *ConfigurationItemStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationItemStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DebuggingConfiguration) DeepCopyInto(out *DebuggingConfiguration) {
 	*out = *in
@@ -1421,6 +1493,46 @@ func (in *EventSourceSpec) DeepCopy() *EventSourceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecEnvVar) DeepCopyInto(out *ExecEnvVar) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecEnvVar.
+func (in *ExecEnvVar) DeepCopy() *ExecEnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecEnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecWorkloadIdentityConfig) DeepCopyInto(out *ExecWorkloadIdentityConfig) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]ExecEnvVar, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecWorkloadIdentityConfig.
+func (in *ExecWorkloadIdentityConfig) DeepCopy() *ExecWorkloadIdentityConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecWorkloadIdentityConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeatureDeploymentInfo) DeepCopyInto(out *FeatureDeploymentInfo) {
 	*out = *in
@@ -2167,6 +2279,36 @@ func (in *NotificationSummary) DeepCopy() *NotificationSummary {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCWorkloadIdentityConfig) DeepCopyInto(out *OIDCWorkloadIdentityConfig) {
+	*out = *in
+	if in.ClientSecretRef != nil {
+		in, out := &in.ClientSecretRef, &out.ClientSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TokenEndpointCASecretRef != nil {
+		in, out := &in.TokenEndpointCASecretRef, &out.TokenEndpointCASecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCWorkloadIdentityConfig.
+func (in *OIDCWorkloadIdentityConfig) DeepCopy() *OIDCWorkloadIdentityConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCWorkloadIdentityConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Patch) DeepCopyInto(out *Patch) {
 	*out = *in
@@ -2409,6 +2551,21 @@ func (in *ReloaderSpec) DeepCopy() *ReloaderSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequestorReference) DeepCopyInto(out *RequestorReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestorReference.
+func (in *RequestorReference) DeepCopy() *RequestorReference {
+	if in == nil {
+		return nil
+	}
+	out := new(RequestorReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resource) DeepCopyInto(out *Resource) {
 	*out = *in
@@ -3025,6 +3182,11 @@ func (in *SveltosClusterSpec) DeepCopyInto(out *SveltosClusterSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AgentHeartbeatMaxAge != nil {
+		in, out := &in.AgentHeartbeatMaxAge, &out.AgentHeartbeatMaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.WorkloadIdentity != nil {
 		in, out := &in.WorkloadIdentity, &out.WorkloadIdentity
 		*out = new(WorkloadIdentityConfig)
@@ -3054,6 +3216,11 @@ func (in *SveltosClusterStatus) DeepCopyInto(out *SveltosClusterStatus) {
 		in, out := &in.AgentLastReportTime, &out.AgentLastReportTime
 		*out = (*in).DeepCopy()
 	}
+	if in.AgentEncryptionKey != nil {
+		in, out := &in.AgentEncryptionKey, &out.AgentEncryptionKey
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
 	if in.NextUnpause != nil {
 		in, out := &in.NextUnpause, &out.NextUnpause
 		*out = (*in).DeepCopy()
@@ -3067,6 +3234,13 @@ func (in *SveltosClusterStatus) DeepCopyInto(out *SveltosClusterStatus) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SveltosClusterStatus.
@@ -3397,6 +3571,16 @@ func (in *WorkloadIdentityConfig) DeepCopyInto(out *WorkloadIdentityConfig) {
 		*out = new(AzureWorkloadIdentityConfig)
 		**out = **in
 	}
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(OIDCWorkloadIdentityConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(ExecWorkloadIdentityConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadIdentityConfig.
@@ -19,7 +19,10 @@ package clustercache
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -32,6 +35,7 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	"sigs.k8s.io/cluster-api/util/secret"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
@@ -39,11 +43,34 @@ import (
 	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
 )
 
+const (
+	// DefaultMaxClusters is the default maximum number of clusters kept in the cache.
+	// Zero means no size limit.
+	DefaultMaxClusters = 0
+
+	// DefaultIdleTTL is the default amount of time a cluster can go without being
+	// accessed before its cached data is evicted. Zero disables idle eviction.
+	DefaultIdleTTL = time.Hour
+
+	// DefaultSweepInterval is the default interval at which idle clusters are evicted
+	// by the runnable registered with AddEvictionSweeper.
+	DefaultSweepInterval = time.Minute
+
+	secretKind = "Secret"
+)
+
 var (
 	managerInstance *clusterCache
 	lock            = &sync.Mutex{}
 )
 
+// cacheEntry tracks when a cluster was last accessed.
+// lastAccess (UnixNano) is updated atomically, so cache hits only need the read lock.
+type cacheEntry struct {
+	cluster    corev1.ObjectReference
+	lastAccess atomic.Int64
+}
+
 type clusterCache struct {
 	rwMux sync.RWMutex
 	// Keeps cache of rest.Config for existing clusters
@@ -58,6 +85,28 @@ type clusterCache struct {
 	// key: secret, value: set of clusters
 	// A secret can potentially contain kubeconfig for one or more clusters
 	secrets map[corev1.ObjectReference]*libsveltosset.Set
+
+	// key: cluster, value: when the cluster was last accessed
+	entries map[corev1.ObjectReference]*cacheEntry
+
+	// maxClusters is the maximum number of clusters cached. Zero means no limit.
+	maxClusters int
+	// idleTTL is the time after which a cluster not accessed is evicted. Zero means never.
+	idleTTL time.Duration
+
+	now func() time.Time
+
+	healthMux sync.Mutex
+	// key: cluster, value: connectivity health of the cluster
+	health map[corev1.ObjectReference]*ClusterHealth
+	// tracked contains the clusters a rest.Config recording health was returned for, and not
+	// deleted since. Calls to other clusters are not recorded.
+	tracked map[corev1.ObjectReference]bool
+	// failureThreshold is the number of consecutive failures opening the circuit breaker.
+	// Zero disables the circuit breaker.
+	failureThreshold int
+	// openDuration is how long the circuit breaker stays open before a probe is let through
+	openDuration time.Duration
 }
 
 // GetManager return manager instance
@@ -66,21 +115,59 @@ func GetManager() *clusterCache {
 		lock.Lock()
 		defer lock.Unlock()
 		if managerInstance == nil {
-			managerInstance = &clusterCache{
-				configs:               make(map[corev1.ObjectReference]*rest.Config),
-				clusters:              make(map[corev1.ObjectReference]*corev1.ObjectReference),
-				mappers:               make(map[corev1.ObjectReference]*restmapper.DeferredDiscoveryRESTMapper),
-				cachedDiscoveryClient: make(map[corev1.ObjectReference]discovery.CachedDiscoveryInterface),
-				secrets:               make(map[corev1.ObjectReference]*libsveltosset.Set),
-				rwMux:                 sync.RWMutex{},
-			}
+			managerInstance = newClusterCache()
 		}
 	}
 
 	return managerInstance
 }
 
-// RemoveCluster removes restConfig cached data for the cluster
+func newClusterCache() *clusterCache {
+	return &clusterCache{
+		configs:               make(map[corev1.ObjectReference]*rest.Config),
+		clusters:              make(map[corev1.ObjectReference]*corev1.ObjectReference),
+		mappers:               make(map[corev1.ObjectReference]*restmapper.DeferredDiscoveryRESTMapper),
+		cachedDiscoveryClient: make(map[corev1.ObjectReference]discovery.CachedDiscoveryInterface),
+		secrets:               make(map[corev1.ObjectReference]*libsveltosset.Set),
+		entries:               make(map[corev1.ObjectReference]*cacheEntry),
+		maxClusters:           DefaultMaxClusters,
+		idleTTL:               DefaultIdleTTL,
+		now:                   time.Now,
+		health:                make(map[corev1.ObjectReference]*ClusterHealth),
+		tracked:               make(map[corev1.ObjectReference]bool),
+		failureThreshold:      DefaultFailureThreshold,
+		openDuration:          DefaultOpenDuration,
+		rwMux:                 sync.RWMutex{},
+	}
+}
+
+// SetLimits configures how many clusters the cache holds and for how long.
+// When more than maxClusters clusters are cached, the least recently used ones are evicted.
+// Clusters not accessed for longer than idleTTL are evicted.
+// A zero value disables the corresponding limit. Negative values are treated as zero.
+// Limits are enforced immediately and then every time a cluster is added to the cache.
+// Idle clusters are not served from the cache; use AddEvictionSweeper to also release
+// their memory when no new cluster is added.
+func (m *clusterCache) SetLimits(maxClusters int, idleTTL time.Duration) {
+	m.rwMux.Lock()
+	defer m.rwMux.Unlock()
+
+	m.maxClusters = max(maxClusters, 0)
+	m.idleTTL = max(idleTTL, 0)
+
+	m.evictLocked()
+}
+
+// Len returns the number of clusters currently cached
+func (m *clusterCache) Len() int {
+	m.rwMux.RLock()
+	defer m.rwMux.RUnlock()
+
+	return len(m.entries)
+}
+
+// RemoveCluster removes restConfig cached data for the cluster.
+// Cluster health (see GetClusterHealth) is kept, use ForgetCluster when the cluster is deleted.
 func (m *clusterCache) RemoveCluster(clusterNamespace, clusterName string,
 	clusterType libsveltosv1beta1.ClusterType) {
 
@@ -89,18 +176,21 @@ func (m *clusterCache) RemoveCluster(clusterNamespace, clusterName string,
 	m.rwMux.Lock()
 	defer m.rwMux.Unlock()
 
-	// Remove from cache the restConfig for this cluster
-	delete(m.configs, *cluster)
+	m.removeClusterLocked(cluster)
+}
 
-	if sec, ok := m.clusters[*cluster]; ok {
-		m.updateSecretMap(sec, cluster)
-	}
+// ForgetCluster removes restConfig cached data and health for a deleted cluster.
+// Calls made with a rest.Config previously returned for the cluster are not tracked anymore.
+func (m *clusterCache) ForgetCluster(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) {
 
-	// Do not track this cluster anymore
-	delete(m.clusters, *cluster)
+	cluster := getClusterObjectReference(clusterNamespace, clusterName, clusterType)
 
-	delete(m.mappers, *cluster)
-	delete(m.cachedDiscoveryClient, *cluster)
+	m.rwMux.Lock()
+	defer m.rwMux.Unlock()
+
+	m.removeClusterLocked(cluster)
+	m.removeHealth(cluster)
 }
 
 // RemoveSecret removes any in-memory data related to secret
@@ -115,10 +205,7 @@ func (m *clusterCache) RemoveSecret(sec *corev1.ObjectReference) {
 
 	clusters := v.Items()
 	for i := range clusters {
-		delete(m.configs, clusters[i])
-		delete(m.clusters, clusters[i])
-		delete(m.cachedDiscoveryClient, clusters[i])
-		delete(m.mappers, clusters[i])
+		m.removeClusterLocked(&clusters[i])
 	}
 }
 
@@ -151,13 +238,10 @@ func (m *clusterCache) GetKubernetesRestConfig(ctx context.Context, mgmtClient c
 			adminNamespace, adminName, clusterType, logger)
 	}
 
-	m.rwMux.Lock()
-	defer m.rwMux.Unlock()
-
 	cluster := getClusterObjectReference(clusterNamespace, clusterName, clusterType)
 
-	config, ok := m.configs[*cluster]
-	if ok {
+	if config, ok := m.lookupRestConfig(cluster); ok {
+		cacheHits.WithLabelValues(objectRestConfig).Inc()
 		if config != nil {
 			logger.V(logs.LogDebug).Info("remote restConfig cache hit")
 		} else {
@@ -166,12 +250,26 @@ func (m *clusterCache) GetKubernetesRestConfig(ctx context.Context, mgmtClient c
 		return config, nil
 	}
 
+	m.rwMux.Lock()
+	defer m.rwMux.Unlock()
+
+	m.evictLocked()
+
+	// Another caller might have populated the cache while the write lock was not held
+	if config, ok := m.configs[*cluster]; ok {
+		m.touchLocked(cluster)
+		cacheHits.WithLabelValues(objectRestConfig).Inc()
+		return config, nil
+	}
+
 	logger.V(logs.LogDebug).Info("remote restConfig cache miss")
+	cacheMisses.WithLabelValues(objectRestConfig).Inc()
 	remoteRestConfig, err := clusterproxy.GetKubernetesRestConfig(ctx, mgmtClient, clusterNamespace, clusterName,
 		adminNamespace, adminName, clusterType, logger)
 	if err != nil {
 		return nil, err
 	}
+	remoteRestConfig = m.trackHealth(cluster, remoteRestConfig)
 
 	var cachedDiscoveryClient discovery.CachedDiscoveryInterface
 	var mapper *restmapper.DeferredDiscoveryRESTMapper
@@ -198,6 +296,8 @@ func (m *clusterCache) GetKubernetesRestConfig(ctx context.Context, mgmtClient c
 		}
 		v.Insert(cluster)
 		m.secrets[*secretInfo] = v
+		m.touchLocked(cluster)
+		m.evictLocked()
 	}
 
 	return remoteRestConfig, nil
@@ -209,14 +309,12 @@ func (m *clusterCache) GetMapper(ctx context.Context, mgmtClient client.Client,
 
 	cluster := getClusterObjectReference(clusterNamespace, clusterName, clusterType)
 
-	// 1. Use a Read Lock to check if it's already cached
-	m.rwMux.RLock()
-	mapper, ok := m.mappers[*cluster]
-	m.rwMux.RUnlock()
-
-	if ok {
+	// 1. Check if it's already cached
+	if mapper, ok := m.lookupMapper(cluster); ok {
+		cacheHits.WithLabelValues(objectMapper).Inc()
 		return mapper, nil
 	}
+	cacheMisses.WithLabelValues(objectMapper).Inc()
 
 	// 2. Cache Miss: We need to initialize the config and mapper.
 	// Calling GetKubernetesRestConfig will populate m.mappers via the logic you wrote.
@@ -245,13 +343,11 @@ func (m *clusterCache) GetCachedDiscoveryClient(ctx context.Context, mgmtClient
 	cluster := getClusterObjectReference(clusterNamespace, clusterName, clusterType)
 
 	// 1. Thread-safe check for existing cached client
-	m.rwMux.RLock()
-	dc, ok := m.cachedDiscoveryClient[*cluster]
-	m.rwMux.RUnlock()
-
-	if ok {
+	if dc, ok := m.lookupCachedDiscoveryClient(cluster); ok {
+		cacheHits.WithLabelValues(objectDiscoveryClient).Inc()
 		return dc, nil
 	}
+	cacheMisses.WithLabelValues(objectDiscoveryClient).Inc()
 
 	// 2. Cache Miss: Initialize the cluster configuration
 	// This will populate m.cachedDiscoveryClient via GetKubernetesRestConfig
@@ -335,7 +431,170 @@ func (m *clusterCache) StoreRestConfig(clusterNamespace, clusterName string,
 	m.rwMux.Lock()
 	defer m.rwMux.Unlock()
 
-	m.configs[*cluster] = config
+	m.configs[*cluster] = m.trackHealth(cluster, config)
+	m.touchLocked(cluster)
+	m.evictLocked()
+}
+
+// lookupRestConfig returns the cached rest.Config for the cluster, if any, and records the access.
+// Only the read lock is held.
+func (m *clusterCache) lookupRestConfig(cluster *corev1.ObjectReference) (*rest.Config, bool) {
+	m.rwMux.RLock()
+	defer m.rwMux.RUnlock()
+
+	config, ok := m.configs[*cluster]
+	return config, ok && m.touch(cluster)
+}
+
+func (m *clusterCache) lookupMapper(cluster *corev1.ObjectReference) (*restmapper.DeferredDiscoveryRESTMapper, bool) {
+	m.rwMux.RLock()
+	defer m.rwMux.RUnlock()
+
+	mapper, ok := m.mappers[*cluster]
+	return mapper, ok && m.touch(cluster)
+}
+
+func (m *clusterCache) lookupCachedDiscoveryClient(cluster *corev1.ObjectReference,
+) (discovery.CachedDiscoveryInterface, bool) {
+
+	m.rwMux.RLock()
+	defer m.rwMux.RUnlock()
+
+	dc, ok := m.cachedDiscoveryClient[*cluster]
+	return dc, ok && m.touch(cluster)
+}
+
+// removeClusterLocked removes any cached data for the cluster. Caller must hold the write lock.
+func (m *clusterCache) removeClusterLocked(cluster *corev1.ObjectReference) {
+	// Remove from cache the restConfig for this cluster
+	delete(m.configs, *cluster)
+
+	if sec, ok := m.clusters[*cluster]; ok {
+		m.updateSecretMap(sec, cluster)
+	}
+
+	// Do not track this cluster anymore
+	delete(m.clusters, *cluster)
+
+	delete(m.mappers, *cluster)
+	delete(m.cachedDiscoveryClient, *cluster)
+
+	delete(m.entries, *cluster)
+	cacheEntries.Set(float64(len(m.entries)))
+}
+
+// touch records an access to a cached cluster. Caller must hold at least the read lock.
+// It returns false, and records nothing, if the cluster has been idle for longer than idleTTL:
+// such a cluster is about to be evicted and must not be served from the cache.
+func (m *clusterCache) touch(cluster *corev1.ObjectReference) bool {
+	entry, ok := m.entries[*cluster]
+	if !ok {
+		return false
+	}
+
+	now := m.now()
+	if m.idleTTL > 0 && time.Unix(0, entry.lastAccess.Load()).Before(now.Add(-m.idleTTL)) {
+		return false
+	}
+
+	entry.lastAccess.Store(now.UnixNano())
+	return true
+}
+
+// touchLocked marks the cluster as most recently used, tracking it if needed.
+// Caller must hold the write lock.
+func (m *clusterCache) touchLocked(cluster *corev1.ObjectReference) {
+	entry, ok := m.entries[*cluster]
+	if !ok {
+		entry = &cacheEntry{cluster: *cluster}
+		m.entries[*cluster] = entry
+		cacheEntries.Set(float64(len(m.entries)))
+	}
+	entry.lastAccess.Store(m.now().UnixNano())
+}
+
+// evictLocked evicts clusters that have been idle for longer than idleTTL and,
+// if the cache is still above maxClusters, the least recently used ones.
+// Caller must hold the write lock.
+func (m *clusterCache) evictLocked() {
+	if m.idleTTL > 0 {
+		deadline := m.now().Add(-m.idleTTL).UnixNano()
+		for cluster, entry := range m.entries {
+			if entry.lastAccess.Load() < deadline {
+				m.removeClusterLocked(&cluster)
+				cacheEvictions.WithLabelValues(evictionReasonIdle).Inc()
+			}
+		}
+	}
+
+	if m.maxClusters > 0 && len(m.entries) > m.maxClusters {
+		entries := make([]*cacheEntry, 0, len(m.entries))
+		for _, entry := range m.entries {
+			entries = append(entries, entry)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].lastAccess.Load() < entries[j].lastAccess.Load()
+		})
+		for i := range entries[:len(entries)-m.maxClusters] {
+			m.removeClusterLocked(&entries[i].cluster)
+			cacheEvictions.WithLabelValues(evictionReasonLRU).Inc()
+		}
+	}
+}
+
+// evict evicts idle and least recently used clusters
+func (m *clusterCache) evict() {
+	m.rwMux.Lock()
+	defer m.rwMux.Unlock()
+
+	m.evictLocked()
+}
+
+// sweeper is a manager.Runnable periodically evicting idle clusters from the cache
+type sweeper struct {
+	cacheMgr *clusterCache
+	interval time.Duration
+	logger   logr.Logger
+}
+
+// AddEvictionSweeper registers with the manager a runnable evicting, every interval, clusters
+// not accessed for longer than idleTTL (see SetLimits). Without it, idle clusters are only
+// evicted when a new cluster is added to the cache.
+// If interval is not positive, DefaultSweepInterval is used.
+// The runnable does not need leader election, as each replica has its own in-memory cluster cache.
+func (m *clusterCache) AddEvictionSweeper(mgr manager.Manager, interval time.Duration,
+	logger logr.Logger) error {
+
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+
+	return mgr.Add(&sweeper{
+		cacheMgr: m,
+		interval: interval,
+		logger:   logger.WithName("clustercache-sweeper"),
+	})
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *sweeper) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable
+func (s *sweeper) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.V(logs.LogInfo).Info(fmt.Sprintf("evicting idle clusters every %s", s.interval))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.cacheMgr.evict()
+		}
+	}
 }
 
 func (m *clusterCache) updateSecretMap(sec, cluster *corev1.ObjectReference) {
@@ -368,7 +627,6 @@ func getClusterObjectReference(clusterNamespace, clusterName string,
 func getSecretObjectReference(ctx context.Context, mgmtClient client.Client,
 	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) (*corev1.ObjectReference, error) {
 
-	secretKind := "Secret"
 	if clusterType == libsveltosv1beta1.ClusterTypeCapi {
 		return &corev1.ObjectReference{
 			Namespace:  clusterNamespace,
@@ -0,0 +1,168 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustercache_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2/textlogger"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clustercache"
+)
+
+var _ = Describe("Clustercache limits", func() {
+	var now time.Time
+	cacheMgr := clustercache.NewClusterCache()
+
+	storeCluster := func(name string) {
+		cacheMgr.StoreRestConfig(name, name, libsveltosv1beta1.ClusterTypeSveltos,
+			&rest.Config{Host: "https://" + name})
+	}
+
+	clusterRef := func(name string) *corev1.ObjectReference {
+		return &corev1.ObjectReference{
+			Namespace:  name,
+			Name:       name,
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+	}
+
+	BeforeEach(func() {
+		now = time.Now()
+		cacheMgr = clustercache.NewClusterCache()
+		cacheMgr.SetNow(func() time.Time { return now })
+	})
+
+	It("evicts least recently used clusters when above maxClusters", func() {
+		cacheMgr.SetLimits(2, 0)
+
+		evictions := clustercache.GetCacheEvictions("lru")
+		hits := clustercache.GetCacheHits("restconfig")
+
+		first := randomString()
+		second := randomString()
+		third := randomString()
+
+		storeCluster(first)
+		now = now.Add(time.Second)
+		storeCluster(second)
+		now = now.Add(time.Second)
+
+		// Access first so that second becomes the least recently used
+		config, err := cacheMgr.GetKubernetesRestConfig(context.TODO(), nil, first, first, "", "",
+			libsveltosv1beta1.ClusterTypeSveltos, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+		Expect(config).ToNot(BeNil())
+		Expect(clustercache.GetCacheHits("restconfig")).To(Equal(hits + 1))
+
+		now = now.Add(time.Second)
+		storeCluster(third)
+
+		Expect(cacheMgr.Len()).To(Equal(2))
+		Expect(cacheMgr.GetConfigFromMap(clusterRef(first))).ToNot(BeNil())
+		Expect(cacheMgr.GetConfigFromMap(clusterRef(second))).To(BeNil())
+		Expect(cacheMgr.GetConfigFromMap(clusterRef(third))).ToNot(BeNil())
+		Expect(clustercache.GetCacheEvictions("lru")).To(Equal(evictions + 1))
+	})
+
+	It("evicts clusters not accessed for longer than idleTTL", func() {
+		cacheMgr.SetLimits(0, time.Minute)
+
+		evictions := clustercache.GetCacheEvictions("idle")
+
+		idle := randomString()
+		active := randomString()
+
+		storeCluster(idle)
+		now = now.Add(45 * time.Second)
+		storeCluster(active)
+		now = now.Add(30 * time.Second)
+
+		// idle was last accessed 75 seconds ago and is evicted on next sweep.
+		// Cache hits do not evict.
+		config, err := cacheMgr.GetKubernetesRestConfig(context.TODO(), nil, active, active, "", "",
+			libsveltosv1beta1.ClusterTypeSveltos, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+		Expect(config).ToNot(BeNil())
+		Expect(cacheMgr.Len()).To(Equal(2))
+
+		cacheMgr.Evict()
+		Expect(cacheMgr.Len()).To(Equal(1))
+		Expect(cacheMgr.GetConfigFromMap(clusterRef(idle))).To(BeNil())
+		Expect(clustercache.GetCacheEvictions("idle")).To(Equal(evictions + 1))
+	})
+
+	It("sweeper periodically evicts idle clusters", func() {
+		cacheMgr.SetLimits(0, time.Minute)
+
+		idle := randomString()
+		storeCluster(idle)
+		now = now.Add(45 * time.Second)
+		active := randomString()
+		storeCluster(active)
+		now = now.Add(30 * time.Second)
+		Expect(cacheMgr.Len()).To(Equal(2))
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(cacheMgr.NewSweeper(10 * time.Millisecond).Start(ctx)).To(Succeed())
+		}()
+
+		Eventually(cacheMgr.Len, time.Second, 10*time.Millisecond).Should(Equal(1))
+		Consistently(cacheMgr.Len, 100*time.Millisecond, 10*time.Millisecond).Should(Equal(1))
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("SetLimits enforces new limits immediately", func() {
+		cacheMgr.SetLimits(0, 0)
+
+		const clusters = 5
+		for range clusters {
+			storeCluster(randomString())
+			now = now.Add(time.Hour)
+		}
+		Expect(cacheMgr.Len()).To(Equal(clusters))
+
+		cacheMgr.SetLimits(3, 0)
+		Expect(cacheMgr.Len()).To(Equal(3))
+
+		cacheMgr.SetLimits(0, 90*time.Minute)
+		Expect(cacheMgr.Len()).To(Equal(1))
+	})
+
+	It("RemoveCluster stops tracking the cluster", func() {
+		name := randomString()
+		storeCluster(name)
+		Expect(cacheMgr.Len()).To(Equal(1))
+
+		cacheMgr.RemoveCluster(name, name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(cacheMgr.Len()).To(Equal(0))
+	})
+})
@@ -17,10 +17,48 @@ limitations under the License.
 package clustercache
 
 import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
+)
+
+var (
+	NewClusterCache = newClusterCache
 )
 
+func (m *clusterCache) SetNow(now func() time.Time) {
+	m.now = now
+}
+
+func (m *clusterCache) Evict() {
+	m.evict()
+}
+
+func (m *clusterCache) NewSweeper(interval time.Duration) manager.Runnable {
+	return &sweeper{cacheMgr: m, interval: interval, logger: logr.Discard()}
+}
+
+func GetCacheHits(object string) float64 {
+	return testutil.ToFloat64(cacheHits.WithLabelValues(object))
+}
+
+func GetCacheMisses(object string) float64 {
+	return testutil.ToFloat64(cacheMisses.WithLabelValues(object))
+}
+
+func GetCacheEvictions(reason string) float64 {
+	return testutil.ToFloat64(cacheEvictions.WithLabelValues(reason))
+}
+
 func (m *clusterCache) GetConfigFromMap(cluster *corev1.ObjectReference) *rest.Config {
 	return m.configs[*cluster]
 }
@@ -42,3 +80,64 @@ func (m *clusterCache) GetClusterFromSecret(secret *corev1.ObjectReference) *cor
 	items := set.Items()
 	return &items[0]
 }
+
+var (
+	GetDeletedObject = getDeletedObject
+)
+
+func GetClusterLatency(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) float64 {
+	cluster := getClusterObjectReference(clusterNamespace, clusterName, clusterType)
+	return testutil.ToFloat64(clusterLatency.WithLabelValues(cluster.Namespace, cluster.Name, cluster.Kind))
+}
+
+func (m *clusterCache) AllowCall(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (bool, error) {
+
+	return m.allowCall(getClusterObjectReference(clusterNamespace, clusterName, clusterType))
+}
+
+func (m *clusterCache) RecordCall(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
+	probe bool, err error) {
+
+	m.recordCall(getClusterObjectReference(clusterNamespace, clusterName, clusterType), probe, time.Millisecond, err)
+}
+
+func (m *clusterCache) OnSecretUpdate(oldSecret, newSecret *metav1.PartialObjectMetadata) {
+	m.onSecretUpdate(oldSecret, newSecret, logr.Discard())
+}
+
+func (m *clusterCache) OnSecretDelete(secret *metav1.PartialObjectMetadata) {
+	m.onSecretDelete(secret, logr.Discard())
+}
+
+func (m *clusterCache) OnSveltosClusterUpdate(oldCluster, newCluster *libsveltosv1beta1.SveltosCluster) {
+	m.onSveltosClusterUpdate(oldCluster, newCluster, logr.Discard())
+}
+
+func (m *clusterCache) OnSveltosClusterDelete(cluster *libsveltosv1beta1.SveltosCluster) {
+	m.onSveltosClusterDelete(cluster, logr.Discard())
+}
+
+func (m *clusterCache) OnClusterUpdate(oldCluster, newCluster *clusterv1.Cluster) {
+	m.onClusterUpdate(oldCluster, newCluster, logr.Discard())
+}
+
+func (m *clusterCache) OnClusterDelete(cluster *clusterv1.Cluster) {
+	m.onClusterDelete(cluster, logr.Discard())
+}
+
+// TrackCluster caches config for cluster as if it had been built from the kubeconfig in secret
+func (m *clusterCache) TrackCluster(cluster, secret *corev1.ObjectReference, config *rest.Config) {
+	m.rwMux.Lock()
+	defer m.rwMux.Unlock()
+
+	m.configs[*cluster] = config
+	m.clusters[*cluster] = secret
+	v, ok := m.secrets[*secret]
+	if !ok {
+		v = &libsveltosset.Set{}
+	}
+	v.Insert(cluster)
+	m.secrets[*secret] = v
+	m.touchLocked(cluster)
+}
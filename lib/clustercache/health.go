@@ -0,0 +1,349 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustercache
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// DefaultFailureThreshold is the default number of consecutive failed calls
+	// after which the circuit breaker for a cluster opens. Zero means the circuit
+	// breaker is disabled: components opt in using SetCircuitBreaker.
+	DefaultFailureThreshold = 0
+
+	// DefaultOpenDuration is the default amount of time the circuit breaker for a
+	// cluster stays open before a single probe call is let through.
+	DefaultOpenDuration = 30 * time.Second
+
+	// latencySmoothing is the weight given to the latest call when computing
+	// the rolling (exponentially weighted) latency
+	latencySmoothing = 0.2
+)
+
+// CircuitState is the state of the circuit breaker for a cluster
+type CircuitState string
+
+const (
+	// CircuitClosed means calls to the cluster are let through
+	CircuitClosed = CircuitState("Closed")
+
+	// CircuitOpen means the cluster is considered down and calls fail fast
+	CircuitOpen = CircuitState("Open")
+
+	// CircuitHalfOpen means a single probe call is in flight to verify whether
+	// the cluster is back
+	CircuitHalfOpen = CircuitState("HalfOpen")
+)
+
+// ClusterHealth contains connectivity information for a cluster, collected from
+// every call made with a rest.Config returned by the cache
+type ClusterHealth struct {
+	// LastSuccessTime is the last time a call to the cluster succeeded
+	LastSuccessTime time.Time
+
+	// LastErrorTime is the last time a call to the cluster failed
+	LastErrorTime time.Time
+
+	// LastError is the error returned by the last failed call
+	LastError error
+
+	// ConsecutiveFailures is the number of failed calls since the last success
+	ConsecutiveFailures int
+
+	// Latency is the rolling average latency of successful calls
+	Latency time.Duration
+
+	// CircuitState is the state of the circuit breaker
+	CircuitState CircuitState
+
+	// OpenUntil is when an open circuit breaker lets a probe call through
+	OpenUntil time.Time
+
+	probeInFlight bool
+}
+
+// CircuitOpenError is returned, without contacting the cluster, for calls made
+// while the circuit breaker for the cluster is open
+type CircuitOpenError struct {
+	Message string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return e.Message
+}
+
+// IsCircuitOpenError returns true if err is (or wraps) a CircuitOpenError
+func IsCircuitOpenError(err error) bool {
+	var circuitOpenError *CircuitOpenError
+	return errors.As(err, &circuitOpenError)
+}
+
+// SetCircuitBreaker configures the circuit breaker shared by all users of the cache.
+// After failureThreshold consecutive failed calls to a cluster, calls fail fast with a
+// CircuitOpenError for openDuration. Then a single probe call is let through: if it
+// succeeds the circuit breaker closes, otherwise it stays open for another openDuration.
+// A failureThreshold of zero disables the circuit breaker. Health is still tracked.
+func (m *clusterCache) SetCircuitBreaker(failureThreshold int, openDuration time.Duration) {
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	m.failureThreshold = max(failureThreshold, 0)
+	m.openDuration = max(openDuration, 0)
+
+	if m.failureThreshold == 0 {
+		for cluster, h := range m.health {
+			h.CircuitState = CircuitClosed
+			h.OpenUntil = time.Time{}
+			h.probeInFlight = false
+			setCircuitOpenMetric(&cluster, false)
+		}
+	}
+}
+
+// GetClusterHealth returns connectivity information for the cluster.
+// Returns nil if no call to the cluster has been recorded yet.
+func (m *clusterCache) GetClusterHealth(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) *ClusterHealth {
+
+	cluster := getClusterObjectReference(clusterNamespace, clusterName, clusterType)
+
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	h, ok := m.health[*cluster]
+	if !ok {
+		return nil
+	}
+
+	result := *h
+	return &result
+}
+
+// trackHealth returns a copy of config whose transport records the outcome of every
+// call to the cluster and enforces the circuit breaker
+func (m *clusterCache) trackHealth(cluster *corev1.ObjectReference, config *rest.Config) *rest.Config {
+	if config == nil {
+		return nil
+	}
+
+	m.healthMux.Lock()
+	m.tracked[*cluster] = true
+	m.healthMux.Unlock()
+
+	tracked := rest.CopyConfig(config)
+	tracked.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &healthRoundTripper{delegate: rt, cluster: *cluster, cacheMgr: m}
+	})
+	return tracked
+}
+
+// allowCall returns an error if the circuit breaker for the cluster is open.
+// When the open period is over, the caller is let through as probe, in which case
+// probe is true.
+func (m *clusterCache) allowCall(cluster *corev1.ObjectReference) (probe bool, err error) {
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	if m.failureThreshold == 0 {
+		return false, nil
+	}
+
+	h, ok := m.health[*cluster]
+	if !ok {
+		return false, nil
+	}
+
+	switch h.CircuitState {
+	case CircuitOpen:
+		if m.now().Before(h.OpenUntil) {
+			return false, m.circuitOpenError(cluster, h)
+		}
+		h.CircuitState = CircuitHalfOpen
+		h.probeInFlight = true
+		return true, nil
+	case CircuitHalfOpen:
+		if h.probeInFlight {
+			return false, m.circuitOpenError(cluster, h)
+		}
+		h.probeInFlight = true
+		return true, nil
+	case CircuitClosed:
+		return false, nil
+	}
+
+	return false, nil
+}
+
+// recordCall records the outcome of a call to the cluster and updates the circuit breaker.
+// probe is true if the call was let through by allowCall as probe. Calls started before the
+// circuit breaker opened can complete while the probe is in flight: they do not release it.
+// Health is kept when a cluster is evicted from the cache, and removed only when the cluster is
+// deleted. Calls made with a rest.Config handed out before are not recorded anymore, or cluster
+// health and its metrics would never be removed.
+func (m *clusterCache) recordCall(cluster *corev1.ObjectReference, probe bool, latency time.Duration,
+	err error) {
+
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	h, ok := m.health[*cluster]
+	if !ok {
+		if !m.tracked[*cluster] {
+			return
+		}
+		h = &ClusterHealth{CircuitState: CircuitClosed}
+		m.health[*cluster] = h
+	}
+	if probe {
+		h.probeInFlight = false
+	}
+
+	now := m.now()
+	if err == nil {
+		h.LastSuccessTime = now
+		h.ConsecutiveFailures = 0
+		if h.Latency == 0 {
+			h.Latency = latency
+		} else {
+			h.Latency = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(h.Latency))
+		}
+		h.CircuitState = CircuitClosed
+		h.OpenUntil = time.Time{}
+		clusterRequestDuration.Observe(latency.Seconds())
+		clusterLatency.WithLabelValues(cluster.Namespace, cluster.Name, cluster.Kind).Set(h.Latency.Seconds())
+		setCircuitOpenMetric(cluster, false)
+		return
+	}
+
+	h.LastErrorTime = now
+	h.LastError = err
+	h.ConsecutiveFailures++
+	clusterRequestFailures.WithLabelValues(cluster.Namespace, cluster.Name, cluster.Kind).Inc()
+
+	if m.failureThreshold == 0 {
+		return
+	}
+
+	// A failed probe reopens the circuit breaker right away
+	if probe || h.ConsecutiveFailures >= m.failureThreshold {
+		h.CircuitState = CircuitOpen
+		h.OpenUntil = now.Add(m.openDuration)
+		setCircuitOpenMetric(cluster, true)
+	}
+}
+
+// releaseProbe lets another probe through when the call allowed as probe completed
+// without telling anything about the cluster (for instance, it was canceled by the caller)
+func (m *clusterCache) releaseProbe(cluster *corev1.ObjectReference) {
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	if h, ok := m.health[*cluster]; ok {
+		h.probeInFlight = false
+	}
+}
+
+// removeHealth stops tracking health for the cluster
+func (m *clusterCache) removeHealth(cluster *corev1.ObjectReference) {
+	m.healthMux.Lock()
+	defer m.healthMux.Unlock()
+
+	delete(m.tracked, *cluster)
+
+	if _, ok := m.health[*cluster]; !ok {
+		return
+	}
+
+	delete(m.health, *cluster)
+	clusterLatency.DeleteLabelValues(cluster.Namespace, cluster.Name, cluster.Kind)
+	clusterRequestFailures.DeleteLabelValues(cluster.Namespace, cluster.Name, cluster.Kind)
+	clusterCircuitOpen.DeleteLabelValues(cluster.Namespace, cluster.Name, cluster.Kind)
+}
+
+func (m *clusterCache) circuitOpenError(cluster *corev1.ObjectReference, h *ClusterHealth) error {
+	msg := fmt.Sprintf("circuit breaker open for cluster %s %s/%s: %d consecutive failures",
+		cluster.Kind, cluster.Namespace, cluster.Name, h.ConsecutiveFailures)
+	if h.LastError != nil {
+		msg += fmt.Sprintf(" (last error: %v)", h.LastError)
+	}
+	return &CircuitOpenError{Message: msg}
+}
+
+func setCircuitOpenMetric(cluster *corev1.ObjectReference, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	clusterCircuitOpen.WithLabelValues(cluster.Namespace, cluster.Name, cluster.Kind).Set(value)
+}
+
+// healthRoundTripper records the outcome of each call to a cluster and fails fast
+// when the circuit breaker for the cluster is open
+type healthRoundTripper struct {
+	delegate http.RoundTripper
+	cluster  corev1.ObjectReference
+	cacheMgr *clusterCache
+}
+
+func (rt *healthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	probe, err := rt.cacheMgr.allowCall(&rt.cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := rt.delegate.RoundTrip(req)
+	latency := time.Since(start)
+
+	if req.Context().Err() != nil {
+		// Call was canceled by the caller. This says nothing about the cluster.
+		if probe {
+			rt.cacheMgr.releaseProbe(&rt.cluster)
+		}
+		return resp, err
+	}
+
+	callErr := err
+	if callErr == nil && isUnavailableStatus(resp.StatusCode) {
+		callErr = fmt.Errorf("cluster API server returned %s", resp.Status)
+	}
+	rt.cacheMgr.recordCall(&rt.cluster, probe, latency, callErr)
+
+	return resp, err
+}
+
+// WrappedRoundTripper allows client-go to access the underlying transport
+func (rt *healthRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegate
+}
+
+// isUnavailableStatus returns true for status codes indicating that the cluster
+// API server (or a proxy in front of it) is not able to serve requests
+func isUnavailableStatus(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustercache_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2/textlogger"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clustercache"
+)
+
+var _ = Describe("Clustercache cluster health", func() {
+	var now time.Time
+	var server *httptest.Server
+	var statusCode atomic.Int32
+	var calls atomic.Int32
+	var clusterName string
+	cacheMgr := clustercache.NewClusterCache()
+
+	call := func() error {
+		config, err := cacheMgr.GetKubernetesRestConfig(context.TODO(), nil, clusterName, clusterName, "", "",
+			libsveltosv1beta1.ClusterTypeSveltos, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+
+		httpClient, err := rest.HTTPClientFor(config)
+		Expect(err).To(BeNil())
+
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, server.URL+"/version", http.NoBody)
+		Expect(err).To(BeNil())
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	BeforeEach(func() {
+		now = time.Now()
+		statusCode.Store(http.StatusOK)
+		calls.Store(0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(int(statusCode.Load()))
+		}))
+
+		clusterName = randomString()
+		cacheMgr = clustercache.NewClusterCache()
+		cacheMgr.SetNow(func() time.Time { return now })
+		cacheMgr.StoreRestConfig(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos,
+			&rest.Config{Host: server.URL})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("GetClusterHealth reports last success and latency", func() {
+		Expect(cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)).To(BeNil())
+
+		Expect(call()).To(Succeed())
+
+		health := cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(health).ToNot(BeNil())
+		Expect(health.LastSuccessTime).To(Equal(now))
+		Expect(health.LastError).To(BeNil())
+		Expect(health.Latency).To(BeNumerically(">", 0))
+		Expect(health.CircuitState).To(Equal(clustercache.CircuitClosed))
+		Expect(clustercache.GetClusterLatency(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)).
+			To(Equal(health.Latency.Seconds()))
+	})
+
+	It("Circuit breaker is disabled by default", func() {
+		statusCode.Store(http.StatusServiceUnavailable)
+		for range 10 {
+			Expect(call()).To(Succeed())
+		}
+		Expect(calls.Load()).To(Equal(int32(10)))
+
+		health := cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(health.ConsecutiveFailures).To(Equal(10))
+		Expect(health.CircuitState).To(Equal(clustercache.CircuitClosed))
+	})
+
+	It("Only the probe call releases the probe", func() {
+		cacheMgr.SetCircuitBreaker(1, time.Minute)
+
+		// Cluster whose API server only answers once unblocked
+		received := make(chan struct{}, 1)
+		unblock := make(chan struct{})
+		slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			received <- struct{}{}
+			<-unblock
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer slowServer.Close()
+		defer close(unblock)
+
+		slowCluster := randomString()
+		cacheMgr.StoreRestConfig(slowCluster, slowCluster, libsveltosv1beta1.ClusterTypeSveltos,
+			&rest.Config{Host: slowServer.URL})
+		config, err := cacheMgr.GetKubernetesRestConfig(context.TODO(), nil, slowCluster, slowCluster, "", "",
+			libsveltosv1beta1.ClusterTypeSveltos, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+		httpClient, err := rest.HTTPClientFor(config)
+		Expect(err).To(BeNil())
+
+		// Call is started while circuit breaker is closed
+		ctx, cancel := context.WithCancel(context.TODO())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, slowServer.URL+"/version", http.NoBody)
+			Expect(err).To(BeNil())
+			resp, err := httpClient.Do(req)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+		Eventually(received).Should(Receive())
+
+		// Circuit breaker opens, then a probe is let through
+		cacheMgr.RecordCall(slowCluster, slowCluster, libsveltosv1beta1.ClusterTypeSveltos, false,
+			errors.New("connection refused"))
+		now = now.Add(2 * time.Minute)
+		probe, err := cacheMgr.AllowCall(slowCluster, slowCluster, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(probe).To(BeTrue())
+
+		// Call started before circuit breaker opened is canceled: probe is still in flight
+		cancel()
+		Eventually(done).Should(BeClosed())
+		_, err = cacheMgr.AllowCall(slowCluster, slowCluster, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(clustercache.IsCircuitOpenError(err)).To(BeTrue())
+
+		// Successful probe closes the circuit breaker
+		cacheMgr.RecordCall(slowCluster, slowCluster, libsveltosv1beta1.ClusterTypeSveltos, true, nil)
+		health := cacheMgr.GetClusterHealth(slowCluster, slowCluster, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(health.CircuitState).To(Equal(clustercache.CircuitClosed))
+		probe, err = cacheMgr.AllowCall(slowCluster, slowCluster, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(probe).To(BeFalse())
+	})
+
+	It("Circuit breaker fails fast once the cluster is known to be down and closes on successful probe", func() {
+		const threshold = 3
+		cacheMgr.SetCircuitBreaker(threshold, time.Minute)
+
+		statusCode.Store(http.StatusServiceUnavailable)
+		for range threshold {
+			Expect(call()).To(Succeed())
+		}
+		Expect(calls.Load()).To(Equal(int32(threshold)))
+
+		health := cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(health.ConsecutiveFailures).To(Equal(threshold))
+		Expect(health.LastError).ToNot(BeNil())
+		Expect(health.CircuitState).To(Equal(clustercache.CircuitOpen))
+
+		// Cluster is not contacted while circuit breaker is open
+		err := call()
+		Expect(err).ToNot(BeNil())
+		Expect(clustercache.IsCircuitOpenError(err)).To(BeTrue())
+		Expect(calls.Load()).To(Equal(int32(threshold)))
+
+		// Failed probe reopens the circuit breaker
+		now = now.Add(2 * time.Minute)
+		Expect(call()).To(Succeed())
+		Expect(calls.Load()).To(Equal(int32(threshold + 1)))
+		health = cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(health.CircuitState).To(Equal(clustercache.CircuitOpen))
+		Expect(clustercache.IsCircuitOpenError(call())).To(BeTrue())
+
+		// Successful probe closes it
+		now = now.Add(2 * time.Minute)
+		statusCode.Store(http.StatusOK)
+		Expect(call()).To(Succeed())
+		health = cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(health.CircuitState).To(Equal(clustercache.CircuitClosed))
+		Expect(health.ConsecutiveFailures).To(BeZero())
+		Expect(call()).To(Succeed())
+	})
+
+	It("Disabling the circuit breaker lets calls through", func() {
+		cacheMgr.SetCircuitBreaker(1, time.Hour)
+
+		statusCode.Store(http.StatusBadGateway)
+		Expect(call()).To(Succeed())
+		Expect(clustercache.IsCircuitOpenError(call())).To(BeTrue())
+
+		cacheMgr.SetCircuitBreaker(0, 0)
+		Expect(call()).To(Succeed())
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("Evicting the cluster from the cache keeps cluster health", func() {
+		cacheMgr.SetCircuitBreaker(1, time.Hour)
+
+		statusCode.Store(http.StatusBadGateway)
+		Expect(call()).To(Succeed())
+
+		// Cluster is cached again on next call: circuit breaker is still open
+		cacheMgr.RemoveCluster(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		cacheMgr.StoreRestConfig(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos,
+			&rest.Config{Host: server.URL})
+		Expect(clustercache.IsCircuitOpenError(call())).To(BeTrue())
+		Expect(calls.Load()).To(Equal(int32(1)))
+
+		health := cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(health).ToNot(BeNil())
+		Expect(health.CircuitState).To(Equal(clustercache.CircuitOpen))
+	})
+
+	It("ForgetCluster forgets cluster health and stops recording calls to the cluster", func() {
+		config, err := cacheMgr.GetKubernetesRestConfig(context.TODO(), nil, clusterName, clusterName, "", "",
+			libsveltosv1beta1.ClusterTypeSveltos, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+
+		Expect(call()).To(Succeed())
+		Expect(cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)).ToNot(BeNil())
+
+		cacheMgr.ForgetCluster(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)).To(BeNil())
+
+		// rest.Config returned before the cluster was forgotten still works but is not tracked
+		httpClient, err := rest.HTTPClientFor(config)
+		Expect(err).To(BeNil())
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, server.URL+"/version", http.NoBody)
+		Expect(err).To(BeNil())
+		resp, err := httpClient.Do(req)
+		Expect(err).To(BeNil())
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(cacheMgr.GetClusterHealth(clusterName, clusterName, libsveltosv1beta1.ClusterTypeSveltos)).To(BeNil())
+	})
+})
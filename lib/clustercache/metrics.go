@@ -0,0 +1,123 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustercache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "sveltos"
+	metricsSubsystem = "cluster_cache"
+
+	// Values for the object label
+	objectRestConfig      = "restconfig"
+	objectMapper          = "mapper"
+	objectDiscoveryClient = "discovery_client"
+
+	// Values for the reason label
+	evictionReasonLRU  = "lru"
+	evictionReasonIdle = "idle"
+)
+
+var (
+	cacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "hits_total",
+			Help:      "Number of cluster cache lookups served from memory",
+		},
+		[]string{"object"},
+	)
+
+	cacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "misses_total",
+			Help:      "Number of cluster cache lookups that required building the entry",
+		},
+		[]string{"object"},
+	)
+
+	cacheEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "evictions_total",
+			Help:      "Number of clusters evicted from the cluster cache because of size limit or inactivity",
+		},
+		[]string{"reason"},
+	)
+
+	clusterRequestDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "cluster_request_duration_seconds",
+			Help:      "Latency of successful calls to managed clusters",
+			Buckets:   prometheus.DefBuckets,
+		},
+	)
+
+	clusterLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "cluster_latency_seconds",
+			Help:      "Rolling average latency of successful calls to a managed cluster",
+		},
+		[]string{"cluster_namespace", "cluster_name", "cluster_kind"},
+	)
+
+	clusterRequestFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "cluster_request_failures_total",
+			Help:      "Number of calls to managed clusters that failed because the cluster was not reachable",
+		},
+		[]string{"cluster_namespace", "cluster_name", "cluster_kind"},
+	)
+
+	clusterCircuitOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "cluster_circuit_open",
+			Help:      "Whether the circuit breaker for a managed cluster is open (1) or not (0)",
+		},
+		[]string{"cluster_namespace", "cluster_name", "cluster_kind"},
+	)
+
+	cacheEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "entries",
+			Help:      "Number of clusters currently held in the cluster cache",
+		},
+	)
+)
+
+// Register cluster cache metrics with the controller-runtime global registry
+func init() {
+	metrics.Registry.MustRegister(cacheHits, cacheMisses, cacheEvictions, cacheEntries,
+		clusterRequestDuration, clusterLatency, clusterRequestFailures, clusterCircuitOpen)
+}
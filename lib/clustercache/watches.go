@@ -0,0 +1,257 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustercache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// invalidator is a manager.Runnable that watches kubeconfig Secrets (metadata only), SveltosClusters
+// and, optionally, ClusterAPI Clusters and evicts the affected cache entries when
+// they change.
+type invalidator struct {
+	cacheMgr          *clusterCache
+	informers         cache.Informers
+	watchCAPIClusters bool
+	logger            logr.Logger
+}
+
+// AddInvalidationWatches registers with the manager a runnable that keeps the cache
+// consistent with the management cluster:
+//   - when a Secret whose kubeconfig is cached is modified or deleted, all clusters
+//     using that Secret are evicted;
+//   - when a SveltosCluster is deleted or its kubeconfig/workload identity settings
+//     change, the cluster is evicted;
+//   - if watchCAPIClusters is true, when a ClusterAPI Cluster is deleted or its control
+//     plane endpoint changes, the cluster is evicted. Set it only if ClusterAPI CRDs are
+//     installed.
+//
+// Secrets are watched as metadata only (metav1.PartialObjectMetadata), so their content is never
+// cached in memory, and any change to a Secret (new resourceVersion) is considered.
+// Watches are served by the manager cache. The runnable does not need leader election,
+// as each replica has its own in-memory cluster cache.
+func (m *clusterCache) AddInvalidationWatches(mgr manager.Manager, watchCAPIClusters bool,
+	logger logr.Logger) error {
+
+	return mgr.Add(&invalidator{
+		cacheMgr:          m,
+		informers:         mgr.GetCache(),
+		watchCAPIClusters: watchCAPIClusters,
+		logger:            logger.WithName("clustercache-invalidator"),
+	})
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (i *invalidator) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable
+func (i *invalidator) Start(ctx context.Context) error {
+	secret := &metav1.PartialObjectMetadata{}
+	secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(secretKind))
+	if err := i.addHandler(ctx, secret, i.secretHandler()); err != nil {
+		return err
+	}
+
+	if err := i.addHandler(ctx, &libsveltosv1beta1.SveltosCluster{}, i.sveltosClusterHandler()); err != nil {
+		return err
+	}
+
+	if i.watchCAPIClusters {
+		if err := i.addHandler(ctx, &clusterv1.Cluster{}, i.clusterHandler()); err != nil {
+			return err
+		}
+	}
+
+	i.logger.V(logs.LogInfo).Info("watching for changes invalidating cluster cache")
+	<-ctx.Done()
+	return nil
+}
+
+func (i *invalidator) addHandler(ctx context.Context, obj client.Object,
+	handler toolscache.ResourceEventHandler) error {
+
+	informer, err := i.informers.GetInformer(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("failed to get informer for %T: %w", obj, err)
+	}
+
+	if _, err := informer.AddEventHandler(handler); err != nil {
+		return fmt.Errorf("failed to add event handler for %T: %w", obj, err)
+	}
+	return nil
+}
+
+func (i *invalidator) secretHandler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSecret, okOld := oldObj.(*metav1.PartialObjectMetadata)
+			newSecret, okNew := newObj.(*metav1.PartialObjectMetadata)
+			if okOld && okNew {
+				i.cacheMgr.onSecretUpdate(oldSecret, newSecret, i.logger)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if secret, ok := getDeletedObject(obj).(*metav1.PartialObjectMetadata); ok {
+				i.cacheMgr.onSecretDelete(secret, i.logger)
+			}
+		},
+	}
+}
+
+func (i *invalidator) sveltosClusterHandler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldCluster, okOld := oldObj.(*libsveltosv1beta1.SveltosCluster)
+			newCluster, okNew := newObj.(*libsveltosv1beta1.SveltosCluster)
+			if okOld && okNew {
+				i.cacheMgr.onSveltosClusterUpdate(oldCluster, newCluster, i.logger)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if cluster, ok := getDeletedObject(obj).(*libsveltosv1beta1.SveltosCluster); ok {
+				i.cacheMgr.onSveltosClusterDelete(cluster, i.logger)
+			}
+		},
+	}
+}
+
+func (i *invalidator) clusterHandler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldCluster, okOld := oldObj.(*clusterv1.Cluster)
+			newCluster, okNew := newObj.(*clusterv1.Cluster)
+			if okOld && okNew {
+				i.cacheMgr.onClusterUpdate(oldCluster, newCluster, i.logger)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if cluster, ok := getDeletedObject(obj).(*clusterv1.Cluster); ok {
+				i.cacheMgr.onClusterDelete(cluster, i.logger)
+			}
+		},
+	}
+}
+
+// getDeletedObject returns the deleted object, unwrapping it if the informer
+// missed the delete event and delivered a tombstone
+func getDeletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+func (m *clusterCache) onSecretUpdate(oldSecret, newSecret *metav1.PartialObjectMetadata, logger logr.Logger) {
+	if oldSecret.ResourceVersion == newSecret.ResourceVersion {
+		// Periodic resync
+		return
+	}
+
+	secret := getSecretReference(newSecret)
+	if !m.isSecretTracked(secret) {
+		return
+	}
+
+	logger.V(logs.LogDebug).Info("secret changed",
+		"secret", fmt.Sprintf("%s/%s", newSecret.Namespace, newSecret.Name))
+	m.RemoveSecret(secret)
+}
+
+func (m *clusterCache) onSecretDelete(oldSecret *metav1.PartialObjectMetadata, logger logr.Logger) {
+	secret := getSecretReference(oldSecret)
+	if !m.isSecretTracked(secret) {
+		return
+	}
+
+	logger.V(logs.LogDebug).Info("secret deleted",
+		"secret", fmt.Sprintf("%s/%s", oldSecret.Namespace, oldSecret.Name))
+	m.RemoveSecret(secret)
+}
+
+// isSecretTracked returns true if the kubeconfig of any cached cluster comes from secret
+func (m *clusterCache) isSecretTracked(secret *corev1.ObjectReference) bool {
+	m.rwMux.RLock()
+	defer m.rwMux.RUnlock()
+
+	_, ok := m.secrets[*secret]
+	return ok
+}
+
+func (m *clusterCache) onSveltosClusterUpdate(oldCluster, newCluster *libsveltosv1beta1.SveltosCluster,
+	logger logr.Logger) {
+
+	if oldCluster.Spec.KubeconfigName == newCluster.Spec.KubeconfigName &&
+		oldCluster.Spec.KubeconfigKeyName == newCluster.Spec.KubeconfigKeyName &&
+		reflect.DeepEqual(oldCluster.Spec.WorkloadIdentity, newCluster.Spec.WorkloadIdentity) {
+
+		return
+	}
+
+	logger.V(logs.LogDebug).Info("SveltosCluster credentials configuration changed",
+		"cluster", fmt.Sprintf("%s/%s", newCluster.Namespace, newCluster.Name))
+	m.RemoveCluster(newCluster.Namespace, newCluster.Name, libsveltosv1beta1.ClusterTypeSveltos)
+	clusterproxy.EvictWorkloadIdentityCache(newCluster.Namespace, newCluster.Name)
+}
+
+func (m *clusterCache) onSveltosClusterDelete(cluster *libsveltosv1beta1.SveltosCluster, logger logr.Logger) {
+	logger.V(logs.LogDebug).Info("SveltosCluster deleted",
+		"cluster", fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name))
+	m.ForgetCluster(cluster.Namespace, cluster.Name, libsveltosv1beta1.ClusterTypeSveltos)
+	clusterproxy.EvictWorkloadIdentityCache(cluster.Namespace, cluster.Name)
+}
+
+func (m *clusterCache) onClusterUpdate(oldCluster, newCluster *clusterv1.Cluster, logger logr.Logger) {
+	if oldCluster.Spec.ControlPlaneEndpoint == newCluster.Spec.ControlPlaneEndpoint {
+		return
+	}
+
+	logger.V(logs.LogDebug).Info("Cluster control plane endpoint changed",
+		"cluster", fmt.Sprintf("%s/%s", newCluster.Namespace, newCluster.Name))
+	m.RemoveCluster(newCluster.Namespace, newCluster.Name, libsveltosv1beta1.ClusterTypeCapi)
+}
+
+func (m *clusterCache) onClusterDelete(cluster *clusterv1.Cluster, logger logr.Logger) {
+	logger.V(logs.LogDebug).Info("Cluster deleted",
+		"cluster", fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name))
+	m.ForgetCluster(cluster.Namespace, cluster.Name, libsveltosv1beta1.ClusterTypeCapi)
+}
+
+func getSecretReference(secret *metav1.PartialObjectMetadata) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Namespace:  secret.Namespace,
+		Name:       secret.Name,
+		Kind:       secretKind,
+		APIVersion: corev1.SchemeGroupVersion.String(),
+	}
+}
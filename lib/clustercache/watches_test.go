@@ -0,0 +1,162 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustercache_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clustercache"
+)
+
+var _ = Describe("Clustercache invalidation watches", func() {
+	var namespace string
+	cacheMgr := clustercache.NewClusterCache()
+
+	getClusterReference := func(name string, clusterType libsveltosv1beta1.ClusterType) *corev1.ObjectReference {
+		ref := &corev1.ObjectReference{
+			Namespace:  namespace,
+			Name:       name,
+			Kind:       clusterv1.ClusterKind,
+			APIVersion: clusterv1.GroupVersion.String(),
+		}
+		if clusterType == libsveltosv1beta1.ClusterTypeSveltos {
+			ref.Kind = libsveltosv1beta1.SveltosClusterKind
+			ref.APIVersion = libsveltosv1beta1.GroupVersion.String()
+		}
+		return ref
+	}
+
+	getSecretReference := func(secret *metav1.PartialObjectMetadata) *corev1.ObjectReference {
+		return &corev1.ObjectReference{
+			Namespace:  secret.Namespace,
+			Name:       secret.Name,
+			Kind:       testKindSecret,
+			APIVersion: corev1.SchemeGroupVersion.String(),
+		}
+	}
+
+	getSecret := func(name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: testKindSecret},
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, ResourceVersion: "1"},
+		}
+	}
+
+	BeforeEach(func() {
+		namespace = randomString()
+		cacheMgr = clustercache.NewClusterCache()
+	})
+
+	It("Secret changes evict only clusters using that secret", func() {
+		shared := getSecret(randomString())
+		other := getSecret(randomString())
+
+		cluster1 := getClusterReference(randomString(), libsveltosv1beta1.ClusterTypeSveltos)
+		cluster2 := getClusterReference(randomString(), libsveltosv1beta1.ClusterTypeSveltos)
+		cluster3 := getClusterReference(randomString(), libsveltosv1beta1.ClusterTypeSveltos)
+		cacheMgr.TrackCluster(cluster1, getSecretReference(shared), &rest.Config{})
+		cacheMgr.TrackCluster(cluster2, getSecretReference(shared), &rest.Config{})
+		cacheMgr.TrackCluster(cluster3, getSecretReference(other), &rest.Config{})
+
+		// Resync does not invalidate anything
+		updated := shared.DeepCopy()
+		cacheMgr.OnSecretUpdate(shared, updated)
+		Expect(cacheMgr.Len()).To(Equal(3))
+
+		// Changes to Secrets not used by any cached cluster are ignored
+		untracked := getSecret(randomString())
+		updatedUntracked := untracked.DeepCopy()
+		updatedUntracked.ResourceVersion = "2"
+		cacheMgr.OnSecretUpdate(untracked, updatedUntracked)
+		cacheMgr.OnSecretDelete(untracked)
+		Expect(cacheMgr.Len()).To(Equal(3))
+
+		updated.ResourceVersion = "2"
+		cacheMgr.OnSecretUpdate(shared, updated)
+		Expect(cacheMgr.Len()).To(Equal(1))
+		Expect(cacheMgr.GetConfigFromMap(cluster1)).To(BeNil())
+		Expect(cacheMgr.GetConfigFromMap(cluster2)).To(BeNil())
+		Expect(cacheMgr.GetConfigFromMap(cluster3)).ToNot(BeNil())
+		Expect(cacheMgr.GetClusterFromSecret(getSecretReference(shared))).To(BeNil())
+
+		cacheMgr.OnSecretDelete(other)
+		Expect(cacheMgr.Len()).To(Equal(0))
+		Expect(cacheMgr.GetSecretForCluster(cluster3)).To(BeNil())
+	})
+
+	It("SveltosCluster changes evict the cluster only when credentials configuration changes", func() {
+		cluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+		}
+		ref := getClusterReference(cluster.Name, libsveltosv1beta1.ClusterTypeSveltos)
+		cacheMgr.TrackCluster(ref, getSecretReference(getSecret(randomString())), &rest.Config{})
+
+		updated := cluster.DeepCopy()
+		updated.Spec.Paused = true
+		cacheMgr.OnSveltosClusterUpdate(cluster, updated)
+		Expect(cacheMgr.GetConfigFromMap(ref)).ToNot(BeNil())
+
+		updated.Spec.KubeconfigName = randomString()
+		cacheMgr.OnSveltosClusterUpdate(cluster, updated)
+		Expect(cacheMgr.GetConfigFromMap(ref)).To(BeNil())
+
+		cacheMgr.TrackCluster(ref, getSecretReference(getSecret(randomString())), &rest.Config{})
+		cacheMgr.OnSveltosClusterDelete(updated)
+		Expect(cacheMgr.GetConfigFromMap(ref)).To(BeNil())
+		Expect(cacheMgr.Len()).To(Equal(0))
+	})
+
+	It("Cluster changes evict the cluster when deleted or endpoint changes", func() {
+		cluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+		}
+		ref := getClusterReference(cluster.Name, libsveltosv1beta1.ClusterTypeCapi)
+		sveltosRef := getClusterReference(cluster.Name, libsveltosv1beta1.ClusterTypeSveltos)
+		cacheMgr.TrackCluster(ref, getSecretReference(getSecret(randomString())), &rest.Config{})
+		cacheMgr.TrackCluster(sveltosRef, getSecretReference(getSecret(randomString())), &rest.Config{})
+
+		updated := cluster.DeepCopy()
+		updated.Labels = map[string]string{randomString(): randomString()}
+		cacheMgr.OnClusterUpdate(cluster, updated)
+		Expect(cacheMgr.GetConfigFromMap(ref)).ToNot(BeNil())
+
+		updated.Spec.ControlPlaneEndpoint.Host = randomString()
+		cacheMgr.OnClusterUpdate(cluster, updated)
+		Expect(cacheMgr.GetConfigFromMap(ref)).To(BeNil())
+
+		cacheMgr.TrackCluster(ref, getSecretReference(getSecret(randomString())), &rest.Config{})
+		cacheMgr.OnClusterDelete(updated)
+		Expect(cacheMgr.GetConfigFromMap(ref)).To(BeNil())
+		// SveltosCluster with same namespace/name is not affected
+		Expect(cacheMgr.GetConfigFromMap(sveltosRef)).ToNot(BeNil())
+	})
+
+	It("getDeletedObject unwraps tombstones", func() {
+		secret := getSecret(randomString())
+		Expect(clustercache.GetDeletedObject(secret)).To(Equal(secret))
+		tombstone := toolscache.DeletedFinalStateUnknown{Key: secret.Namespace + "/" + secret.Name, Obj: secret}
+		Expect(clustercache.GetDeletedObject(tombstone)).To(Equal(secret))
+	})
+})
@@ -0,0 +1,427 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterproxy
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// defaultRenewedKubeconfigKey is the Secret key the renewed kubeconfig is stored in
+	// when TokenRequestRenewalOption.KubeconfigKeyName is not set
+	defaultRenewedKubeconfigKey = "re-kubeconfig"
+
+	// credentialRotationLeadTime is how long before credentials expire they are renewed
+	credentialRotationLeadTime = 5 * time.Minute
+
+	serviceAccountSubjectPrefix = "system:serviceaccount:"
+)
+
+// CredentialSource identifies the kubeconfig credential an expiry was read from
+type CredentialSource string
+
+const (
+	// CredentialSourceClientCertificate means expiry is the client certificate NotAfter
+	CredentialSourceClientCertificate = CredentialSource("ClientCertificate")
+
+	// CredentialSourceToken means expiry is the bearer token exp claim
+	CredentialSourceToken = CredentialSource("Token")
+)
+
+// CredentialExpiry contains when the credentials in a kubeconfig expire
+type CredentialExpiry struct {
+	// AuthInfo is the name of the kubeconfig user the credentials belong to
+	AuthInfo string
+
+	// Source is the credential ExpiresAt was read from
+	Source CredentialSource
+
+	// ExpiresAt is when the credentials expire
+	ExpiresAt time.Time
+}
+
+// CredentialRotation is the result of RotateSveltosClusterCredentials
+type CredentialRotation struct {
+	// Rotated is true if credentials have been renewed
+	Rotated bool
+
+	// Expiry is the expiry of the credentials currently stored. Nil if it cannot be determined.
+	Expiry *CredentialExpiry
+
+	// NextRotation is when credentials should be renewed next.
+	// Zero if SveltosCluster has no TokenRequestRenewalOption.
+	NextRotation time.Time
+}
+
+// newRemoteClient returns the client used to request tokens to the managed cluster
+var newRemoteClient = func(config *rest.Config, s *runtime.Scheme) (client.Client, error) {
+	return client.New(config, client.Options{Scheme: s})
+}
+
+// GetKubeconfigCredentialExpiry returns when the credentials used by the current context of
+// kubeconfig expire. Client certificates (client-certificate-data) and JWT bearer tokens
+// (token) are inspected; if both are present the earliest expiry is returned.
+// Returns nil if credentials do not expire or their expiry cannot be determined
+// (for instance opaque tokens, token files or exec plugins).
+func GetKubeconfigCredentialExpiry(kubeconfig []byte) (*CredentialExpiry, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse kubeconfig")
+	}
+
+	authInfoName, authInfo, err := getCurrentAuthInfo(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiry *CredentialExpiry
+	if len(authInfo.ClientCertificateData) > 0 {
+		notAfter, err := getCertificateExpiry(authInfo.ClientCertificateData)
+		if err != nil {
+			return nil, err
+		}
+		expiry = &CredentialExpiry{AuthInfo: authInfoName, Source: CredentialSourceClientCertificate,
+			ExpiresAt: notAfter}
+	}
+
+	if authInfo.Token != "" {
+		claims, err := getJWTClaims(authInfo.Token)
+		if err == nil && claims.Exp > 0 {
+			exp := time.Unix(claims.Exp, 0)
+			if expiry == nil || exp.Before(expiry.ExpiresAt) {
+				expiry = &CredentialExpiry{AuthInfo: authInfoName, Source: CredentialSourceToken, ExpiresAt: exp}
+			}
+		}
+	}
+
+	return expiry, nil
+}
+
+// GetSveltosClusterCredentialExpiry returns when the credentials in the kubeconfig of
+// the SveltosCluster expire. See GetKubeconfigCredentialExpiry.
+func GetSveltosClusterCredentialExpiry(ctx context.Context, logger logr.Logger, c client.Client,
+	clusterNamespace, clusterName string) (*CredentialExpiry, error) {
+
+	kubeconfig, err := GetSveltosSecretData(ctx, logger, c, clusterNamespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetKubeconfigCredentialExpiry(kubeconfig)
+}
+
+// RotateSveltosClusterCredentials renews, using a TokenRequest, the credentials in the
+// kubeconfig of the SveltosCluster when either:
+//   - Spec.TokenRequestRenewalOption.RenewTokenRequestInterval elapsed since
+//     Status.LastReconciledTokenRequestAt;
+//   - current credentials expire in less than five minutes.
+//
+// The token is requested, with the current kubeconfig, for TokenRequestRenewalOption.SAName
+// in TokenRequestRenewalOption.SANamespace (deduced from current token if not set).
+// The renewed kubeconfig is stored via UpdateSveltosSecretData and
+// Status.LastReconciledTokenRequestAt is updated.
+// If SveltosCluster has no TokenRequestRenewalOption, nothing is renewed and only the
+// credential expiry is reported.
+func RotateSveltosClusterCredentials(ctx context.Context, logger logr.Logger, c client.Client,
+	clusterNamespace, clusterName string) (*CredentialRotation, error) {
+
+	logger = logger.WithValues("namespace", clusterNamespace, "cluster", clusterName)
+
+	sveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: clusterNamespace, Name: clusterName}, sveltosCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfig, err := GetSveltosSecretData(ctx, logger, c, clusterNamespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	expiry, err := GetKubeconfigCredentialExpiry(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	option := sveltosCluster.Spec.TokenRequestRenewalOption
+	if option == nil {
+		return &CredentialRotation{Expiry: expiry}, nil
+	}
+
+	now := time.Now()
+	nextRotation := getNextRotation(sveltosCluster, expiry)
+	if now.Before(nextRotation) {
+		logger.V(logs.LogDebug).Info("credentials do not need to be renewed yet",
+			"nextRotation", nextRotation)
+		return &CredentialRotation{Expiry: expiry, NextRotation: nextRotation}, nil
+	}
+
+	renewed, tokenExpiry, err := renewKubeconfigToken(ctx, kubeconfig, option)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfigKey := defaultRenewedKubeconfigKey
+	if option.KubeconfigKeyName != nil {
+		kubeconfigKey = *option.KubeconfigKeyName
+	}
+	if err := UpdateSveltosSecretData(ctx, logger, c, clusterNamespace, clusterName,
+		string(renewed), kubeconfigKey); err != nil {
+
+		return nil, err
+	}
+
+	if option.KubeconfigKeyName == nil && sveltosCluster.Spec.KubeconfigKeyName != kubeconfigKey {
+		patch := client.MergeFrom(sveltosCluster.DeepCopy())
+		sveltosCluster.Spec.KubeconfigKeyName = kubeconfigKey
+		if err := c.Patch(ctx, sveltosCluster, patch); err != nil {
+			return nil, errors.Wrap(err, "failed to update SveltosCluster kubeconfigKeyName")
+		}
+	}
+
+	sveltosCluster.Status.LastReconciledTokenRequestAt = now.UTC().Format(time.RFC3339)
+	if err := c.Status().Update(ctx, sveltosCluster); err != nil {
+		return nil, errors.Wrap(err, "failed to update SveltosCluster lastReconciledTokenRequestAt")
+	}
+
+	// Prefer expiry in the token itself. Fall back to expiration reported by the TokenRequest.
+	expiry = tokenExpiry
+	if parsed, err := GetKubeconfigCredentialExpiry(renewed); err == nil && parsed != nil {
+		expiry = parsed
+	}
+
+	nextRotation = getNextRotation(sveltosCluster, expiry)
+	logger.V(logs.LogInfo).Info("renewed credentials", "expiresAt", expiry.ExpiresAt,
+		"nextRotation", nextRotation)
+	return &CredentialRotation{Rotated: true, Expiry: expiry, NextRotation: nextRotation}, nil
+}
+
+// getNextRotation returns when credentials should be renewed: the earliest between the end
+// of current renewal interval and shortly before credentials expire
+func getNextRotation(sveltosCluster *libsveltosv1beta1.SveltosCluster, expiry *CredentialExpiry) time.Time {
+	var nextRotation time.Time
+
+	option := sveltosCluster.Spec.TokenRequestRenewalOption
+	lastRenewal, err := time.Parse(time.RFC3339, sveltosCluster.Status.LastReconciledTokenRequestAt)
+	if err == nil {
+		nextRotation = lastRenewal.Add(option.RenewTokenRequestInterval.Duration)
+	}
+	// If credentials were never renewed, nextRotation is zero: renew right away
+
+	if expiry != nil {
+		expiryRotation := expiry.ExpiresAt.Add(-credentialRotationLeadTime)
+		if expiryRotation.Before(nextRotation) {
+			nextRotation = expiryRotation
+		}
+	}
+
+	return nextRotation
+}
+
+// renewKubeconfigToken requests, using kubeconfig, a new token for the ServiceAccount and
+// returns kubeconfig with the current context user authenticating with the new token,
+// along with the token expiration reported by the TokenRequest
+func renewKubeconfigToken(ctx context.Context, kubeconfig []byte,
+	option *libsveltosv1beta1.TokenRequestRenewalOption) (renewed []byte, expiry *CredentialExpiry, err error) {
+
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse kubeconfig")
+	}
+
+	authInfoName, authInfo, err := getCurrentAuthInfo(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	saNamespace, saName, err := getTokenRequestServiceAccount(authInfo, option)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get rest config from kubeconfig")
+	}
+
+	s := runtime.NewScheme()
+	if err := corev1.AddToScheme(s); err != nil {
+		return nil, nil, err
+	}
+	if err := authenticationv1.AddToScheme(s); err != nil {
+		return nil, nil, err
+	}
+
+	remoteClient, err := newRemoteClient(restConfig, s)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get managed cluster client")
+	}
+
+	tokenDuration := option.TokenDuration.Duration
+	if tokenDuration == 0 {
+		tokenDuration = option.RenewTokenRequestInterval.Duration
+	}
+	tokenRequest := &authenticationv1.TokenRequest{}
+	if tokenDuration > 0 {
+		expirationSeconds := int64(tokenDuration.Seconds())
+		tokenRequest.Spec.ExpirationSeconds = &expirationSeconds
+	}
+
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: saNamespace, Name: saName},
+	}
+	if err := remoteClient.SubResource("token").Create(ctx, sa, tokenRequest); err != nil {
+		return nil, nil, errors.Wrap(err,
+			fmt.Sprintf("failed to request token for ServiceAccount %s/%s", saNamespace, saName))
+	}
+
+	config.AuthInfos[authInfoName] = &clientcmdapi.AuthInfo{Token: tokenRequest.Status.Token}
+	renewed, err = clientcmd.Write(*config)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to write renewed kubeconfig")
+	}
+
+	return renewed, &CredentialExpiry{
+		AuthInfo:  authInfoName,
+		Source:    CredentialSourceToken,
+		ExpiresAt: tokenRequest.Status.ExpirationTimestamp.Time,
+	}, nil
+}
+
+// getTokenRequestServiceAccount returns the ServiceAccount to request the token for. If not
+// set in option, it is deduced from the subject of the current service account token.
+func getTokenRequestServiceAccount(authInfo *clientcmdapi.AuthInfo,
+	option *libsveltosv1beta1.TokenRequestRenewalOption) (saNamespace, saName string, err error) {
+
+	if option.SANamespace != "" && option.SAName != "" {
+		return option.SANamespace, option.SAName, nil
+	}
+
+	claims, err := getJWTClaims(authInfo.Token)
+	if err != nil || !strings.HasPrefix(claims.Sub, serviceAccountSubjectPrefix) {
+		return "", "", errors.New("ServiceAccount not specified in tokenRequestRenewalOption " +
+			"and it cannot be deduced from kubeconfig")
+	}
+
+	const saParts = 2
+	parts := strings.Split(strings.TrimPrefix(claims.Sub, serviceAccountSubjectPrefix), ":")
+	if len(parts) != saParts {
+		return "", "", fmt.Errorf("unexpected service account token subject %q", claims.Sub)
+	}
+
+	saNamespace, saName = parts[0], parts[1]
+	if option.SANamespace != "" {
+		saNamespace = option.SANamespace
+	}
+	if option.SAName != "" {
+		saName = option.SAName
+	}
+	return saNamespace, saName, nil
+}
+
+// getCurrentAuthInfo returns the user used by the current context. If current context is
+// not set and kubeconfig has a single user, that user is returned.
+func getCurrentAuthInfo(config *clientcmdapi.Config) (string, *clientcmdapi.AuthInfo, error) {
+	if kubeContext, ok := config.Contexts[config.CurrentContext]; ok {
+		if authInfo, ok := config.AuthInfos[kubeContext.AuthInfo]; ok {
+			return kubeContext.AuthInfo, authInfo, nil
+		}
+		return "", nil, fmt.Errorf("user %q of current context not found in kubeconfig", kubeContext.AuthInfo)
+	}
+
+	if len(config.AuthInfos) == 1 {
+		for name, authInfo := range config.AuthInfos {
+			return name, authInfo, nil
+		}
+	}
+
+	return "", nil, errors.New("kubeconfig has no current context")
+}
+
+// getCertificateExpiry returns the earliest NotAfter of the PEM encoded certificates
+func getCertificateExpiry(data []byte) (time.Time, error) {
+	var notAfter time.Time
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "failed to parse client certificate")
+		}
+		if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+
+	if notAfter.IsZero() {
+		return time.Time{}, errors.New("client certificate data contains no certificate")
+	}
+	return notAfter, nil
+}
+
+type jwtClaims struct {
+	Exp int64  `json:"exp"`
+	Sub string `json:"sub"`
+}
+
+// getJWTClaims returns the claims of a JWT. Signature is not verified.
+func getJWTClaims(token string) (*jwtClaims, error) {
+	const jwtParts = 3
+	parts := strings.Split(token, ".")
+	if len(parts) != jwtParts {
+		return nil, errors.New("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode JWT payload")
+	}
+
+	claims := &jwtClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, errors.Wrap(err, "failed to parse JWT claims")
+	}
+	return claims, nil
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterproxy_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+)
+
+var _ = Describe("Credential expiry and rotation", func() {
+	const (
+		saNamespace = "projectsveltos"
+		saName      = "sveltos-applier"
+	)
+
+	getJWT := func(sub string, exp time.Time) string {
+		payload := fmt.Sprintf(`{"sub":%q,"exp":%d}`, sub, exp.Unix())
+		return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+	}
+
+	getCertificate := func(notAfter time.Time) []byte {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).To(BeNil())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "sveltos"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     notAfter,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).To(BeNil())
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+
+	getKubeconfig := func(authInfo *clientcmdapi.AuthInfo) []byte {
+		config := clientcmdapi.NewConfig()
+		config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: wiTestEndpoint}
+		config.AuthInfos["user"] = authInfo
+		config.Contexts["context"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+		config.CurrentContext = "context"
+		data, err := clientcmd.Write(*config)
+		Expect(err).To(BeNil())
+		return data
+	}
+
+	It("GetKubeconfigCredentialExpiry returns the earliest expiry between client certificate and token", func() {
+		certExpiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		tokenExpiry := time.Now().Add(time.Hour).Truncate(time.Second)
+
+		expiry, err := clusterproxy.GetKubeconfigCredentialExpiry(getKubeconfig(&clientcmdapi.AuthInfo{
+			ClientCertificateData: getCertificate(certExpiry),
+		}))
+		Expect(err).To(BeNil())
+		Expect(expiry).ToNot(BeNil())
+		Expect(expiry.AuthInfo).To(Equal("user"))
+		Expect(expiry.Source).To(Equal(clusterproxy.CredentialSourceClientCertificate))
+		Expect(expiry.ExpiresAt.Equal(certExpiry)).To(BeTrue())
+
+		expiry, err = clusterproxy.GetKubeconfigCredentialExpiry(getKubeconfig(&clientcmdapi.AuthInfo{
+			ClientCertificateData: getCertificate(certExpiry),
+			Token:                 getJWT("system:serviceaccount:a:b", tokenExpiry),
+		}))
+		Expect(err).To(BeNil())
+		Expect(expiry.Source).To(Equal(clusterproxy.CredentialSourceToken))
+		Expect(expiry.ExpiresAt.Equal(tokenExpiry)).To(BeTrue())
+	})
+
+	It("GetKubeconfigCredentialExpiry returns nil when expiry cannot be determined", func() {
+		expiry, err := clusterproxy.GetKubeconfigCredentialExpiry(getKubeconfig(&clientcmdapi.AuthInfo{
+			Token: "opaque-token",
+		}))
+		Expect(err).To(BeNil())
+		Expect(expiry).To(BeNil())
+	})
+
+	Context("RotateSveltosClusterCredentials", func() {
+		var (
+			namespace      string
+			sveltosCluster *libsveltosv1beta1.SveltosCluster
+			secret         *corev1.Secret
+			restoreRemote  func()
+		)
+
+		const interval = time.Hour
+
+		BeforeEach(func() {
+			namespace = randomString()
+			sveltosCluster = &libsveltosv1beta1.SveltosCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      randomString(),
+				},
+				Spec: libsveltosv1beta1.SveltosClusterSpec{
+					TokenRequestRenewalOption: &libsveltosv1beta1.TokenRequestRenewalOption{
+						RenewTokenRequestInterval: metav1.Duration{Duration: interval},
+					},
+				},
+			}
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      sveltosCluster.Name + clusterproxy.SveltosKubeconfigSecretNamePostfix,
+				},
+			}
+
+			s, err := setupScheme()
+			Expect(err).To(BeNil())
+			remoteClient := fake.NewClientBuilder().WithScheme(s).WithObjects(&corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{Namespace: saNamespace, Name: saName},
+			}).Build()
+			restoreRemote = clusterproxy.SetRemoteClientForTest(remoteClient)
+		})
+
+		AfterEach(func() {
+			restoreRemote()
+		})
+
+		getClient := func(tokenExpiry time.Time) client.Client {
+			secret.Data = map[string][]byte{
+				"kubeconfig": getKubeconfig(&clientcmdapi.AuthInfo{
+					Token: getJWT(fmt.Sprintf("system:serviceaccount:%s:%s", saNamespace, saName), tokenExpiry),
+				}),
+			}
+			s, err := setupScheme()
+			Expect(err).To(BeNil())
+			return fake.NewClientBuilder().WithScheme(s).WithObjects(sveltosCluster, secret).
+				WithStatusSubresource(&libsveltosv1beta1.SveltosCluster{}).Build()
+		}
+
+		It("does not renew credentials before renewal interval elapses", func() {
+			lastRenewal := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+			sveltosCluster.Status.LastReconciledTokenRequestAt = lastRenewal.Format(time.RFC3339)
+			c := getClient(time.Now().Add(10 * time.Hour))
+
+			rotation, err := clusterproxy.RotateSveltosClusterCredentials(context.TODO(), logr.Discard(), c,
+				sveltosCluster.Namespace, sveltosCluster.Name)
+			Expect(err).To(BeNil())
+			Expect(rotation.Rotated).To(BeFalse())
+			Expect(rotation.NextRotation.Equal(lastRenewal.Add(interval))).To(BeTrue())
+			Expect(rotation.Expiry).ToNot(BeNil())
+		})
+
+		It("renews credentials about to expire and stores the renewed kubeconfig", func() {
+			sveltosCluster.Status.LastReconciledTokenRequestAt = time.Now().UTC().Format(time.RFC3339)
+			c := getClient(time.Now().Add(2 * time.Minute))
+
+			rotation, err := clusterproxy.RotateSveltosClusterCredentials(context.TODO(), logr.Discard(), c,
+				sveltosCluster.Namespace, sveltosCluster.Name)
+			Expect(err).To(BeNil())
+			Expect(rotation.Rotated).To(BeTrue())
+			Expect(rotation.Expiry).ToNot(BeNil())
+			// Fake TokenRequest returns an opaque token, so expiry comes from the TokenRequest status
+			Expect(rotation.Expiry.ExpiresAt.After(time.Now().Add(interval))).To(BeTrue())
+			Expect(rotation.NextRotation).To(BeTemporally("~", time.Now().Add(interval), time.Minute))
+
+			currentSecret := &corev1.Secret{}
+			Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(secret), currentSecret)).To(Succeed())
+			Expect(currentSecret.Data).To(HaveKey("re-kubeconfig"))
+			renewed, err := clientcmd.Load(currentSecret.Data["re-kubeconfig"])
+			Expect(err).To(BeNil())
+			Expect(renewed.AuthInfos["user"].Token).To(Equal("fake-token"))
+			Expect(renewed.Clusters["cluster"].Server).To(Equal(wiTestEndpoint))
+
+			currentCluster := &libsveltosv1beta1.SveltosCluster{}
+			Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(sveltosCluster), currentCluster)).To(Succeed())
+			Expect(currentCluster.Spec.KubeconfigKeyName).To(Equal("re-kubeconfig"))
+			Expect(currentCluster.Status.LastReconciledTokenRequestAt).ToNot(BeEmpty())
+		})
+
+		It("only reports expiry when TokenRequestRenewalOption is not set", func() {
+			sveltosCluster.Spec.TokenRequestRenewalOption = nil
+			tokenExpiry := time.Now().Add(time.Minute).Truncate(time.Second)
+			c := getClient(tokenExpiry)
+
+			rotation, err := clusterproxy.RotateSveltosClusterCredentials(context.TODO(), logr.Discard(), c,
+				sveltosCluster.Namespace, sveltosCluster.Name)
+			Expect(err).To(BeNil())
+			Expect(rotation.Rotated).To(BeFalse())
+			Expect(rotation.NextRotation.IsZero()).To(BeTrue())
+			Expect(rotation.Expiry.ExpiresAt.Equal(tokenExpiry)).To(BeTrue())
+		})
+	})
+})
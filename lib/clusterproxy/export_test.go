@@ -19,7 +19,9 @@ package clusterproxy
 import (
 	"time"
 
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
@@ -41,7 +43,8 @@ func StoreTestWiCache(namespace, name string, cfg *rest.Config, expiresAt time.T
 	wi *libsveltosv1beta1.WorkloadIdentityConfig) {
 
 	specHash, _ := workloadIdentityConfigHash(wi)
-	wiCache.Store(wiCacheKey(namespace, name), cachedRestConfig{config: cfg, expiresAt: expiresAt, specHash: specHash})
+	wiCache.Store(wiCacheKey(namespace, name), cachedRestConfig{config: cfg, expiresAt: expiresAt,
+		refreshAt: getRefreshTime(time.Now(), expiresAt), specHash: specHash})
 }
 
 // WorkloadIdentityConfigHashForTest calls the internal workloadIdentityConfigHash function.
@@ -62,3 +65,14 @@ func LoadTestWiCache(namespace, name string) (*rest.Config, time.Time, bool) {
 // GetCADataForTest calls the internal getCAData function.
 // For use in tests only.
 var GetCADataForTest = getCAData
+
+// SetRemoteClientForTest makes RotateSveltosClusterCredentials use c to request tokens
+// to the managed cluster. Returned function restores the default.
+// For use in tests only.
+func SetRemoteClientForTest(c client.Client) func() {
+	original := newRemoteClient
+	newRemoteClient = func(_ *rest.Config, _ *runtime.Scheme) (client.Client, error) {
+		return c, nil
+	}
+	return func() { newRemoteClient = original }
+}
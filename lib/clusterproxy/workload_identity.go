@@ -17,13 +17,21 @@ limitations under the License.
 package clusterproxy
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,6 +48,8 @@ import (
 	"golang.org/x/sync/singleflight"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthenticationv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -49,6 +59,7 @@ import (
 
 const (
 	// wiRefreshThreshold is how much time before expiry we proactively refresh.
+	// Credentials living less than twice as long are refreshed half way through their lifetime.
 	wiRefreshThreshold = 5 * time.Minute
 
 	//nolint:gosec // this is a token format prefix, not a credential
@@ -63,11 +74,37 @@ const (
 	gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
 
 	caSecretKey = "ca.crt"
+
+	oidcGrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	oidcTokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+	oidcTokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+
+	oidcTokenEndpointTimeout = 30 * time.Second
+
+	execInfoEnv = "KUBERNETES_EXEC_INFO"
+
+	execCredentialKind = "ExecCredential"
+
+	// execCommandTimeout is the maximum time an exec credential plugin can run
+	execCommandTimeout = time.Minute
+
+	// execWaitDelay is how long to wait, once an exec credential plugin exited or was killed, for
+	// processes it started and which still hold its output open
+	execWaitDelay = 5 * time.Second
+
+	// execMaxOutputSize limits the output read from an exec credential plugin
+	execMaxOutputSize = 1024 * 1024
+
+	// execNoExpiryRefreshInterval is how long credentials without expirationTimestamp are
+	// cached before the plugin is run again
+	execNoExpiryRefreshInterval = time.Hour
 )
 
 type cachedRestConfig struct {
 	config    *rest.Config
 	expiresAt time.Time
+	// refreshAt is when this entry stops being served and credentials are refreshed
+	refreshAt time.Time
 	// specHash is the hash of the WorkloadIdentityConfig this entry was built from.
 	// A mismatch means spec.workloadIdentity changed since this entry was cached, so
 	// it must not be reused even though it has not expired yet.
@@ -77,8 +114,131 @@ type cachedRestConfig struct {
 var (
 	wiCache sync.Map           // map[string]cachedRestConfig
 	wiGroup singleflight.Group // deduplicates concurrent refreshes for the same cluster
+
+	allowedExecCommandsMux sync.RWMutex
+	allowedExecCommands    []AllowedExecCommand
+
+	// execInheritedEnv are the environment variables exec credential plugins inherit
+	execInheritedEnv = []string{"PATH", "HOME"}
+
+	oidcSubjectTokenPathMux sync.RWMutex
+	oidcSubjectTokenPath    string
+
+	oidcTokenEndpointsMux sync.RWMutex
+	oidcTokenEndpoints    []string
 )
 
+// SetOIDCSubjectTokenPath sets the path of the token SveltosClusters with workload identity
+// provider OIDC present to the token endpoint. It must be a service account token projected
+// in the Sveltos pod with the OIDC provider as audience, not the default pod service account
+// token, whose audience is the management cluster API server.
+// By default no path is set and OIDC workload identity fails. This is meant to be called once,
+// when a component starts, from configuration controlled by the Sveltos administrator.
+func SetOIDCSubjectTokenPath(path string) {
+	oidcSubjectTokenPathMux.Lock()
+	defer oidcSubjectTokenPathMux.Unlock()
+
+	oidcSubjectTokenPath = path
+}
+
+func getOIDCSubjectTokenPath() string {
+	oidcSubjectTokenPathMux.RLock()
+	defer oidcSubjectTokenPathMux.RUnlock()
+
+	return oidcSubjectTokenPath
+}
+
+// SetOIDCTokenEndpoints sets the token endpoints SveltosClusters with workload identity provider
+// OIDC can use. The projected token is presented only to one of those, matched exactly, so a
+// SveltosCluster cannot have it sent elsewhere.
+// By default nothing is allowed. This is meant to be called once, when a component starts,
+// from configuration controlled by the Sveltos administrator.
+func SetOIDCTokenEndpoints(endpoints []string) {
+	oidcTokenEndpointsMux.Lock()
+	defer oidcTokenEndpointsMux.Unlock()
+
+	oidcTokenEndpoints = slices.Clone(endpoints)
+}
+
+func isOIDCTokenEndpointAllowed(endpoint string) bool {
+	oidcTokenEndpointsMux.RLock()
+	defer oidcTokenEndpointsMux.RUnlock()
+
+	return slices.Contains(oidcTokenEndpoints, endpoint)
+}
+
+// AllowedExecCommand is an exec credential plugin invocation SveltosClusters with workload
+// identity provider Exec can request
+type AllowedExecCommand struct {
+	// Command is the plugin executable, matched exactly
+	Command string
+
+	// Args are the arguments the plugin is run with, matched exactly and in order
+	Args []string
+
+	// Env are the names of the environment variables a SveltosCluster can set
+	Env []string
+}
+
+// SetAllowedExecCommands sets the exec credential plugins SveltosClusters with workload
+// identity provider Exec can run. A plugin is run only if one entry matches its command
+// and arguments and allows all the environment variables set by the SveltosCluster.
+// By default nothing is allowed. This is meant to be called once, when a component starts,
+// from configuration controlled by the Sveltos administrator.
+func SetAllowedExecCommands(commands []AllowedExecCommand) {
+	allowedExecCommandsMux.Lock()
+	defer allowedExecCommandsMux.Unlock()
+
+	allowedExecCommands = make([]AllowedExecCommand, len(commands))
+	for i := range commands {
+		allowedExecCommands[i] = AllowedExecCommand{
+			Command: commands[i].Command,
+			Args:    slices.Clone(commands[i].Args),
+			Env:     slices.Clone(commands[i].Env),
+		}
+	}
+}
+
+func isExecCommandAllowed(execCfg *libsveltosv1beta1.ExecWorkloadIdentityConfig) bool {
+	allowedExecCommandsMux.RLock()
+	defer allowedExecCommandsMux.RUnlock()
+
+	for i := range allowedExecCommands {
+		allowed := &allowedExecCommands[i]
+		if allowed.Command != execCfg.Command || !slices.Equal(allowed.Args, execCfg.Args) {
+			continue
+		}
+
+		envAllowed := true
+		for j := range execCfg.Env {
+			if !slices.Contains(allowed.Env, execCfg.Env[j].Name) {
+				envAllowed = false
+				break
+			}
+		}
+		if envAllowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getExecEnv returns the environment the plugin is run with: only the variables in
+// execInheritedEnv are inherited from the Sveltos process
+func getExecEnv(execCfg *libsveltosv1beta1.ExecWorkloadIdentityConfig, execInfo string) []string {
+	env := make([]string, 0, len(execInheritedEnv)+len(execCfg.Env)+1)
+	for i := range execInheritedEnv {
+		if value, ok := os.LookupEnv(execInheritedEnv[i]); ok {
+			env = append(env, fmt.Sprintf("%s=%s", execInheritedEnv[i], value))
+		}
+	}
+	for i := range execCfg.Env {
+		env = append(env, fmt.Sprintf("%s=%s", execCfg.Env[i].Name, execCfg.Env[i].Value))
+	}
+	return append(env, fmt.Sprintf("%s=%s", execInfoEnv, execInfo))
+}
+
 // EvictWorkloadIdentityCache removes the cached rest.Config for the given
 // SveltosCluster. Call this from the SveltosCluster delete handler in any
 // component that uses workload identity.
@@ -126,7 +286,7 @@ func getWorkloadIdentityRestConfig(
 	// Fast path: valid cached entry for the current spec.
 	if v, ok := wiCache.Load(key); ok {
 		entry := v.(cachedRestConfig)
-		if entry.specHash == specHash && time.Until(entry.expiresAt) > wiRefreshThreshold {
+		if entry.specHash == specHash && time.Now().Before(entry.refreshAt) {
 			return entry.config, nil
 		}
 	}
@@ -151,6 +311,10 @@ func getWorkloadIdentityRestConfig(
 			cfg, expiresAt, err = getGCPRestConfig(ctx, wi, caData, logger)
 		case libsveltosv1beta1.WorkloadIdentityProviderAzure:
 			cfg, expiresAt, err = getAzureRestConfig(ctx, wi, caData, logger)
+		case libsveltosv1beta1.WorkloadIdentityProviderOIDC:
+			cfg, expiresAt, err = getOIDCRestConfig(ctx, c, clusterNamespace, wi, caData, logger)
+		case libsveltosv1beta1.WorkloadIdentityProviderExec:
+			cfg, expiresAt, err = getExecRestConfig(ctx, wi, caData, logger)
 		default:
 			err = fmt.Errorf("unknown workload identity provider %q", wi.Provider)
 		}
@@ -158,7 +322,8 @@ func getWorkloadIdentityRestConfig(
 			return nil, err
 		}
 
-		wiCache.Store(key, cachedRestConfig{config: cfg, expiresAt: expiresAt, specHash: specHash})
+		wiCache.Store(key, cachedRestConfig{config: cfg, expiresAt: expiresAt,
+			refreshAt: getRefreshTime(time.Now(), expiresAt), specHash: specHash})
 		return result{cfg: cfg}, nil
 	})
 	if err != nil {
@@ -168,6 +333,14 @@ func getWorkloadIdentityRestConfig(
 	return val.(result).cfg, nil
 }
 
+// getRefreshTime returns when credentials expiring at expiresAt must be refreshed: wiRefreshThreshold
+// before expiry, or half way through their lifetime if shorter, so short-lived credentials are still
+// served from the cache.
+func getRefreshTime(now, expiresAt time.Time) time.Time {
+	halfLifetime := max(expiresAt.Sub(now), 0) / 2
+	return expiresAt.Add(-min(wiRefreshThreshold, halfLifetime))
+}
+
 // getCAData fetches the CA certificate bytes from the referenced Secret.
 // If caSecretRef is nil, nil is returned and the system certificate pool is used.
 func getCAData(
@@ -363,3 +536,382 @@ func getAzureRestConfig(
 	logger.V(logs.LogDebug).Info("obtained Azure AAD token", "expiresAt", tokenResp.ExpiresOn)
 	return buildRestConfig(wi.Endpoint, tokenResp.Token, caData), tokenResp.ExpiresOn, nil
 }
+
+// ── OIDC ──────────────────────────────────────────────────────────────────────
+
+// oidcTokenResponse is the token endpoint response defined by RFC 8693 section 2.2.1
+type oidcTokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// oidcErrorResponse is the token endpoint error response defined by RFC 6749 section 5.2
+type oidcErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func getOIDCRestConfig(
+	ctx context.Context,
+	c client.Client,
+	clusterNamespace string,
+	wi *libsveltosv1beta1.WorkloadIdentityConfig,
+	caData []byte,
+	logger logr.Logger,
+) (*rest.Config, time.Time, error) {
+
+	oidcCfg := wi.OIDC
+	if oidcCfg == nil {
+		return nil, time.Time{}, errors.New("oidc configuration is required when provider is OIDC")
+	}
+
+	if !isOIDCTokenEndpointAllowed(oidcCfg.TokenEndpoint) {
+		return nil, time.Time{}, fmt.Errorf("oidc token endpoint %s is not allowed", oidcCfg.TokenEndpoint)
+	}
+
+	subjectTokenPath := getOIDCSubjectTokenPath()
+	if subjectTokenPath == "" {
+		return nil, time.Time{}, errors.New("oidc subject token path is not configured")
+	}
+	subjectToken, err := os.ReadFile(subjectTokenPath)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err,
+			fmt.Sprintf("failed to read subject token file %s", subjectTokenPath))
+	}
+
+	clientSecret, err := getOIDCClientSecret(ctx, c, clusterNamespace, oidcCfg.ClientSecretRef)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	tokenEndpointCA, err := getCAData(ctx, c, clusterNamespace, oidcCfg.TokenEndpointCASecretRef, logger)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	httpClient, err := getOIDCHTTPClient(tokenEndpointCA)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	token, err := exchangeOIDCToken(ctx, httpClient, oidcCfg, strings.TrimSpace(string(subjectToken)), clientSecret)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	expiresAt, err := getOIDCTokenExpiry(token, time.Now())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	logger.V(logs.LogDebug).Info("obtained OIDC token via token exchange",
+		"tokenEndpoint", oidcCfg.TokenEndpoint, "expiresAt", expiresAt)
+	return buildRestConfig(wi.Endpoint, token.AccessToken, caData), expiresAt, nil
+}
+
+// exchangeOIDCToken performs an OAuth 2.0 Token Exchange (RFC 8693) request
+func exchangeOIDCToken(
+	ctx context.Context,
+	httpClient *http.Client,
+	oidcCfg *libsveltosv1beta1.OIDCWorkloadIdentityConfig,
+	subjectToken, clientSecret string,
+) (*oidcTokenResponse, error) {
+
+	subjectTokenType := oidcCfg.SubjectTokenType
+	if subjectTokenType == "" {
+		subjectTokenType = oidcTokenTypeJWT
+	}
+	requestedTokenType := oidcCfg.RequestedTokenType
+	if requestedTokenType == "" {
+		requestedTokenType = oidcTokenTypeAccessToken
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", oidcGrantTypeTokenExchange)
+	form.Set("subject_token", subjectToken)
+	form.Set("subject_token_type", subjectTokenType)
+	form.Set("requested_token_type", requestedTokenType)
+	if oidcCfg.Audience != "" {
+		form.Set("audience", oidcCfg.Audience)
+	}
+	if len(oidcCfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(oidcCfg.Scopes, " "))
+	}
+	if oidcCfg.ClientID != "" && clientSecret == "" {
+		// Public client identifies itself in the request body
+		form.Set("client_id", oidcCfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oidcCfg.TokenEndpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build token exchange request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if oidcCfg.ClientID != "" && clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(oidcCfg.ClientID), url.QueryEscape(clientSecret))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "token exchange request failed")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read token exchange response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := &oidcErrorResponse{}
+		if json.Unmarshal(body, errResp) == nil && errResp.Error != "" {
+			return nil, fmt.Errorf("token exchange failed with status %d: %s: %s",
+				resp.StatusCode, errResp.Error, errResp.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token exchange failed with status %d", resp.StatusCode)
+	}
+
+	token := &oidcTokenResponse{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, errors.Wrap(err, "failed to parse token exchange response")
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("token exchange response does not contain access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "Bearer") &&
+		!strings.EqualFold(token.TokenType, "N_A") {
+
+		return nil, fmt.Errorf("token exchange returned unsupported token_type %q", token.TokenType)
+	}
+
+	return token, nil
+}
+
+// getOIDCTokenExpiry returns when the issued token expires. expires_in is used when present,
+// otherwise the exp claim is read from the token if it is a JWT.
+func getOIDCTokenExpiry(token *oidcTokenResponse, now time.Time) (time.Time, error) {
+	if token.ExpiresIn > 0 {
+		return now.Add(time.Duration(token.ExpiresIn) * time.Second), nil
+	}
+
+	claims, err := getJWTClaims(token.AccessToken)
+	if err == nil && claims.Exp > 0 {
+		return time.Unix(claims.Exp, 0), nil
+	}
+
+	return time.Time{}, errors.New("token exchange response has no expires_in and token expiry cannot be determined")
+}
+
+// getOIDCClientSecret returns the OAuth client secret. Returns an empty string if
+// secretRef is nil.
+func getOIDCClientSecret(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	secretRef *corev1.SecretKeySelector,
+) (string, error) {
+
+	if secretRef == nil {
+		return "", nil
+	}
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: namespace, Name: secretRef.Name}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return "", errors.Wrap(err,
+			fmt.Sprintf("failed to get OIDC client secret %s/%s", namespace, secretRef.Name))
+	}
+
+	value, ok := secret.Data[secretRef.Key]
+	if !ok {
+		return "", fmt.Errorf("OIDC client secret %s/%s has no %q key", namespace, secretRef.Name, secretRef.Key)
+	}
+	return string(value), nil
+}
+
+// getOIDCHTTPClient returns the HTTP client used to contact the token endpoint.
+// If caData is nil, the system certificate pool is used.
+func getOIDCHTTPClient(caData []byte) (*http.Client, error) {
+	httpClient := &http.Client{
+		Timeout: oidcTokenEndpointTimeout,
+		// Redirects are not followed, or the subject token could be sent to a token endpoint not allowed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if caData == nil {
+		return httpClient, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, errors.New("failed to parse token endpoint CA certificate")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	httpClient.Transport = transport
+	return httpClient, nil
+}
+
+// ── Exec ──────────────────────────────────────────────────────────────────────
+
+func getExecRestConfig(
+	ctx context.Context,
+	wi *libsveltosv1beta1.WorkloadIdentityConfig,
+	caData []byte,
+	logger logr.Logger,
+) (*rest.Config, time.Time, error) {
+
+	execCfg := wi.Exec
+	if execCfg == nil {
+		return nil, time.Time{}, errors.New("exec configuration is required when provider is Exec")
+	}
+
+	if !isExecCommandAllowed(execCfg) {
+		return nil, time.Time{}, fmt.Errorf("exec credential plugin %q is not allowed with the given args and env",
+			execCfg.Command)
+	}
+
+	apiVersion := execCfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = clientauthenticationv1.SchemeGroupVersion.String()
+	}
+	if apiVersion != clientauthenticationv1.SchemeGroupVersion.String() {
+		return nil, time.Time{}, fmt.Errorf("exec credential plugin apiVersion %q is not supported", apiVersion)
+	}
+
+	execInfo, err := getExecInfo(wi, caData)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, execCommandTimeout)
+	defer cancel()
+
+	//nolint:gosec // command and args are checked against the allowlist set by the Sveltos administrator
+	cmd := exec.CommandContext(ctx, execCfg.Command, execCfg.Args...)
+	cmd.Env = getExecEnv(execCfg, execInfo)
+	cmd.WaitDelay = execWaitDelay
+
+	stdout := &limitedBuffer{limit: execMaxOutputSize}
+	stderr := &limitedBuffer{limit: execMaxOutputSize}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, time.Time{}, errors.Wrap(err,
+			fmt.Sprintf("exec credential plugin %q failed: %s", execCfg.Command, strings.TrimSpace(stderr.String())))
+	}
+	if stdout.exceeded {
+		return nil, time.Time{}, fmt.Errorf("exec credential plugin %q output exceeds maximum size of %d bytes",
+			execCfg.Command, execMaxOutputSize)
+	}
+
+	status, err := parseExecCredential(stdout.Bytes(), apiVersion)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err,
+			fmt.Sprintf("invalid output from exec credential plugin %q", execCfg.Command))
+	}
+
+	cfg := buildRestConfig(wi.Endpoint, status.Token, caData)
+	if status.ClientCertificateData != "" {
+		cfg.CertData = []byte(status.ClientCertificateData)
+		cfg.KeyData = []byte(status.ClientKeyData)
+	}
+
+	expiresAt := time.Now().Add(execNoExpiryRefreshInterval)
+	if status.ExpirationTimestamp != nil {
+		expiresAt = status.ExpirationTimestamp.Time
+	}
+
+	logger.V(logs.LogDebug).Info("obtained credentials from exec plugin",
+		"command", execCfg.Command, "expiresAt", expiresAt)
+	return cfg, expiresAt, nil
+}
+
+// limitedBuffer stores up to limit bytes and discards the rest, recording that it did.
+// bytes.Buffer is not embedded, or its ReadFrom method would let io.Copy bypass the limit.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if available := b.limit - b.buf.Len(); len(p) > available {
+		b.exceeded = true
+		b.buf.Write(p[:max(available, 0)])
+		// Consume everything so the plugin is not blocked writing its output
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+// getExecInfo returns the ExecCredential passed to the plugin via KUBERNETES_EXEC_INFO
+func getExecInfo(wi *libsveltosv1beta1.WorkloadIdentityConfig, caData []byte) (string, error) {
+	execInfo := &clientauthenticationv1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clientauthenticationv1.SchemeGroupVersion.String(),
+			Kind:       execCredentialKind,
+		},
+		Spec: clientauthenticationv1.ExecCredentialSpec{
+			Interactive: false,
+		},
+	}
+	if wi.Exec.ProvideClusterInfo {
+		execInfo.Spec.Cluster = &clientauthenticationv1.Cluster{
+			Server:                   wi.Endpoint,
+			CertificateAuthorityData: caData,
+		}
+	}
+
+	data, err := json.Marshal(execInfo)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal exec credential info")
+	}
+	return string(data), nil
+}
+
+// parseExecCredential parses the ExecCredential printed by the plugin on stdout
+func parseExecCredential(data []byte, apiVersion string) (*clientauthenticationv1.ExecCredentialStatus, error) {
+	execCredential := &clientauthenticationv1.ExecCredential{}
+	if err := json.Unmarshal(data, execCredential); err != nil {
+		return nil, errors.Wrap(err, "failed to parse ExecCredential")
+	}
+
+	if execCredential.APIVersion != apiVersion || execCredential.Kind != execCredentialKind {
+		return nil, fmt.Errorf("expected %s %s, got %s %s", apiVersion, execCredentialKind,
+			execCredential.APIVersion, execCredential.Kind)
+	}
+
+	status := execCredential.Status
+	if status == nil {
+		return nil, errors.New("ExecCredential has no status")
+	}
+
+	hasToken := status.Token != ""
+	hasCert := status.ClientCertificateData != "" || status.ClientKeyData != ""
+	if hasCert && (status.ClientCertificateData == "" || status.ClientKeyData == "") {
+		return nil, errors.New("ExecCredential must contain both clientCertificateData and clientKeyData")
+	}
+	if !hasToken && !hasCert {
+		return nil, errors.New("ExecCredential contains neither token nor client certificate")
+	}
+
+	return status, nil
+}
@@ -17,6 +17,15 @@ limitations under the License.
 package clusterproxy_test
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -26,6 +35,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
@@ -209,3 +219,362 @@ var _ = Describe("WorkloadIdentity CA secret", func() {
 		Expect(err.Error()).To(ContainSubstring("missing-ca"))
 	})
 })
+
+var _ = Describe("WorkloadIdentity OIDC token exchange", func() {
+	const (
+		subjectToken = "projected-sa-token"
+		clientID     = "sveltos"
+		clientSecret = "client-secret"
+		audience     = "managed-cluster"
+	)
+
+	var (
+		server         *httptest.Server
+		calls          atomic.Int32
+		tokenResponse  map[string]interface{}
+		statusCode     int
+		lastForm       map[string]string
+		lastBasicUser  string
+		lastBasicPass  string
+		namespace      string
+		name           string
+		subjectPath    string
+		sveltosCluster *libsveltosv1beta1.SveltosCluster
+	)
+
+	BeforeEach(func() {
+		calls.Store(0)
+		statusCode = http.StatusOK
+		tokenResponse = map[string]interface{}{
+			"access_token":      "exchanged-token",
+			"issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+			"token_type":        "Bearer",
+			"expires_in":        3600,
+		}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			Expect(r.ParseForm()).To(Succeed())
+			lastForm = map[string]string{}
+			for k := range r.PostForm {
+				lastForm[k] = r.PostForm.Get(k)
+			}
+			lastBasicUser, lastBasicPass, _ = r.BasicAuth()
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statusCode)
+			Expect(json.NewEncoder(w).Encode(tokenResponse)).To(Succeed())
+		}))
+
+		subjectPath = filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(subjectPath, []byte(subjectToken+"\n"), 0o600)).To(Succeed())
+		clusterproxy.SetOIDCSubjectTokenPath(subjectPath)
+		clusterproxy.SetOIDCTokenEndpoints([]string{server.URL})
+
+		namespace = randomString()
+		name = randomString()
+		sveltosCluster = &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+			},
+			Spec: libsveltosv1beta1.SveltosClusterSpec{
+				WorkloadIdentity: &libsveltosv1beta1.WorkloadIdentityConfig{
+					Provider: libsveltosv1beta1.WorkloadIdentityProviderOIDC,
+					Endpoint: wiTestEndpoint,
+					OIDC: &libsveltosv1beta1.OIDCWorkloadIdentityConfig{
+						TokenEndpoint: server.URL,
+						ClientID:      clientID,
+						Audience:      audience,
+						Scopes:        []string{"openid", "groups"},
+					},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		clusterproxy.SetOIDCSubjectTokenPath("")
+		clusterproxy.SetOIDCTokenEndpoints(nil)
+		clusterproxy.EvictWorkloadIdentityCache(namespace, name)
+	})
+
+	getRestConfig := func(objects ...client.Object) (*rest.Config, error) {
+		s, err := setupScheme()
+		Expect(err).To(BeNil())
+		objects = append(objects, sveltosCluster)
+		c := fake.NewClientBuilder().WithScheme(s).WithObjects(objects...).Build()
+		return clusterproxy.GetSveltosKubernetesRestConfig(context.TODO(), logr.Discard(), c, namespace, name)
+	}
+
+	It("fails when no subject token path is configured", func() {
+		clusterproxy.SetOIDCSubjectTokenPath("")
+
+		_, err := getRestConfig()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("subject token path is not configured"))
+		Expect(calls.Load()).To(BeZero())
+	})
+
+	It("fails when the token endpoint is not allowed", func() {
+		clusterproxy.SetOIDCTokenEndpoints([]string{server.URL + "/other"})
+
+		_, err := getRestConfig()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("is not allowed"))
+		Expect(calls.Load()).To(BeZero())
+	})
+
+	It("exchanges the projected service account token and caches the result", func() {
+		cfg, err := getRestConfig()
+		Expect(err).To(BeNil())
+		Expect(cfg.Host).To(Equal(wiTestEndpoint))
+		Expect(cfg.BearerToken).To(Equal("exchanged-token"))
+
+		Expect(lastForm).To(HaveKeyWithValue("grant_type", "urn:ietf:params:oauth:grant-type:token-exchange"))
+		Expect(lastForm).To(HaveKeyWithValue("subject_token", subjectToken))
+		Expect(lastForm).To(HaveKeyWithValue("subject_token_type", "urn:ietf:params:oauth:token-type:jwt"))
+		Expect(lastForm).To(HaveKeyWithValue("requested_token_type",
+			"urn:ietf:params:oauth:token-type:access_token"))
+		Expect(lastForm).To(HaveKeyWithValue("audience", audience))
+		Expect(lastForm).To(HaveKeyWithValue("scope", "openid groups"))
+		// Public client
+		Expect(lastForm).To(HaveKeyWithValue("client_id", clientID))
+		Expect(lastBasicUser).To(BeEmpty())
+
+		_, expiresAt, ok := clusterproxy.LoadTestWiCache(namespace, name)
+		Expect(ok).To(BeTrue())
+		Expect(time.Until(expiresAt)).To(BeNumerically("~", time.Hour, time.Minute))
+
+		// Second call is served from cache
+		cfg, err = getRestConfig()
+		Expect(err).To(BeNil())
+		Expect(cfg.BearerToken).To(Equal("exchanged-token"))
+		Expect(calls.Load()).To(Equal(int32(1)))
+	})
+
+	It("serves short-lived tokens from the cache", func() {
+		tokenResponse["expires_in"] = 300
+
+		_, err := getRestConfig()
+		Expect(err).To(BeNil())
+		_, err = getRestConfig()
+		Expect(err).To(BeNil())
+		Expect(calls.Load()).To(Equal(int32(1)))
+	})
+
+	It("authenticates with client secret when configured", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      randomString(),
+			},
+			Data: map[string][]byte{"secret": []byte(clientSecret)},
+		}
+		sveltosCluster.Spec.WorkloadIdentity.OIDC.ClientSecretRef = &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+			Key:                  "secret",
+		}
+
+		_, err := getRestConfig(secret)
+		Expect(err).To(BeNil())
+		Expect(lastBasicUser).To(Equal(clientID))
+		Expect(lastBasicPass).To(Equal(clientSecret))
+		Expect(lastForm).ToNot(HaveKey("client_id"))
+	})
+
+	It("uses the token exp claim when expires_in is missing", func() {
+		exp := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+		payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+		tokenResponse["access_token"] = "header." + payload + ".signature"
+		delete(tokenResponse, "expires_in")
+
+		_, err := getRestConfig()
+		Expect(err).To(BeNil())
+
+		_, expiresAt, ok := clusterproxy.LoadTestWiCache(namespace, name)
+		Expect(ok).To(BeTrue())
+		Expect(expiresAt.Equal(exp)).To(BeTrue())
+	})
+
+	It("returns the token endpoint error", func() {
+		statusCode = http.StatusBadRequest
+		tokenResponse = map[string]interface{}{
+			"error":             "invalid_grant",
+			"error_description": "subject token is expired",
+		}
+
+		_, err := getRestConfig()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("invalid_grant"))
+		Expect(err.Error()).To(ContainSubstring("subject token is expired"))
+
+		_, _, ok := clusterproxy.LoadTestWiCache(namespace, name)
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("WorkloadIdentity exec credential plugin", func() {
+	var (
+		namespace      string
+		name           string
+		pluginPath     string
+		sveltosCluster *libsveltosv1beta1.SveltosCluster
+	)
+
+	writePlugin := func(output string) {
+		script := "#!/bin/sh\ncat <<EOF\n" + output + "\nEOF\n"
+		//nolint:gosec // test plugin must be executable
+		Expect(os.WriteFile(pluginPath, []byte(script), 0o700)).To(Succeed())
+	}
+
+	getRestConfig := func() (*rest.Config, error) {
+		s, err := setupScheme()
+		Expect(err).To(BeNil())
+		c := fake.NewClientBuilder().WithScheme(s).WithObjects(sveltosCluster).Build()
+		return clusterproxy.GetSveltosKubernetesRestConfig(context.TODO(), logr.Discard(), c, namespace, name)
+	}
+
+	BeforeEach(func() {
+		namespace = randomString()
+		name = randomString()
+		pluginPath = filepath.Join(GinkgoT().TempDir(), "plugin")
+		sveltosCluster = &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+			},
+			Spec: libsveltosv1beta1.SveltosClusterSpec{
+				WorkloadIdentity: &libsveltosv1beta1.WorkloadIdentityConfig{
+					Provider: libsveltosv1beta1.WorkloadIdentityProviderExec,
+					Endpoint: wiTestEndpoint,
+					Exec: &libsveltosv1beta1.ExecWorkloadIdentityConfig{
+						Command: pluginPath,
+						Env: []libsveltosv1beta1.ExecEnvVar{
+							{Name: "PLUGIN_TOKEN", Value: "token-from-env"},
+						},
+					},
+				},
+			},
+		}
+		clusterproxy.SetAllowedExecCommands([]clusterproxy.AllowedExecCommand{
+			{Command: pluginPath, Env: []string{"PLUGIN_TOKEN"}},
+		})
+	})
+
+	AfterEach(func() {
+		clusterproxy.SetAllowedExecCommands(nil)
+		clusterproxy.EvictWorkloadIdentityCache(namespace, name)
+	})
+
+	It("runs the plugin and honors expirationTimestamp", func() {
+		expiration := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+		writePlugin(fmt.Sprintf(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential",`+
+			`"status":{"token":"$PLUGIN_TOKEN","expirationTimestamp":%q}}`, expiration.Format(time.RFC3339)))
+
+		cfg, err := getRestConfig()
+		Expect(err).To(BeNil())
+		Expect(cfg.Host).To(Equal(wiTestEndpoint))
+		Expect(cfg.BearerToken).To(Equal("token-from-env"))
+
+		_, expiresAt, ok := clusterproxy.LoadTestWiCache(namespace, name)
+		Expect(ok).To(BeTrue())
+		Expect(expiresAt.Equal(expiration)).To(BeTrue())
+	})
+
+	It("serves short-lived credentials from the cache", func() {
+		expiration := time.Now().Add(4 * time.Minute).UTC().Truncate(time.Second)
+		// Each run returns a different token
+		writePlugin(fmt.Sprintf(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential",`+
+			`"status":{"token":"$(date +%%s%%N)","expirationTimestamp":%q}}`, expiration.Format(time.RFC3339)))
+
+		cfg, err := getRestConfig()
+		Expect(err).To(BeNil())
+		cached, err := getRestConfig()
+		Expect(err).To(BeNil())
+		Expect(cached.BearerToken).To(Equal(cfg.BearerToken))
+	})
+
+	It("returns an error when the plugin output exceeds the maximum size", func() {
+		script := "#!/bin/sh\nhead -c 2000000 /dev/zero\n"
+		//nolint:gosec // test plugin must be executable
+		Expect(os.WriteFile(pluginPath, []byte(script), 0o700)).To(Succeed())
+
+		_, err := getRestConfig()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("exceeds maximum size"))
+	})
+
+	It("passes cluster info to the plugin when requested", func() {
+		sveltosCluster.Spec.WorkloadIdentity.Exec.ProvideClusterInfo = true
+		writePlugin(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential",` +
+			`"status":{"token":"$(echo $KUBERNETES_EXEC_INFO | base64 | tr -d '\n')"}}`)
+
+		cfg, err := getRestConfig()
+		Expect(err).To(BeNil())
+
+		execInfo, err := base64.StdEncoding.DecodeString(cfg.BearerToken)
+		Expect(err).To(BeNil())
+		Expect(string(execInfo)).To(ContainSubstring(`"kind":"ExecCredential"`))
+		Expect(string(execInfo)).To(ContainSubstring(wiTestEndpoint))
+	})
+
+	It("refuses commands not in the allowlist", func() {
+		writePlugin(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential",` +
+			`"status":{"token":"token"}}`)
+		clusterproxy.SetAllowedExecCommands([]clusterproxy.AllowedExecCommand{
+			{Command: "/usr/local/bin/kubelogin", Env: []string{"PLUGIN_TOKEN"}},
+		})
+
+		_, err := getRestConfig()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("is not allowed"))
+	})
+
+	It("refuses args and env not in the allowlist", func() {
+		writePlugin(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential",` +
+			`"status":{"token":"token"}}`)
+		clusterproxy.SetAllowedExecCommands([]clusterproxy.AllowedExecCommand{
+			{Command: pluginPath, Args: []string{"get-token"}, Env: []string{"PLUGIN_TOKEN"}},
+		})
+
+		// Args do not match
+		_, err := getRestConfig()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("is not allowed"))
+
+		sveltosCluster.Spec.WorkloadIdentity.Exec.Args = []string{"get-token"}
+		_, err = getRestConfig()
+		Expect(err).To(BeNil())
+		clusterproxy.EvictWorkloadIdentityCache(namespace, name)
+
+		// Env name is not allowed
+		sveltosCluster.Spec.WorkloadIdentity.Exec.Env = append(sveltosCluster.Spec.WorkloadIdentity.Exec.Env,
+			libsveltosv1beta1.ExecEnvVar{Name: "LD_PRELOAD", Value: "/tmp/evil.so"})
+		_, err = getRestConfig()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("is not allowed"))
+	})
+
+	It("does not pass the Sveltos environment to the plugin", func() {
+		GinkgoT().Setenv("SVELTOS_TEST_SECRET", "secret")
+		writePlugin(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential",` +
+			`"status":{"token":"[$SVELTOS_TEST_SECRET]$PLUGIN_TOKEN"}}`)
+
+		cfg, err := getRestConfig()
+		Expect(err).To(BeNil())
+		Expect(cfg.BearerToken).To(Equal("[]token-from-env"))
+	})
+
+	It("returns an error when the plugin output is not a valid ExecCredential", func() {
+		writePlugin(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{}}`)
+
+		_, err := getRestConfig()
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("neither token nor client certificate"))
+
+		_, _, ok := clusterproxy.LoadTestWiCache(namespace, name)
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -57,6 +57,25 @@ spec:
             type: object
           spec:
             properties:
+              encoding:
+                description: |-
+                  Encoding indicates how Resources is encoded. When not set or set to None, each
+                  entry is a plain resource. When set to GzipBase64, Resources contains a single
+                  compressed entry. Use it for large content (for instance big helm charts) which would
+                  otherwise exceed the maximum object size accepted by the API server.
+                enum:
+                - None
+                - GzipBase64
+                type: string
+              encryptedResources:
+                description: |-
+                  EncryptedResources lists the indexes, within the (decoded) Resources, of the entries
+                  which are encrypted with the public key registered by the agent in the target SveltosCluster.
+                  Only Secrets are encrypted.
+                items:
+                  type: integer
+                type: array
+                x-kubernetes-list-type: atomic
               force:
                 default: false
                 description: |-
@@ -137,7 +156,7 @@ spec:
               resources:
                 description: |-
                   Resources contains all resources that need to be deployed.
-                  Content is either YAML or JSON
+                  Content is either YAML or JSON, unless Encoding says otherwise
                 items:
                   type: string
                 type: array
@@ -165,6 +184,16 @@ spec:
                   the ConfigurationBundle
                 format: byte
                 type: string
+              referencedBy:
+                description: |-
+                  ReferencedBy lists the requestors whose ConfigurationGroups reference this ConfigurationBundle.
+                  It is set only for shared ConfigurationBundles, whose content is stored once and referenced by
+                  the ConfigurationGroups of multiple clusters. A shared ConfigurationBundle is deleted when
+                  no requestor references it anymore.
+                items:
+                  type: string
+                type: array
+                x-kubernetes-list-type: set
             type: object
         type: object
     served: true
@@ -138,6 +138,36 @@ spec:
                   If set to true, Sveltos will attempt to deploy remaining resources in the ClusterProfile even
                   if errors are detected for previous resources.
                 type: boolean
+              dependsOn:
+                description: |-
+                  DependsOn lists the ConfigurationGroups, for the same cluster, whose content must be
+                  processed before the content of this ConfigurationGroup. For instance, a ConfigurationGroup
+                  deploying CustomResources depends on the ConfigurationGroup deploying the corresponding CRDs.
+                items:
+                  description: |-
+                    RequestorReference identifies the ConfigurationGroup created, for the same cluster,
+                    by a given requestor
+                  properties:
+                    requestorFeature:
+                      description: |-
+                        RequestorFeature is the feature within the component which created the ConfigurationGroup
+                        (e.g., helm, kustomize, policyrefs)
+                      type: string
+                    requestorKind:
+                      description: |-
+                        RequestorKind is the kind of the component which created the ConfigurationGroup
+                        (e.g., ClusterSummary)
+                      type: string
+                    requestorName:
+                      description: RequestorName is the name of the component which
+                        created the ConfigurationGroup
+                      type: string
+                  required:
+                  - requestorKind
+                  - requestorName
+                  type: object
+                type: array
+                x-kubernetes-list-type: atomic
               deployedGroupVersionKind:
                 description: |-
                   DeployedGroupVersionKind contains all GroupVersionKinds deployed in either
@@ -932,6 +962,14 @@ spec:
                   will be used (typically the same namespace where the Sveltos-applier is deployed).
                   The ServiceAccount must exist in the managed cluster.
                 type: string
+              signature:
+                description: |-
+                  Signature is the Ed25519 signature, produced by the management cluster components,
+                  of this spec (Signature excluded) and of the ordered hashes of all referenced
+                  ConfigurationBundles. When set, agent can verify content was produced by Sveltos and
+                  not modified afterwards.
+                format: byte
+                type: string
               sourceRef:
                 description: |-
                   SourceRef is the user facing Sveltos resource that caused this ConfigurationGroup to be
@@ -1251,6 +1289,162 @@ spec:
               failureMessage:
                 description: FailureMessage provides more information about the error.
                 type: string
+              itemStatuses:
+                description: |-
+                  ItemStatuses contains, for each ConfigurationItem, the deployment outcome
+                  reported by the agent
+                items:
+                  description: |-
+                    ConfigurationItemStatus reports the outcome of deploying the content referenced by
+                    a single ConfigurationItem
+                  properties:
+                    contentRef:
+                      description: |-
+                        ContentRef references the content this status refers to. It matches the ContentRef
+                        of one of the ConfigurationItems.
+                      properties:
+                        apiVersion:
+                          description: API version of the referent.
+                          type: string
+                        fieldPath:
+                          description: |-
+                            If referring to a piece of an object instead of an entire object, this string
+                            should contain a valid JSON/Go field access statement, such as desiredState.manifest.containers[2].
+                            For example, if the object reference is to a container within a pod, this would take on a value like:
+                            "spec.containers{name}" (where "name" refers to the name of the container that triggered
+                            the event) or if no container name is specified "spec.containers[2]" (container with
+                            index 2 in this pod). This syntax is chosen only to have some well-defined way of
+                            referencing a part of an object.
+                          type: string
+                        kind:
+                          description: |-
+                            Kind of the referent.
+                            More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds
+                          type: string
+                        name:
+                          description: |-
+                            Name of the referent.
+                            More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#names
+                          type: string
+                        namespace:
+                          description: |-
+                            Namespace of the referent.
+                            More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/namespaces/
+                          type: string
+                        resourceVersion:
+                          description: |-
+                            Specific resourceVersion to which this reference is made, if any.
+                            More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#concurrency-control-and-consistency
+                          type: string
+                        uid:
+                          description: |-
+                            UID of the referent.
+                            More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#uids
+                          type: string
+                      type: object
+                      x-kubernetes-map-type: atomic
+                    failureMessage:
+                      description: FailureMessage provides more information about
+                        the error.
+                      type: string
+                    hash:
+                      description: Hash is the hash of the content this status refers
+                        to
+                      format: byte
+                      type: string
+                    resourceReports:
+                      description: |-
+                        ResourceReports contains the outcome for each resource contained in the
+                        referenced content. When ConfigurationGroup is in DryRun mode, reports describe
+                        what would happen and Message contains the diff, if any, the change would cause.
+                      items:
+                        properties:
+                          action:
+                            description: Action represent the type of operation on
+                              the Kubernetes resource.
+                            enum:
+                            - No Action
+                            - Create
+                            - Update
+                            - Recreate
+                            - Delete
+                            - Orphan
+                            - Conflict
+                            - Error
+                            type: string
+                          message:
+                            description: |-
+                              Message is for any message that needs to added to better
+                              explain the action.
+                            type: string
+                          resource:
+                            description: Resource contains information about Kubernetes
+                              Resource
+                            properties:
+                              group:
+                                description: Group of the resource deployed in the
+                                  Cluster.
+                                type: string
+                              ignoreForConfigurationDrift:
+                                default: false
+                                description: |-
+                                  IgnoreForConfigurationDrift indicates to not track resource
+                                  for configuration drift detection.
+                                  This field has a meaning only when mode is ContinuousWithDriftDetection
+                                type: boolean
+                              kind:
+                                description: Kind of the resource deployed in the
+                                  Cluster.
+                                minLength: 1
+                                type: string
+                              lastAppliedTime:
+                                description: LastAppliedTime identifies when this
+                                  resource was last applied to the cluster.
+                                format: date-time
+                                type: string
+                              name:
+                                description: Name of the resource deployed in the
+                                  Cluster.
+                                minLength: 1
+                                type: string
+                              namespace:
+                                description: |-
+                                  Namespace of the resource deployed in the Cluster.
+                                  Empty for resources scoped at cluster level.
+                                type: string
+                              version:
+                                description: Version of the resource deployed in the
+                                  Cluster.
+                                minLength: 1
+                                type: string
+                            required:
+                            - group
+                            - kind
+                            - name
+                            - version
+                            type: object
+                        required:
+                        - resource
+                        type: object
+                      type: array
+                      x-kubernetes-list-type: atomic
+                    status:
+                      description: Status represents the state of the content in the
+                        workload cluster
+                      enum:
+                      - Provisioning
+                      - Provisioned
+                      - Failed
+                      - FailedNonRetriable
+                      - Removing
+                      - Removed
+                      - AgentRemoving
+                      type: string
+                  required:
+                  - contentRef
+                  type: object
+                type: array
+                x-kubernetes-list-type: atomic
               lastAppliedTime:
                 description: LastAppliedTime is the time feature was last reconciled
                 format: date-time
@@ -1271,6 +1465,78 @@ spec:
                   from spec.requestorHash, it means the requestor has changed and reconciliation is needed.
                 format: byte
                 type: string
+              staleResourceReports:
+                description: |-
+                  StaleResourceReports contains, in DryRun mode only, the reports for resources
+                  previously deployed because of this ConfigurationGroup and not referenced anymore.
+                  Action is either Delete or, when LeavePolicies is set, Orphan.
+                items:
+                  properties:
+                    action:
+                      description: Action represent the type of operation on the Kubernetes
+                        resource.
+                      enum:
+                      - No Action
+                      - Create
+                      - Update
+                      - Recreate
+                      - Delete
+                      - Orphan
+                      - Conflict
+                      - Error
+                      type: string
+                    message:
+                      description: |-
+                        Message is for any message that needs to added to better
+                        explain the action.
+                      type: string
+                    resource:
+                      description: Resource contains information about Kubernetes
+                        Resource
+                      properties:
+                        group:
+                          description: Group of the resource deployed in the Cluster.
+                          type: string
+                        ignoreForConfigurationDrift:
+                          default: false
+                          description: |-
+                            IgnoreForConfigurationDrift indicates to not track resource
+                            for configuration drift detection.
+                            This field has a meaning only when mode is ContinuousWithDriftDetection
+                          type: boolean
+                        kind:
+                          description: Kind of the resource deployed in the Cluster.
+                          minLength: 1
+                          type: string
+                        lastAppliedTime:
+                          description: LastAppliedTime identifies when this resource
+                            was last applied to the cluster.
+                          format: date-time
+                          type: string
+                        name:
+                          description: Name of the resource deployed in the Cluster.
+                          minLength: 1
+                          type: string
+                        namespace:
+                          description: |-
+                            Namespace of the resource deployed in the Cluster.
+                            Empty for resources scoped at cluster level.
+                          type: string
+                        version:
+                          description: Version of the resource deployed in the Cluster.
+                          minLength: 1
+                          type: string
+                      required:
+                      - group
+                      - kind
+                      - name
+                      - version
+                      type: object
+                  required:
+                  - resource
+                  type: object
+                type: array
+                x-kubernetes-list-type: atomic
               status:
                 description: Status represents the state of the feature in the workload
                   cluster
@@ -101,6 +101,12 @@ spec:
                 - from
                 - to
                 type: object
+              agentHeartbeatMaxAge:
+                description: |-
+                  AgentHeartbeatMaxAge is the maximum age of Status.AgentLastReportTime before the
+                  Sveltos agent in the managed cluster is considered late. If not set, a default is used.
+                  This field is used exclusively when Sveltos operates in pull mode.
+                type: string
               consecutiveFailureThreshold:
                 default: 3
                 description: |-
@@ -606,6 +612,63 @@ spec:
                       cluster (e.g. https://…).
                     minLength: 1
                     type: string
+                  exec:
+                    description: |-
+                      Exec contains configuration specific to an exec credential plugin.
+                      Required when Provider is Exec.
+                    properties:
+                      apiVersion:
+                        description: |-
+                          APIVersion is the version of the client.authentication.k8s.io ExecCredential
+                          the plugin understands. Only client.authentication.k8s.io/v1 is supported.
+                        enum:
+                        - client.authentication.k8s.io/v1
+                        type: string
+                      args:
+                        description: |-
+                          Args are the arguments passed to the plugin. They must match exactly, and in order,
+                          the arguments allowed for Command in the Sveltos controller configuration.
+                        items:
+                          type: string
+                        type: array
+                      command:
+                        description: |-
+                          Command is the plugin executable. It must match one of the commands allowed
+                          in the Sveltos controller configuration.
+                        minLength: 1
+                        type: string
+                      env:
+                        description: |-
+                          Env are the environment variables set when running the plugin. Their names must be
+                          allowed for Command in the Sveltos controller configuration. The plugin does not
+                          inherit the Sveltos environment, other than PATH and HOME.
+                        items:
+                          description: ExecEnvVar is an environment variable set when
+                            running an exec credential plugin.
+                          properties:
+                            name:
+                              description: Name of the environment variable.
+                              minLength: 1
+                              type: string
+                            value:
+                              description: Value of the environment variable.
+                              type: string
+                          required:
+                          - name
+                          - value
+                          type: object
+                        type: array
+                        x-kubernetes-list-map-keys:
+                        - name
+                        x-kubernetes-list-type: map
+                      provideClusterInfo:
+                        description: |-
+                          ProvideClusterInfo, when true, passes the managed cluster endpoint and CA to the
+                          plugin via the KUBERNETES_EXEC_INFO environment variable.
+                        type: boolean
+                    required:
+                    - command
+                    type: object
                   gcp:
                     description: |-
                       GCP contains configuration specific to GCP Workload Identity Federation.
@@ -630,6 +693,88 @@ spec:
                     - location
                     - projectID
                     type: object
+                  oidc:
+                    description: |-
+                      OIDC contains configuration specific to a generic OIDC provider.
+                      Required when Provider is OIDC.
+                    properties:
+                      audience:
+                        description: Audience is the logical name of the managed cluster
+                          the requested token is for.
+                        type: string
+                      clientID:
+                        description: ClientID is the OAuth client Sveltos authenticates
+                          as when calling the token endpoint.
+                        type: string
+                      clientSecretRef:
+                        description: |-
+                          ClientSecretRef references a key in a Secret, in the SveltosCluster namespace, containing
+                          the OAuth client secret. If not set, Sveltos authenticates as a public client.
+                        properties:
+                          key:
+                            description: The key of the secret to select from.  Must
+                              be a valid secret key.
+                            type: string
+                          name:
+                            default: ""
+                            description: |-
+                              Name of the referent.
+                              This field is effectively required, but due to backwards compatibility is
+                              allowed to be empty. Instances of this type with an empty value here are
+                              almost certainly wrong.
+                              More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#names
+                            type: string
+                          optional:
+                            description: Specify whether the Secret or its key must
+                              be defined
+                            type: boolean
+                        required:
+                        - key
+                        type: object
+                        x-kubernetes-map-type: atomic
+                      requestedTokenType:
+                        description: |-
+                          RequestedTokenType is the type of the token requested to the token endpoint.
+                          Defaults to urn:ietf:params:oauth:token-type:access_token.
+                        type: string
+                      scopes:
+                        description: Scopes are the scopes requested for the issued
+                          token.
+                        items:
+                          type: string
+                        type: array
+                      subjectTokenType:
+                        description: |-
+                          SubjectTokenType is the type of the token presented to the token endpoint.
+                          Defaults to urn:ietf:params:oauth:token-type:jwt.
+                        type: string
+                      tokenEndpoint:
+                        description: |-
+                          TokenEndpoint is the URL of the OIDC provider token endpoint
+                          (e.g. https://keycloak.example.com/realms/sveltos/protocol/openid-connect/token).
+                          It must be one of the token endpoints allowed by the Sveltos administrator.
+                        minLength: 1
+                        type: string
+                      tokenEndpointCASecretRef:
+                        description: |-
+                          TokenEndpointCASecretRef references a Secret in the management cluster containing the
+                          CA certificate of the token endpoint under the key "ca.crt".
+                          If not set, the system certificate pool is used.
+                        properties:
+                          name:
+                            default: ""
+                            description: |-
+                              Name of the referent.
+                              This field is effectively required, but due to backwards compatibility is
+                              allowed to be empty. Instances of this type with an empty value here are
+                              almost certainly wrong.
+                              More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#names
+                            type: string
+                        type: object
+                        x-kubernetes-map-type: atomic
+                    required:
+                    - tokenEndpoint
+                    type: object
                   provider:
                     description: Provider is the cloud provider implementing the workload
                       identity mechanism.
@@ -637,6 +782,8 @@ spec:
                     - AWS
                     - GCP
                     - Azure
+                    - OIDC
+                    - Exec
                     type: string
                 required:
                 - endpoint
@@ -649,6 +796,10 @@ spec:
                   rule: (self.provider == 'GCP') == has(self.gcp)
                 - message: azure must be set if and only if provider is Azure
                   rule: (self.provider == 'Azure') == has(self.azure)
+                - message: oidc must be set if and only if provider is OIDC
+                  rule: (self.provider == 'OIDC') == has(self.oidc)
+                - message: exec must be set if and only if provider is Exec
+                  rule: (self.provider == 'Exec') == has(self.exec)
             type: object
             x-kubernetes-validations:
             - message: 'workloadIdentity, kubeconfigName: conflict'
@@ -663,6 +814,14 @@ spec:
                   and requires recalculation of NextPause and NextUnpause.
                 format: byte
                 type: string
+              agentEncryptionKey:
+                description: |-
+                  AgentEncryptionKey is the X25519 public key registered by the Sveltos agent in the
+                  managed cluster. When set, Secrets sent to this cluster in pull mode can be encrypted
+                  so that only the agent, holding the matching private key, can read them.
+                  This field is used exclusively when Sveltos operates in pull mode.
+                format: byte
+                type: string
               agentLastReportTime:
                 description: |-
                   AgentLastReportTime indicates the last time the Sveltos agent in the managed cluster
@@ -671,6 +830,67 @@ spec:
                   serving as a heartbeat from the agent's perspective.
                 format: date-time
                 type: string
+              conditions:
+                description: Conditions contains the latest observations of the SveltosCluster
+                  state.
+                items:
+                  description: Condition contains details for one aspect of the current
+                    state of this API Resource.
+                  properties:
+                    lastTransitionTime:
+                      description: |-
+                        lastTransitionTime is the last time the condition transitioned from one status to another.
+                        This should be when the underlying condition changed.  If that is not known, then using the time when the API field changed is acceptable.
+                      format: date-time
+                      type: string
+                    message:
+                      description: |-
+                        message is a human readable message indicating details about the transition.
+                        This may be an empty string.
+                      maxLength: 32768
+                      type: string
+                    observedGeneration:
+                      description: |-
+                        observedGeneration represents the .metadata.generation that the condition was set based upon.
+                        For instance, if .metadata.generation is currently 12, but the .status.conditions[x].observedGeneration is 9, the condition is out of date
+                        with respect to the current state of the instance.
+                      format: int64
+                      minimum: 0
+                      type: integer
+                    reason:
+                      description: |-
+                        reason contains a programmatic identifier indicating the reason for the condition's last transition.
+                        Producers of specific condition types may define expected values and meanings for this field,
+                        and whether the values are considered a guaranteed API.
+                        The value should be a CamelCase string.
+                        This field may not be empty.
+                      maxLength: 1024
+                      minLength: 1
+                      pattern: ^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$
+                      type: string
+                    status:
+                      description: status of the condition, one of True, False, Unknown.
+                      enum:
+                      - "True"
+                      - "False"
+                      - Unknown
+                      type: string
+                    type:
+                      description: type of condition in CamelCase or in foo.example.com/CamelCase.
+                      maxLength: 316
+                      pattern: ^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$
+                      type: string
+                  required:
+                  - lastTransitionTime
+                  - message
+                  - reason
+                  - status
+                  - type
+                  type: object
+                type: array
+                x-kubernetes-list-map-keys:
+                - type
+                x-kubernetes-list-type: map
               connectionFailures:
                 default: 0
                 description: |-
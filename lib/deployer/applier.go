@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -94,8 +95,12 @@ func requiresRecreate(err error) bool {
 		return true
 	}
 
+	return matchesImmutableFieldPattern(err.Error())
+}
+
+func matchesImmutableFieldPattern(message string) bool {
 	for i := range immutableFieldErrorPatterns {
-		if immutableFieldErrorPatterns[i].MatchString(err.Error()) {
+		if immutableFieldErrorPatterns[i].MatchString(message) {
 			return true
 		}
 	}
@@ -103,6 +108,67 @@ func requiresRecreate(err error) bool {
 	return false
 }
 
+// RecreateError is returned by UpdateResource in DryRun mode when the API server rejects the
+// change to an existing object in a way only a delete+recreate can resolve (see requiresRecreate).
+// It lets callers report the change as a Recreate instead of a plain Update or Error.
+type RecreateError struct {
+	// Fields lists the fields reported by the API server as responsible for the rejection
+	// (for instance spec.selector). Empty when the rejection does not carry field causes,
+	// as with most admission webhooks.
+	Fields []string
+
+	// Forced indicates whether, outside of DryRun, the object would actually be deleted and
+	// recreated (forceRecreate or the forceRecreate annotation is set). When false, the apply
+	// will fail instead.
+	Forced bool
+
+	err error
+}
+
+func (e *RecreateError) Error() string {
+	return e.err.Error()
+}
+
+func (e *RecreateError) Unwrap() error {
+	return e.err
+}
+
+// getRecreateFields returns the sorted list of fields the API server reported as immutable
+// in err. If none is, all the fields reported as invalid are returned.
+func getRecreateFields(err error) []string {
+	var apiStatus apierrors.APIStatus
+	if !errors.As(err, &apiStatus) {
+		return nil
+	}
+
+	details := apiStatus.Status().Details
+	if details == nil {
+		return nil
+	}
+
+	fields := make([]string, 0, len(details.Causes))
+	invalidFields := make([]string, 0, len(details.Causes))
+	for i := range details.Causes {
+		field := details.Causes[i].Field
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(invalidFields, field) {
+			invalidFields = append(invalidFields, field)
+		}
+		if !slices.Contains(fields, field) && matchesImmutableFieldPattern(details.Causes[i].Message) {
+			fields = append(fields, field)
+		}
+	}
+
+	if len(fields) == 0 {
+		fields = invalidFields
+	}
+
+	sort.Strings(fields)
+	return fields
+}
+
 // recreateResource deletes object and waits for it to be gone, so a subsequent create
 // does not race against a still-terminating object (eg one with finalizers).
 func recreateResource(ctx context.Context, dr dynamic.ResourceInterface, object *unstructured.Unstructured) error {
@@ -221,6 +287,9 @@ func addExtraAnnotations(policy *unstructured.Unstructured, extraAnnotations map
 	policy.SetAnnotations(annotations)
 }
 
+// GetUnstructured returns the resources contained in section. Section can contain YAML documents
+// separated by `---`, a YAML array, a JSON array or a stream of JSON objects. Any resource of kind
+// List (for instance the output of `kubectl get -o yaml`) is expanded into its items.
 func GetUnstructured(section []byte, logger logr.Logger) ([]*unstructured.Unstructured, error) {
 	elements, err := CustomSplit(string(section))
 	if err != nil {
@@ -231,20 +300,65 @@ func GetUnstructured(section []byte, logger logr.Logger) ([]*unstructured.Unstru
 		policy, err := k8s_utils.GetUnstructured([]byte(elements[i]))
 		if err != nil {
 			logger.Error(err, fmt.Sprintf("failed to get policy from Data %.100s", elements[i]))
-			return nil, err
+			return nil, fmt.Errorf("document %d: %w", i, err)
 		}
 
 		if policy == nil {
 			logger.Error(err, fmt.Sprintf("failed to get policy from Data %.100s", elements[i]))
-			return nil, fmt.Errorf("failed to get policy from Data %.100s", elements[i])
+			return nil, fmt.Errorf("document %d: failed to get policy from Data %.100s", i, elements[i])
+		}
+
+		items, err := expandList(policy)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("failed to expand List from Data %.100s", elements[i]))
+			return nil, fmt.Errorf("document %d: %w", i, err)
 		}
 
-		policies = append(policies, policy)
+		policies = append(policies, items...)
 	}
 
 	return policies, nil
 }
 
+// isList returns true if u is a List (kind List or any <Kind>List carrying items).
+func isList(u *unstructured.Unstructured) bool {
+	return strings.HasSuffix(u.GetKind(), "List") && u.IsList()
+}
+
+// expandList returns the items contained in u if u is a List, u itself otherwise.
+// Nested Lists are expanded as well.
+func expandList(u *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	if !isList(u) {
+		return []*unstructured.Unstructured{u}, nil
+	}
+
+	items, ok := u.Object["items"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s items is not an array", u.GetKind())
+	}
+
+	result := make([]*unstructured.Unstructured, 0, len(items))
+	for i := range items {
+		content, ok := items[i].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s item %d is not an object", u.GetKind(), i)
+		}
+
+		item := &unstructured.Unstructured{Object: content}
+		if item.GetAPIVersion() == "" || item.GetKind() == "" {
+			return nil, fmt.Errorf("%s item %d: apiVersion and kind must be set", u.GetKind(), i)
+		}
+
+		expanded, err := expandList(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s item %d: %w", u.GetKind(), i, err)
+		}
+		result = append(result, expanded...)
+	}
+
+	return result, nil
+}
+
 var (
 	reCommentLine = regexp.MustCompile(`(?m)^\s*#([^#].*?)$`)
 	reEmptyLine   = regexp.MustCompile(`(?m)^\s*$`)
@@ -265,12 +379,77 @@ func removeCommentsAndEmptyLines(text string) string {
 	return result
 }
 
+// splitJSON returns the documents contained in text when text is either a JSON array or a stream
+// of JSON objects (for instance newline-delimited JSON). Returns false if text is not one of those
+// formats (a single JSON object is left to the YAML path, which handles it already).
+func splitJSON(text string) ([]string, bool, error) {
+	trimmed := strings.TrimSpace(text)
+
+	if strings.HasPrefix(trimmed, "[") {
+		var elements []json.RawMessage
+		if err := json.Unmarshal([]byte(trimmed), &elements); err != nil {
+			// Not JSON (could be a YAML flow sequence)
+			return nil, false, nil
+		}
+
+		documents := make([]string, 0, len(elements))
+		for i := range elements {
+			element := bytes.TrimSpace(elements[i])
+			if string(element) == "null" {
+				continue
+			}
+			if !bytes.HasPrefix(element, []byte("{")) {
+				return nil, true, fmt.Errorf("document %d: expected a JSON object, found %.50s", i, string(element))
+			}
+			documents = append(documents, string(element))
+		}
+		return documents, true, nil
+	}
+
+	if strings.HasPrefix(trimmed, "{") {
+		dec := json.NewDecoder(strings.NewReader(trimmed))
+		documents := []string{}
+		for {
+			var element json.RawMessage
+			err := dec.Decode(&element)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				// Not JSON (could be KYAML or a YAML flow mapping)
+				return nil, false, nil
+			}
+			if !bytes.HasPrefix(bytes.TrimSpace(element), []byte("{")) {
+				return nil, true, fmt.Errorf("document %d: expected a JSON object, found %.50s",
+					len(documents), string(element))
+			}
+			documents = append(documents, string(element))
+		}
+
+		if len(documents) > 1 {
+			return documents, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// CustomSplit splits text into the documents it contains. Supported formats are YAML documents
+// separated by `---`, YAML arrays, JSON arrays and streams of JSON objects.
 func CustomSplit(text string) ([]string, error) {
 	section := removeCommentsAndEmptyLines(text)
 	if section == "" {
 		return nil, nil
 	}
 
+	jsonDocuments, isJSON, err := splitJSON(text)
+	if err != nil {
+		return nil, err
+	}
+	if isJSON {
+		return jsonDocuments, nil
+	}
+
 	result := []string{}
 
 	// First split by document separators if they exist
@@ -285,7 +464,7 @@ func CustomSplit(text string) ([]string, error) {
 				break
 			}
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("document %d: %w", len(documents), err)
 			}
 			if value == nil {
 				continue
@@ -486,7 +665,13 @@ func GenerateConflictResourceReport(ctx context.Context, dr dynamic.ResourceInte
 
 // GenerateErrorResourceReport returns a ResourceReport recording that a resource's apply failed,
 // so DryRun evaluations still surface which resource errored and why instead of omitting it entirely.
+// If err is a *RecreateError, the report is generated by GenerateRecreateResourceReport instead.
 func GenerateErrorResourceReport(resource *libsveltosv1beta1.Resource, err error) *libsveltosv1beta1.ResourceReport {
+	var recreateErr *RecreateError
+	if errors.As(err, &recreateErr) {
+		return GenerateRecreateResourceReport(resource, recreateErr)
+	}
+
 	return &libsveltosv1beta1.ResourceReport{
 		Resource: *resource,
 		Action:   string(libsveltosv1beta1.ErrorResourceAction),
@@ -494,6 +679,28 @@ func GenerateErrorResourceReport(resource *libsveltosv1beta1.Resource, err error
 	}
 }
 
+// GenerateRecreateResourceReport returns a ResourceReport recording that applying resource requires
+// deleting and recreating it. The message lists the fields responsible, so DryRun reviewers can spot
+// disruptive changes before committing to them.
+func GenerateRecreateResourceReport(resource *libsveltosv1beta1.Resource,
+	recreateErr *RecreateError) *libsveltosv1beta1.ResourceReport {
+
+	message := "Object must be deleted and recreated. "
+	if len(recreateErr.Fields) > 0 {
+		message += fmt.Sprintf("Fields that cannot be updated in place: %s. ", strings.Join(recreateErr.Fields, ", "))
+	}
+	if !recreateErr.Forced {
+		message += "Apply will fail unless forceRecreate is set. "
+	}
+	message += fmt.Sprintf("API server error: %v", recreateErr.err)
+
+	return &libsveltosv1beta1.ResourceReport{
+		Resource: *resource,
+		Action:   string(libsveltosv1beta1.RecreateResourceAction),
+		Message:  message,
+	}
+}
+
 func removeDriftExclusionsFields(ctx context.Context, dr dynamic.ResourceInterface, isDritfDetectionMode, isDryRun bool,
 	driftExclusions []libsveltosv1beta1.DriftExclusion, object *unstructured.Unstructured) (bool, error) {
 
@@ -530,7 +737,8 @@ func removeDriftExclusionsFields(ctx context.Context, dr dynamic.ResourceInterfa
 }
 
 // UpdateResource creates or updates a resource in a Cluster.
-// No action in DryRun mode.
+// No action in DryRun mode. In DryRun mode, if the change modifies an immutable field of an
+// existing object, a *RecreateError is returned.
 // When forceRecreate is true, or object carries the `projectsveltos.io/forceRecreate`
 // annotation (see HasForceRecreateAnnotation), and the apply is rejected with an error
 // that only a delete+recreate can resolve (see requiresRecreate), the object is deleted
@@ -597,6 +805,16 @@ func UpdateResource(ctx context.Context, dr dynamic.ResourceInterface, isDriftDe
 			return nil
 		})
 
+		if err != nil && isDryRun && requiresRecreate(err) && objectExists(ctx, dr, object) {
+			// Report the change as disruptive: a real apply will either fail or delete and
+			// recreate the object (same condition as below). Any other error is reported as is.
+			return nil, &RecreateError{
+				Fields: getRecreateFields(err),
+				Forced: forceRecreate || HasForceRecreateAnnotation(object),
+				err:    err,
+			}
+		}
+
 		if err != nil && !isDryRun && (forceRecreate || HasForceRecreateAnnotation(object)) && requiresRecreate(err) {
 			l.V(logs.LogInfo).Info(fmt.Sprintf("apply rejected (%v), recreating resource", err))
 			if recreateErr := recreateResource(ctx, dr, object); recreateErr != nil {
@@ -612,6 +830,12 @@ func UpdateResource(ctx context.Context, dr dynamic.ResourceInterface, isDriftDe
 	return updatedObject, applySubresources(ctx, dr, object, subresources, &options)
 }
 
+// objectExists returns true if object is currently present
+func objectExists(ctx context.Context, dr dynamic.ResourceInterface, object *unstructured.Unstructured) bool {
+	_, err := dr.Get(ctx, object.GetName(), metav1.GetOptions{})
+	return err == nil
+}
+
 func isCustomResourceDefinition(u *unstructured.Unstructured) bool {
 	gvk := schema.FromAPIVersionAndKind(u.GetAPIVersion(), u.GetKind())
 
@@ -18,9 +18,11 @@ package deployer_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +30,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2/textlogger"
 
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
@@ -690,6 +693,82 @@ status:
 	})
 })
 
+var _ = Describe("GetUnstructured with JSON and Lists", func() {
+	var logger logr.Logger
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig(textlogger.Verbosity(1)))
+	})
+
+	It("expands a JSON array into individual resources", func() {
+		data := `[
+  {"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "foo"}},
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "bar", "namespace": "foo"}}
+]`
+
+		result, err := deployer.GetUnstructured([]byte(data), logger)
+		Expect(err).To(BeNil())
+		Expect(len(result)).To(Equal(2))
+		Expect(result[0].GetKind()).To(Equal("Namespace"))
+		Expect(result[1].GetKind()).To(Equal("ConfigMap"))
+	})
+
+	It("expands newline-delimited JSON objects into individual resources", func() {
+		data := `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "foo"}}
+{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "bar", "namespace": "foo"}}
+{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "bar", "namespace": "foo"}}
+`
+
+		result, err := deployer.GetUnstructured([]byte(data), logger)
+		Expect(err).To(BeNil())
+		Expect(len(result)).To(Equal(3))
+		Expect(result[2].GetKind()).To(Equal("Secret"))
+	})
+
+	It("expands v1 List items into individual resources", func() {
+		data := `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: foo
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: bar
+    namespace: foo
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: bar
+  namespace: foo`
+
+		result, err := deployer.GetUnstructured([]byte(data), logger)
+		Expect(err).To(BeNil())
+		Expect(len(result)).To(Equal(3))
+		Expect(result[0].GetKind()).To(Equal("Namespace"))
+		Expect(result[1].GetKind()).To(Equal("ConfigMap"))
+		Expect(result[2].GetKind()).To(Equal("ServiceAccount"))
+	})
+
+	It("reports which document and List item are invalid", func() {
+		data := `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "foo"}}
+{"apiVersion": "v1", "kind": "List", "items": [{"apiVersion": "v1", "kind": "Namespace"}, {"metadata": {}}]}
+`
+
+		_, err := deployer.GetUnstructured([]byte(data), logger)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("document 1"))
+		Expect(err.Error()).To(ContainSubstring("item 1"))
+
+		_, err = deployer.GetUnstructured([]byte(`[{"kind": "Namespace"}, "foo"]`), logger)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("document 1"))
+	})
+})
+
 var _ = Describe("requiresRecreate", func() {
 	It("returns true for Invalid errors", func() {
 		err := apierrors.NewInvalid(
@@ -713,6 +792,31 @@ var _ = Describe("requiresRecreate", func() {
 	})
 })
 
+var _ = Describe("getRecreateFields", func() {
+	It("returns the sorted, deduplicated immutable fields of an Invalid error", func() {
+		err := apierrors.NewInvalid(
+			schema.GroupKind{Group: appsGroup, Kind: "Deployment"}, "foo", field.ErrorList{
+				field.Invalid(field.NewPath("spec", "template"), nil, "field is immutable"),
+				field.Invalid(field.NewPath("spec", "selector"), nil, "field is immutable"),
+				field.Forbidden(field.NewPath("spec", "strategy", "rollingUpdate"), "may not be specified"),
+				field.Invalid(field.NewPath("spec", "selector"), nil, "field is immutable"),
+			})
+		Expect(deployer.GetRecreateFields(err)).To(Equal([]string{"spec.selector", "spec.template"}))
+	})
+
+	It("returns the invalid fields of an Invalid error with no immutable field", func() {
+		err := apierrors.NewInvalid(
+			schema.GroupKind{Group: appsGroup, Kind: "Deployment"}, "foo", field.ErrorList{
+				field.Forbidden(field.NewPath("spec", "strategy", "rollingUpdate"), "may not be specified"),
+			})
+		Expect(deployer.GetRecreateFields(err)).To(Equal([]string{"spec.strategy.rollingUpdate"}))
+	})
+
+	It("returns no fields for errors not returned by the API server", func() {
+		Expect(deployer.GetRecreateFields(fmt.Errorf("the field xyz is immutable"))).To(BeEmpty())
+	})
+})
+
 var _ = Describe("HasForceRecreateAnnotation", func() {
 	It("returns true when the projectsveltos.io/forceRecreate annotation is set", func() {
 		resource := &unstructured.Unstructured{}
@@ -896,4 +1000,162 @@ var _ = Describe("UpdateResource force recreate", func() {
 		strategyType, _, _ := unstructured.NestedString(updated.Object, "spec", "strategy", "type")
 		Expect(strategyType).To(Equal("Recreate"))
 	})
+
+	It("reports a Recreate in DryRun mode when the change modifies an immutable field", func() {
+		name := randomString()
+		nsName := randomString()
+
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}}
+		Expect(testEnv.Create(context.TODO(), ns)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, ns)).To(Succeed())
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		initialYAML := fmt.Sprintf(deploymentNoStrategyTemplate, name, nsName, name, name)
+		initialObj, err := k8s_utils.GetUnstructured([]byte(initialYAML))
+		Expect(err).To(BeNil())
+
+		dr, err := k8s_utils.GetDynamicResourceInterface(testEnv.Config, initialObj.GroupVersionKind(), nsName)
+		Expect(err).To(BeNil())
+
+		_, err = deployer.UpdateResource(context.TODO(), dr, false, false, false,
+			nil, initialObj, nil, logger)
+		Expect(err).To(BeNil())
+
+		Eventually(func() error {
+			_, getErr := dr.Get(context.TODO(), name, metav1.GetOptions{})
+			return getErr
+		}, time.Minute, 5*time.Second).Should(BeNil())
+
+		// spec.selector of a Deployment is immutable
+		newLabel := name + "-new"
+		selectorYAML := fmt.Sprintf(deploymentNoStrategyTemplate, name, nsName, newLabel, newLabel)
+		selectorObj, err := k8s_utils.GetUnstructured([]byte(selectorYAML))
+		Expect(err).To(BeNil())
+
+		_, err = deployer.UpdateResource(context.TODO(), dr, false, true, false,
+			nil, selectorObj, nil, logger)
+		Expect(err).ToNot(BeNil())
+
+		var recreateErr *deployer.RecreateError
+		Expect(errors.As(err, &recreateErr)).To(BeTrue())
+		Expect(recreateErr.Forced).To(BeFalse())
+		Expect(recreateErr.Fields).To(ContainElement("spec.selector"))
+
+		resource := &libsveltosv1beta1.Resource{
+			Name:      name,
+			Namespace: nsName,
+			Kind:      "Deployment",
+			Group:     appsGroup,
+			Version:   "v1",
+		}
+		report := deployer.GenerateErrorResourceReport(resource, err)
+		Expect(report.Action).To(Equal(string(libsveltosv1beta1.RecreateResourceAction)))
+		Expect(report.Message).To(ContainSubstring("spec.selector"))
+
+		// DryRun must leave the object untouched
+		current, err := dr.Get(context.TODO(), name, metav1.GetOptions{})
+		Expect(err).To(BeNil())
+		selector, _, _ := unstructured.NestedString(current.Object, "spec", "selector", "matchLabels", "app")
+		Expect(selector).To(Equal(name))
+	})
+
+	It("reports a Recreate in DryRun mode when the change is an invalid combination of fields", func() {
+		name := randomString()
+		nsName := randomString()
+
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}}
+		Expect(testEnv.Create(context.TODO(), ns)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, ns)).To(Succeed())
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		initialYAML := fmt.Sprintf(deploymentNoStrategyTemplate, name, nsName, name, name)
+		initialObj, err := k8s_utils.GetUnstructured([]byte(initialYAML))
+		Expect(err).To(BeNil())
+
+		dr, err := k8s_utils.GetDynamicResourceInterface(testEnv.Config, initialObj.GroupVersionKind(), nsName)
+		Expect(err).To(BeNil())
+
+		_, err = deployer.UpdateResource(context.TODO(), dr, false, false, false,
+			nil, initialObj, nil, logger)
+		Expect(err).To(BeNil())
+
+		Eventually(func() bool {
+			current, getErr := dr.Get(context.TODO(), name, metav1.GetOptions{})
+			if getErr != nil {
+				return false
+			}
+			_, found, _ := unstructured.NestedMap(current.Object, "spec", "strategy", "rollingUpdate")
+			return found
+		}, time.Minute, 5*time.Second).Should(BeTrue())
+
+		// Leftover rollingUpdate conflicts with strategy.type Recreate: a forced apply recreates
+		recreateYAML := fmt.Sprintf(deploymentRecreateStrategyTemplate, name, nsName, name, name)
+		recreateObj, err := k8s_utils.GetUnstructured([]byte(recreateYAML))
+		Expect(err).To(BeNil())
+
+		_, err = deployer.UpdateResource(context.TODO(), dr, false, true, true,
+			nil, recreateObj, nil, logger)
+		Expect(err).ToNot(BeNil())
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+		var recreateErr *deployer.RecreateError
+		Expect(errors.As(err, &recreateErr)).To(BeTrue())
+		Expect(recreateErr.Forced).To(BeTrue())
+		Expect(recreateErr.Fields).To(ContainElement("spec.strategy.rollingUpdate"))
+
+		resource := &libsveltosv1beta1.Resource{
+			Name:      name,
+			Namespace: nsName,
+			Kind:      "Deployment",
+			Group:     appsGroup,
+			Version:   "v1",
+		}
+		report := deployer.GenerateErrorResourceReport(resource, err)
+		Expect(report.Action).To(Equal(string(libsveltosv1beta1.RecreateResourceAction)))
+
+		// DryRun must leave the object untouched
+		current, err := dr.Get(context.TODO(), name, metav1.GetOptions{})
+		Expect(err).To(BeNil())
+		strategyType, _, _ := unstructured.NestedString(current.Object, "spec", "strategy", "type")
+		Expect(strategyType).To(Equal("RollingUpdate"))
+	})
+
+	It("reports an Error in DryRun mode when the object to create is invalid", func() {
+		name := randomString()
+		nsName := randomString()
+
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}}
+		Expect(testEnv.Create(context.TODO(), ns)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, ns)).To(Succeed())
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		// selector does not match template labels
+		invalidYAML := fmt.Sprintf(deploymentNoStrategyTemplate, name, nsName, name, name+"-other")
+		invalidObj, err := k8s_utils.GetUnstructured([]byte(invalidYAML))
+		Expect(err).To(BeNil())
+
+		dr, err := k8s_utils.GetDynamicResourceInterface(testEnv.Config, invalidObj.GroupVersionKind(), nsName)
+		Expect(err).To(BeNil())
+
+		_, err = deployer.UpdateResource(context.TODO(), dr, false, true, false,
+			nil, invalidObj, nil, logger)
+		Expect(err).ToNot(BeNil())
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+		var recreateErr *deployer.RecreateError
+		Expect(errors.As(err, &recreateErr)).To(BeFalse())
+
+		resource := &libsveltosv1beta1.Resource{
+			Name:      name,
+			Namespace: nsName,
+			Kind:      "Deployment",
+			Group:     appsGroup,
+			Version:   "v1",
+		}
+		report := deployer.GenerateErrorResourceReport(resource, err)
+		Expect(report.Action).To(Equal(string(libsveltosv1beta1.ErrorResourceAction)))
+	})
 })
@@ -19,9 +19,16 @@ package deployer
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
@@ -29,33 +36,27 @@ import (
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 )
 
+const (
+	undeployWavePollInterval   = 2 * time.Second
+	defaultUndeployWaveTimeout = time.Minute
+)
+
 func UndeployStaleResource(ctx context.Context, skipAnnotationKey, skipAnnotationValue string, c client.Client,
 	profile client.Object, leavePolicies, isDryRunMode bool, r unstructured.Unstructured,
 	currentPolicies map[string]libsveltosv1beta1.Resource, logger logr.Logger) (*libsveltosv1beta1.ResourceReport, error) {
 
 	logger.V(logs.LogVerbose).Info(fmt.Sprintf("considering %s/%s", r.GetNamespace(), r.GetName()))
 
-	// Verify if this policy was deployed because of a projectsveltos (ReferenceLabelName
-	// is present as label in such a case).
-	// Check both labels (this used to be set) and annotations (this is what gets set now)
-	if !hasLabel(&r, ReferenceNameLabel, "") && !hasAnnotation(&r, ReferenceNameAnnotation, "") {
+	if !isUndeployCandidate(&r, skipAnnotationKey, skipAnnotationValue) {
 		return nil, nil
 	}
 
-	if skipAnnotationKey != "" {
-		if !hasAnnotation(&r, skipAnnotationKey, skipAnnotationValue) {
-			return nil, nil
-		}
-	}
-
 	var resourceReport *libsveltosv1beta1.ResourceReport = nil
 	// If in DryRun do not withdrawn any policy.
 	// If this ClusterSummary is the only OwnerReference and it is not deploying this policy anymore,
-	// policy would be withdrawn
+	// policy would be withdrawn (or, if leavePolicies is set, released by Sveltos)
 	if isDryRunMode {
-		if canDelete(&r, currentPolicies) && isResourceOwner(&r, profile) &&
-			!leavePolicies {
-
+		if canDelete(&r, currentPolicies) && isResourceOwner(&r, profile) {
 			resourceReport = &libsveltosv1beta1.ResourceReport{
 				Resource: libsveltosv1beta1.Resource{
 					Kind: r.GetObjectKind().GroupVersionKind().Kind, Namespace: r.GetNamespace(), Name: r.GetName(),
@@ -63,6 +64,10 @@ func UndeployStaleResource(ctx context.Context, skipAnnotationKey, skipAnnotatio
 				},
 				Action: string(libsveltosv1beta1.DeleteResourceAction),
 			}
+			if leavePolicies {
+				resourceReport.Action = string(libsveltosv1beta1.OrphanResourceAction)
+				resourceReport.Message = "Resource will be left in the cluster and all Sveltos metadata removed."
+			}
 		}
 	} else if canDelete(&r, currentPolicies) {
 		logger.V(logs.LogVerbose).Info(fmt.Sprintf("remove owner reference %s/%s", r.GetNamespace(), r.GetName()))
@@ -78,6 +83,186 @@ func UndeployStaleResource(ctx context.Context, skipAnnotationKey, skipAnnotatio
 	return resourceReport, nil
 }
 
+// UndeployStaleResources undeploys a set of stale resources (see UndeployStaleResource) in reverse
+// install order. Resources are grouped in waves, one per Kind, and each wave must be gone from the
+// cluster before the next one is processed. So, for instance, custom resources are removed before
+// their CustomResourceDefinition and workloads before their Namespace.
+// Returns the ResourceReports generated (only in DryRun mode). Each wave is given until ctx deadline
+// (one minute if ctx has no deadline) to disappear. If it does not, an error is returned and following
+// waves are not processed.
+func UndeployStaleResources(ctx context.Context, skipAnnotationKey, skipAnnotationValue string, c client.Client,
+	profile client.Object, leavePolicies, isDryRunMode bool, resources []unstructured.Unstructured,
+	currentPolicies map[string]libsveltosv1beta1.Resource, logger logr.Logger,
+) ([]libsveltosv1beta1.ResourceReport, error) {
+
+	reports := make([]libsveltosv1beta1.ResourceReport, 0)
+
+	waves := getUndeployWaves(resources)
+	for i := range waves {
+		deleted := make([]*unstructured.Unstructured, 0, len(waves[i]))
+		for j := range waves[i] {
+			r := &waves[i][j]
+			report, err := UndeployStaleResource(ctx, skipAnnotationKey, skipAnnotationValue, c, profile,
+				leavePolicies, isDryRunMode, *r, currentPolicies, logger)
+			if err != nil {
+				return reports, err
+			}
+			if report != nil {
+				reports = append(reports, *report)
+			}
+
+			if !isDryRunMode && !leavePolicies &&
+				isUndeployCandidate(r, skipAnnotationKey, skipAnnotationValue) &&
+				canDelete(r, currentPolicies) && isResourceOwner(r, profile) {
+
+				deleted = append(deleted, r)
+			}
+		}
+
+		if err := waitForResourcesDeletion(ctx, c, deleted, logger); err != nil {
+			return reports, err
+		}
+	}
+
+	return reports, nil
+}
+
+// installOrder is the order resources are installed in. Stale resources are undeployed in
+// reverse order. Any Kind not listed here (usually instances of a CustomResourceDefinition)
+// is considered installed last, so undeployed first.
+var installOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+func getInstallRank(kind string) int {
+	rank := slices.Index(installOrder, kind)
+	if rank == -1 {
+		return len(installOrder)
+	}
+	return rank
+}
+
+// getUndeployWaves sorts resources in reverse install order and groups them by GroupKind.
+// Resources with same install rank are ordered by GroupKind, so result is deterministic.
+func getUndeployWaves(resources []unstructured.Unstructured) [][]unstructured.Unstructured {
+	sorted := slices.Clone(resources)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		gkI := sorted[i].GroupVersionKind().GroupKind()
+		gkJ := sorted[j].GroupVersionKind().GroupKind()
+		rankI := getInstallRank(gkI.Kind)
+		rankJ := getInstallRank(gkJ.Kind)
+		if rankI != rankJ {
+			return rankI > rankJ
+		}
+		return gkI.String() < gkJ.String()
+	})
+
+	waves := make([][]unstructured.Unstructured, 0)
+	for i := range sorted {
+		if i == 0 || sorted[i].GroupVersionKind().GroupKind() != sorted[i-1].GroupVersionKind().GroupKind() {
+			waves = append(waves, []unstructured.Unstructured{})
+		}
+		waves[len(waves)-1] = append(waves[len(waves)-1], sorted[i])
+	}
+
+	return waves
+}
+
+// waitForResourcesDeletion waits for all resources to be gone from the cluster.
+func waitForResourcesDeletion(ctx context.Context, c client.Client, resources []*unstructured.Unstructured,
+	logger logr.Logger) error {
+
+	if len(resources) == 0 {
+		return nil
+	}
+
+	var pending []string
+	err := wait.PollUntilContextTimeout(ctx, undeployWavePollInterval, getUndeployWaveTimeout(ctx), true,
+		func(ctx context.Context) (bool, error) {
+			pending = make([]string, 0)
+			for i := range resources {
+				current := &unstructured.Unstructured{}
+				current.SetGroupVersionKind(resources[i].GroupVersionKind())
+				getErr := c.Get(ctx, client.ObjectKeyFromObject(resources[i]), current)
+				if apierrors.IsNotFound(getErr) {
+					continue
+				}
+				if getErr != nil {
+					return false, getErr
+				}
+				pending = append(pending, fmt.Sprintf("%s %s/%s", resources[i].GetKind(),
+					resources[i].GetNamespace(), resources[i].GetName()))
+			}
+			return len(pending) == 0, nil
+		})
+	if err != nil && len(pending) > 0 {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("resources still being deleted: %s", strings.Join(pending, ", ")))
+		return fmt.Errorf("resources still being deleted: %s: %w", strings.Join(pending, ", "), err)
+	}
+
+	return err
+}
+
+// getUndeployWaveTimeout returns how long to wait for a wave to be deleted. Callers control it
+// via ctx deadline; defaultUndeployWaveTimeout is used when ctx has none.
+func getUndeployWaveTimeout(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return defaultUndeployWaveTimeout
+	}
+	return time.Until(deadline)
+}
+
+// isUndeployCandidate returns true if resource was deployed by Sveltos and, when skipAnnotationKey
+// is set, has the skip annotation.
+func isUndeployCandidate(r *unstructured.Unstructured, skipAnnotationKey, skipAnnotationValue string) bool {
+	// Verify if this policy was deployed because of a projectsveltos (ReferenceLabelName
+	// is present as label in such a case).
+	// Check both labels (this used to be set) and annotations (this is what gets set now)
+	if !hasLabel(r, ReferenceNameLabel, "") && !hasAnnotation(r, ReferenceNameAnnotation, "") {
+		return false
+	}
+
+	if skipAnnotationKey != "" {
+		if !hasAnnotation(r, skipAnnotationKey, skipAnnotationValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func isResourceOwner(resource *unstructured.Unstructured, profile client.Object) bool {
 	// First consider annotations
 	annotations := resource.GetAnnotations()
@@ -114,26 +299,11 @@ func handleResourceDelete(ctx context.Context, c client.Client, policy client.Ob
 	leavePolicies bool, logger logr.Logger) error {
 
 	// If mode is set to LeavePolicies, leave policies in the workload cluster.
-	// Remove all labels added by Sveltos.
+	// Remove all metadata added by Sveltos, so resource is not managed by Sveltos anymore.
 	if leavePolicies {
-		// for backward compatibility (those labels used to be set, but are now
-		// replaced by corresponding annotations)
-		l := policy.GetLabels()
-		delete(l, ReferenceKindLabel)
-		delete(l, ReferenceNameLabel)
-		delete(l, ReferenceNamespaceLabel)
-		policy.SetLabels(l)
-
-		annotations := policy.GetAnnotations()
-		delete(annotations, ReferenceKindAnnotation)
-		delete(annotations, ReferenceNameAnnotation)
-		delete(annotations, ReferenceNamespaceAnnotation)
-		delete(annotations, ReferenceTierAnnotation)
-		delete(annotations, OwnerKind)
-		delete(annotations, OwnerName)
-		delete(annotations, OwnerTier)
-		policy.SetAnnotations(annotations)
-
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("releasing resource %s %s/%s",
+			policy.GetObjectKind().GroupVersionKind().Kind, policy.GetNamespace(), policy.GetName()))
+		RemoveSveltosMetadata(policy)
 		return c.Update(ctx, policy)
 	}
 
@@ -142,6 +312,46 @@ func handleResourceDelete(ctx context.Context, c client.Client, policy client.Ob
 	return c.Delete(ctx, policy)
 }
 
+// RemoveSveltosMetadata removes from policy every marker Sveltos uses to track ownership of a
+// deployed resource: reference labels and annotations, owner annotations, the policy hash, the
+// reason label and any OwnerReference to a Sveltos resource.
+// A resource stripped of those is truly unmanaged: a profile later deploying it will not detect
+// any conflict.
+func RemoveSveltosMetadata(policy client.Object) {
+	// for backward compatibility (those labels used to be set, but are now
+	// replaced by corresponding annotations)
+	l := policy.GetLabels()
+	delete(l, ReferenceKindLabel)
+	delete(l, ReferenceNameLabel)
+	delete(l, ReferenceNamespaceLabel)
+	delete(l, ReasonLabel)
+	policy.SetLabels(l)
+
+	annotations := policy.GetAnnotations()
+	delete(annotations, ReferenceKindAnnotation)
+	delete(annotations, ReferenceNameAnnotation)
+	delete(annotations, ReferenceNamespaceAnnotation)
+	delete(annotations, ReferenceTierAnnotation)
+	delete(annotations, PolicyHash)
+	delete(annotations, OwnerKind)
+	delete(annotations, OwnerName)
+	delete(annotations, OwnerTier)
+	policy.SetAnnotations(annotations)
+
+	ownerReferences := policy.GetOwnerReferences()
+	if ownerReferences != nil {
+		result := make([]metav1.OwnerReference, 0, len(ownerReferences))
+		for i := range ownerReferences {
+			// Only Sveltos resources are removed
+			if strings.Contains(ownerReferences[i].APIVersion, "projectsveltos.io") {
+				continue
+			}
+			result = append(result, ownerReferences[i])
+		}
+		policy.SetOwnerReferences(result)
+	}
+}
+
 // hasLabel search if key is one of the label.
 // If value is empty, returns true if key is present.
 // If value is not empty, returns true if key is present and value is a match.
@@ -18,6 +18,7 @@ package deployer_test
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -25,6 +26,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2/textlogger"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -74,6 +76,169 @@ var _ = Describe("Clean utils", func() {
 		Expect(v).To(Equal(randomValue))
 	})
 
+	It("handleResourceDelete removes every Sveltos ownership marker when mode is LeavePolicies", func() {
+		randomKey := randomString()
+		randomValue := randomString()
+		depl := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				Annotations: map[string]string{
+					deployer.ReferenceKindAnnotation:      randomString(),
+					deployer.ReferenceNameAnnotation:      randomString(),
+					deployer.ReferenceNamespaceAnnotation: randomString(),
+					deployer.ReferenceTierAnnotation:      "100",
+					deployer.PolicyHash:                   randomString(),
+					deployer.OwnerKind:                    "ClusterSummary",
+					deployer.OwnerName:                    randomString(),
+					deployer.OwnerTier:                    "100",
+					randomKey:                             randomValue,
+				},
+				Labels: map[string]string{
+					deployer.ReasonLabel: randomString(),
+					randomKey:            randomValue,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "config.projectsveltos.io/v1beta1", Kind: "ClusterSummary", Name: randomString(), UID: "1"},
+					{APIVersion: "v1", Kind: "ConfigMap", Name: randomString(), UID: "2"},
+				},
+			},
+		}
+		Expect(addTypeInformationToObject(scheme, depl)).To(Succeed())
+
+		initObjects := []client.Object{depl}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		Expect(deployer.HandleResourceDelete(ctx, c, depl, true,
+			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
+
+		currentDepl := &appsv1.Deployment{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: depl.Namespace, Name: depl.Name}, currentDepl)).To(Succeed())
+		Expect(currentDepl.Annotations).To(Equal(map[string]string{randomKey: randomValue}))
+		Expect(currentDepl.Labels).To(Equal(map[string]string{randomKey: randomValue}))
+		Expect(len(currentDepl.OwnerReferences)).To(Equal(1))
+		Expect(currentDepl.OwnerReferences[0].Kind).To(Equal("ConfigMap"))
+	})
+
+	It("UndeployStaleResource reports Orphan in DryRun mode when policies are left", func() {
+		profile := &libsveltosv1beta1.ClusterHealthCheck{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		}
+		Expect(addTypeInformationToObject(scheme, profile)).To(Succeed())
+
+		depl := &unstructured.Unstructured{}
+		depl.SetAPIVersion("apps/v1")
+		depl.SetKind("Deployment")
+		depl.SetNamespace(randomString())
+		depl.SetName(randomString())
+		depl.SetAnnotations(map[string]string{
+			deployer.ReferenceNameAnnotation: randomString(),
+			deployer.OwnerKind:               profile.GetObjectKind().GroupVersionKind().Kind,
+			deployer.OwnerName:               profile.GetName(),
+		})
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		report, err := deployer.UndeployStaleResource(ctx, "", "", c, profile, true, true, *depl,
+			map[string]libsveltosv1beta1.Resource{}, logger)
+		Expect(err).To(BeNil())
+		Expect(report).ToNot(BeNil())
+		Expect(report.Action).To(Equal(string(libsveltosv1beta1.OrphanResourceAction)))
+
+		report, err = deployer.UndeployStaleResource(ctx, "", "", c, profile, false, true, *depl,
+			map[string]libsveltosv1beta1.Resource{}, logger)
+		Expect(err).To(BeNil())
+		Expect(report).ToNot(BeNil())
+		Expect(report.Action).To(Equal(string(libsveltosv1beta1.DeleteResourceAction)))
+	})
+
+	It("getUndeployWaves groups resources by Kind in reverse install order", func() {
+		resources := []unstructured.Unstructured{
+			*getStaleResource("v1", "Namespace", "", randomString(), nil),
+			*getStaleResource("apps/v1", "Deployment", randomString(), randomString(), nil),
+			*getStaleResource("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", randomString(), nil),
+			*getStaleResource("lib.projectsveltos.io/v1beta1", "ClusterHealthCheck", "", randomString(), nil),
+			*getStaleResource("apps/v1", "Deployment", randomString(), randomString(), nil),
+			*getStaleResource("v1", "ServiceAccount", randomString(), randomString(), nil),
+		}
+
+		waves := deployer.GetUndeployWaves(resources)
+		Expect(len(waves)).To(Equal(5))
+		Expect(waves[0][0].GetKind()).To(Equal("ClusterHealthCheck"))
+		Expect(len(waves[1])).To(Equal(2))
+		Expect(waves[1][0].GetKind()).To(Equal("Deployment"))
+		Expect(waves[2][0].GetKind()).To(Equal("CustomResourceDefinition"))
+		Expect(waves[3][0].GetKind()).To(Equal("ServiceAccount"))
+		Expect(waves[4][0].GetKind()).To(Equal("Namespace"))
+	})
+
+	It("getUndeployWaveTimeout honors context deadline", func() {
+		Expect(deployer.GetUndeployWaveTimeout(context.TODO())).To(Equal(time.Minute))
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Minute)
+		defer cancel()
+		timeout := deployer.GetUndeployWaveTimeout(ctx)
+		Expect(timeout).To(BeNumerically(">", 4*time.Minute))
+		Expect(timeout).To(BeNumerically("<=", 5*time.Minute))
+	})
+
+	It("UndeployStaleResources removes stale resources owned by profile", func() {
+		profile := &libsveltosv1beta1.ClusterHealthCheck{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		}
+		Expect(addTypeInformationToObject(scheme, profile)).To(Succeed())
+
+		ownerAnnotations := map[string]string{
+			deployer.ReferenceNameAnnotation: randomString(),
+			deployer.OwnerKind:               profile.GetObjectKind().GroupVersionKind().Kind,
+			deployer.OwnerName:               profile.GetName(),
+		}
+
+		namespace := randomString()
+		stale := []*unstructured.Unstructured{
+			getStaleResource("v1", "Namespace", "", namespace, ownerAnnotations),
+			getStaleResource("v1", "ServiceAccount", namespace, randomString(), ownerAnnotations),
+			getStaleResource("v1", "ConfigMap", namespace, randomString(), ownerAnnotations),
+		}
+		// Deployed by Sveltos but owned by a different profile
+		other := getStaleResource("v1", "ConfigMap", namespace, randomString(), map[string]string{
+			deployer.ReferenceNameAnnotation: randomString(),
+			deployer.OwnerKind:               "ClusterProfile",
+			deployer.OwnerName:               randomString(),
+		})
+
+		initObjects := []client.Object{other}
+		resources := []unstructured.Unstructured{*other}
+		for i := range stale {
+			initObjects = append(initObjects, stale[i])
+			resources = append(resources, *stale[i])
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		// DryRun only reports
+		reports, err := deployer.UndeployStaleResources(ctx, "", "", c, profile, false, true, resources,
+			map[string]libsveltosv1beta1.Resource{}, logger)
+		Expect(err).To(BeNil())
+		Expect(len(reports)).To(Equal(3))
+		Expect(reports[0].Resource.Kind).To(Equal("ConfigMap"))
+		Expect(reports[2].Resource.Kind).To(Equal("Namespace"))
+		for i := range stale {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(stale[i]), stale[i])).To(Succeed())
+		}
+
+		reports, err = deployer.UndeployStaleResources(ctx, "", "", c, profile, false, false, resources,
+			map[string]libsveltosv1beta1.Resource{}, logger)
+		Expect(err).To(BeNil())
+		Expect(reports).To(BeEmpty())
+		for i := range stale {
+			err = c.Get(ctx, client.ObjectKeyFromObject(stale[i]), stale[i])
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(other), other)).To(Succeed())
+	})
+
 	It("handleResourceDelete removes policies from Cluster when mode is not set to leave policies", func() {
 		randomKey := randomString()
 		randomValue := randomString()
@@ -124,3 +289,15 @@ var _ = Describe("Clean utils", func() {
 		Expect(deployer.CanDelete(depl, map[string]libsveltosv1beta1.Resource{name: {}})).To(BeFalse())
 	})
 })
+
+func getStaleResource(apiVersion, kind, namespace, name string, annotations map[string]string,
+) *unstructured.Unstructured {
+
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetAnnotations(annotations)
+	return u
+}
@@ -26,12 +26,15 @@ var (
 	GetRequestStatus = getRequestStatus
 	ProcessRequests  = processRequests
 
-	HandleResourceDelete = handleResourceDelete
-	CanDelete            = canDelete
+	HandleResourceDelete   = handleResourceDelete
+	CanDelete              = canDelete
+	GetUndeployWaves       = getUndeployWaves
+	GetUndeployWaveTimeout = getUndeployWaveTimeout
 
 	DeployResourceSummaryInstance = deployResourceSummaryInstance
 
-	RequiresRecreate = requiresRecreate
+	RequiresRecreate  = requiresRecreate
+	GetRecreateFields = getRecreateFields
 )
 
 func (d *deployer) SetInProgress(inProgress []string) {
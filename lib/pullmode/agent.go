@@ -0,0 +1,276 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/k8s_utils"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// ConfigurationItemContent is the content recorded for a single key of a ConfigurationGroup,
+// ready to be applied.
+type ConfigurationItemContent struct {
+	// Index is the key the content was recorded for
+	Index string
+
+	// Bundles lists, in order, the ConfigurationBundles the content is stored in, with any
+	// encrypted resource already decrypted. First one carries the deployment options
+	// (for instance Helm release information).
+	Bundles []libsveltosv1beta1.ConfigurationBundle
+
+	// Objects contains all resources to deploy
+	Objects []*unstructured.Unstructured
+}
+
+// ConfigurationGroupContent is a ConfigurationGroup along with the content it references.
+type ConfigurationGroupContent struct {
+	ConfigurationGroup *libsveltosv1beta1.ConfigurationGroup
+
+	// Items contains the referenced content, in the same order it was recorded.
+	// It is empty when the ConfigurationGroup Action is Remove.
+	Items []ConfigurationItemContent
+
+	// Err is set when the referenced content could not be fetched or validated. In this case
+	// Items is empty and the ConfigurationGroup should not be processed.
+	Err error
+}
+
+// FetchConfigurationGroups returns all ConfigurationGroups ready to be processed for the
+// cluster clusterNamespace/clusterName, along with their content.
+// ConfigurationGroups being prepared (UpdatePhase set to Preparing) are skipped. When an agent
+// audience is set (see WithAgentAudience), only ConfigurationGroups addressed to it are returned. Otherwise
+// only ConfigurationGroups not addressed to any audience are returned. Returned
+// ConfigurationGroups are sorted in the order they must be processed in (see SortConfigurationGroups).
+// For each ConfigurationGroup all referenced ConfigurationBundles are fetched, decrypted (see
+// WithDecryptionKey), validated against the hash recorded in the ConfigurationGroup and, if
+// requested, against the ConfigurationGroup signature (see WithVerificationKey). Content split
+// across multiple ConfigurationBundles is reassembled and parsed.
+// A failure to fetch or validate the content of a ConfigurationGroup is reported in the
+// corresponding ConfigurationGroupContent and does not prevent other ConfigurationGroups from
+// being returned. ConfigurationGroups depending on it are reported with a DependencyNotReadyError.
+// ConfigurationGroups depending on missing ConfigurationGroups, or on ConfigurationGroups being prepared,
+// are not returned and a DependencyNotReadyError is returned. If ConfigurationGroups depend on each other,
+// they are not returned either and a DependencyCycleError is returned.
+// This method is made available to the agent running in the managed cluster.
+func FetchConfigurationGroups(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	logger logr.Logger, setters ...AgentOption) ([]ConfigurationGroupContent, error) {
+
+	options := getAgentOptions(setters...)
+
+	selector, err := GetClusterLabelSelector(clusterNamespace, clusterName, options.Audience)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get label selector: %v", err))
+		return nil, err
+	}
+
+	confGroups := &libsveltosv1beta1.ConfigurationGroupList{}
+	listOptions := []client.ListOption{
+		client.InNamespace(clusterNamespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	}
+	if err = c.List(ctx, confGroups, listOptions...); err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to list ConfigurationGroups: %v", err))
+		return nil, err
+	}
+
+	getBundle := func(ctx context.Context, ref *corev1.ObjectReference) (*libsveltosv1beta1.ConfigurationBundle, error) {
+		bundle := &libsveltosv1beta1.ConfigurationBundle{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, bundle)
+		return bundle, err
+	}
+
+	return getConfigurationGroupsContent(ctx, confGroups.Items, getBundle, options, logger)
+}
+
+// bundleGetter returns the ConfigurationBundle referenced by a ConfigurationItem
+type bundleGetter func(ctx context.Context, ref *corev1.ObjectReference) (*libsveltosv1beta1.ConfigurationBundle, error)
+
+// getConfigurationGroupsContent skips ConfigurationGroups being prepared, sorts the other ones in
+// processing order and gets their content.
+func getConfigurationGroupsContent(ctx context.Context, confGroups []libsveltosv1beta1.ConfigurationGroup,
+	getBundle bundleGetter, options *AgentOptions, logger logr.Logger) ([]ConfigurationGroupContent, error) {
+
+	ready := make([]libsveltosv1beta1.ConfigurationGroup, 0, len(confGroups))
+	for i := range confGroups {
+		if confGroups[i].Spec.UpdatePhase == libsveltosv1beta1.UpdatePhasePreparing {
+			logger.V(logs.LogDebug).Info(fmt.Sprintf("ConfigurationGroup %s is being prepared",
+				confGroups[i].Name))
+			continue
+		}
+		ready = append(ready, confGroups[i])
+	}
+
+	sorted, sortErr := SortConfigurationGroups(ready)
+	if sortErr != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to sort ConfigurationGroups: %v", sortErr))
+	}
+
+	// failed contains the requestor keys of the ConfigurationGroups whose content could not be fetched.
+	// ConfigurationGroups depending on those are held back as well.
+	failed := make(map[string]bool)
+	// failedDependents contains the requestor keys of the ConfigurationGroups a failed ConfigurationGroup
+	// marked for removal depends on. Those are removed after their dependents, so they are held back.
+	failedDependents := make(map[string]bool)
+	result := make([]ConfigurationGroupContent, len(sorted))
+	for i := range sorted {
+		result[i].ConfigurationGroup = &sorted[i]
+		key := getConfigurationGroupRequestorKey(&sorted[i])
+		isRemoval := sorted[i].Spec.Action == libsveltosv1beta1.ActionRemove
+		if (isRemoval && failedDependents[key]) || (!isRemoval && hasFailedDependency(&sorted[i], failed)) {
+			result[i].Err = NewDependencyNotReadyError([]string{fmt.Sprintf("%s/%s", sorted[i].Namespace,
+				sorted[i].Name)})
+		} else {
+			result[i].Items, result[i].Err = getConfigurationGroupContent(ctx, getBundle, &sorted[i], options)
+		}
+		if result[i].Err != nil {
+			failed[key] = true
+			if isRemoval {
+				for j := range sorted[i].Spec.DependsOn {
+					failedDependents[getRequestorKey(sorted[i].Namespace, sorted[i].Labels[clusterNameLabelKey],
+						&sorted[i].Spec.DependsOn[j])] = true
+				}
+			}
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get content for ConfigurationGroup %s: %v",
+				sorted[i].Name, result[i].Err))
+		}
+	}
+
+	return result, sortErr
+}
+
+// hasFailedDependency returns true if confGroup depends on any of the failed ConfigurationGroups
+func hasFailedDependency(confGroup *libsveltosv1beta1.ConfigurationGroup, failed map[string]bool) bool {
+	for i := range confGroup.Spec.DependsOn {
+		key := getRequestorKey(confGroup.Namespace, confGroup.Labels[clusterNameLabelKey],
+			&confGroup.Spec.DependsOn[i])
+		if failed[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// getConfigurationGroupContent fetches, validates and parses the content referenced by a ConfigurationGroup.
+func getConfigurationGroupContent(ctx context.Context, getBundle bundleGetter,
+	confGroup *libsveltosv1beta1.ConfigurationGroup, options *AgentOptions) ([]ConfigurationItemContent, error) {
+
+	if confGroup.Spec.Action == libsveltosv1beta1.ActionRemove {
+		// A ConfigurationGroup marked for removal references no ConfigurationBundle. Signature must
+		// still be verified, or anyone able to modify the ConfigurationGroup could trigger a removal.
+		if options.VerificationKey != nil {
+			if err := VerifyConfigurationGroup(confGroup, nil, options.VerificationKey); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	bundles := make([]libsveltosv1beta1.ConfigurationBundle, 0, len(confGroup.Spec.ConfigurationItems))
+	for i := range confGroup.Spec.ConfigurationItems {
+		item := &confGroup.Spec.ConfigurationItems[i]
+		if item.ContentRef == nil {
+			continue
+		}
+
+		bundle, err := getValidatedConfigurationBundle(ctx, getBundle, item, options)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, *bundle)
+	}
+
+	if options.VerificationKey != nil {
+		if err := VerifyConfigurationGroup(confGroup, bundles, options.VerificationKey); err != nil {
+			return nil, err
+		}
+	}
+
+	contents, err := AssembleConfigurationBundles(bundles)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleMap := make(map[string]*libsveltosv1beta1.ConfigurationBundle, len(bundles))
+	for i := range bundles {
+		bundleMap[bundles[i].Name] = &bundles[i]
+	}
+
+	result := make([]ConfigurationItemContent, len(contents))
+	for i := range contents {
+		result[i].Index = contents[i].Index
+		for _, name := range contents[i].BundleNames {
+			result[i].Bundles = append(result[i].Bundles, *bundleMap[name])
+		}
+
+		result[i].Objects = make([]*unstructured.Unstructured, len(contents[i].Resources))
+		for j := range contents[i].Resources {
+			result[i].Objects[j], err = k8s_utils.GetUnstructured([]byte(contents[i].Resources[j]))
+			if err != nil {
+				return nil, fmt.Errorf("content %s: %w", contents[i].Index, err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// getValidatedConfigurationBundle fetches the ConfigurationBundle referenced by a ConfigurationItem,
+// decrypts it and verifies its content matches the hash recorded in the ConfigurationItem.
+func getValidatedConfigurationBundle(ctx context.Context, getBundle bundleGetter,
+	item *libsveltosv1beta1.ConfigurationItem, options *AgentOptions,
+) (*libsveltosv1beta1.ConfigurationBundle, error) {
+
+	bundle, err := getBundle(ctx, item.ContentRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bundle.Spec.EncryptedResources) > 0 {
+		if options.DecryptionKey == nil {
+			return nil, fmt.Errorf("ConfigurationBundle %s/%s contains encrypted resources and no decryption key is set",
+				bundle.Namespace, bundle.Name)
+		}
+		bundle, err = DecryptConfigurationBundle(bundle, options.DecryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(item.Hash) != 0 {
+		hash, err := getBundleHash(bundle)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(hash, item.Hash) {
+			return nil, fmt.Errorf("ConfigurationBundle %s/%s: content hash mismatch",
+				bundle.Namespace, bundle.Name)
+		}
+	}
+
+	return bundle, nil
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+var _ = Describe("Agent", func() {
+	var logger logr.Logger
+	var c client.Client
+	var clusterNamespace string
+	var clusterName string
+	var requestorKind string
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+
+		c = k8sClient
+
+		clusterNamespace = randomString()
+		clusterName = randomString()
+		requestorKind = randomString()
+
+		createNamespace(clusterNamespace)
+	})
+
+	getConfigurationGroup := func(requestorName, requestorFeature string) *libsveltosv1beta1.ConfigurationGroup {
+		labels := pullmode.GetConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+		groups, err := pullmode.GetConfigurationGroups(context.TODO(), c, clusterNamespace, requestorName, labels)
+		Expect(err).To(BeNil())
+		Expect(len(groups.Items)).To(Equal(1))
+		return &groups.Items[0]
+	}
+
+	It("FetchConfigurationGroups returns ready ConfigurationGroups with their parsed content", func() {
+		index := randomString()
+		firstName := randomString()
+		secondName := randomString()
+		requestorFeature := randomString()
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, firstName, requestorFeature, map[string][]unstructured.Unstructured{index: getResources()},
+			logger, pullmode.WithBundleCompression(), pullmode.WithDependsOn([]libsveltosv1beta1.RequestorReference{
+				{RequestorKind: requestorKind, RequestorName: secondName, RequestorFeature: requestorFeature},
+			}))).To(Succeed())
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, secondName, requestorFeature, map[string][]unstructured.Unstructured{index: getResources()},
+			logger)).To(Succeed())
+
+		// ConfigurationGroup for another cluster is ignored
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, randomString(),
+			requestorKind, randomString(), requestorFeature, map[string][]unstructured.Unstructured{index: getResources()},
+			logger)).To(Succeed())
+
+		contents, err := pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(2))
+
+		// second is processed first as first depends on it
+		Expect(contents[0].ConfigurationGroup.Name).To(Equal(getConfigurationGroup(secondName, requestorFeature).Name))
+		Expect(contents[1].ConfigurationGroup.Name).To(Equal(getConfigurationGroup(firstName, requestorFeature).Name))
+
+		for i := range contents {
+			Expect(contents[i].Err).To(BeNil())
+			Expect(len(contents[i].Items)).To(Equal(1))
+			Expect(contents[i].Items[0].Index).To(Equal(index))
+			Expect(len(contents[i].Items[0].Bundles)).To(Equal(1))
+			Expect(len(contents[i].Items[0].Objects)).To(Equal(len(getResources())))
+			Expect(contents[i].Items[0].Objects[0].GetKind()).To(Equal("Namespace"))
+		}
+
+		// ConfigurationGroups being prepared are skipped
+		confGroup := getConfigurationGroup(firstName, requestorFeature)
+		confGroup.Spec.UpdatePhase = libsveltosv1beta1.UpdatePhasePreparing
+		Expect(c.Update(context.TODO(), confGroup)).To(Succeed())
+
+		contents, err = pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].ConfigurationGroup.Name).To(Equal(getConfigurationGroup(secondName, requestorFeature).Name))
+	})
+
+	It("FetchConfigurationGroups holds back ConfigurationGroups whose dependencies are not ready", func() {
+		index := randomString()
+		firstName := randomString()
+		secondName := randomString()
+		requestorFeature := randomString()
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, firstName, requestorFeature, map[string][]unstructured.Unstructured{index: getResources()},
+			logger, pullmode.WithDependsOn([]libsveltosv1beta1.RequestorReference{
+				{RequestorKind: requestorKind, RequestorName: secondName, RequestorFeature: requestorFeature},
+			}))).To(Succeed())
+
+		// Dependency does not exist yet
+		contents, err := pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger)
+		Expect(pullmode.IsDependencyNotReadyError(err)).To(BeTrue())
+		Expect(contents).To(BeEmpty())
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, secondName, requestorFeature, map[string][]unstructured.Unstructured{index: getResources()},
+			logger)).To(Succeed())
+
+		// Dependency is being prepared
+		confGroup := getConfigurationGroup(secondName, requestorFeature)
+		confGroup.Spec.UpdatePhase = libsveltosv1beta1.UpdatePhasePreparing
+		Expect(c.Update(context.TODO(), confGroup)).To(Succeed())
+
+		contents, err = pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger)
+		Expect(pullmode.IsDependencyNotReadyError(err)).To(BeTrue())
+		Expect(contents).To(BeEmpty())
+
+		// Dependency content cannot be validated
+		confGroup = getConfigurationGroup(secondName, requestorFeature)
+		confGroup.Spec.UpdatePhase = libsveltosv1beta1.UpdatePhaseReady
+		Expect(c.Update(context.TODO(), confGroup)).To(Succeed())
+
+		contentRef := confGroup.Spec.ConfigurationItems[0].ContentRef
+		bundle := &libsveltosv1beta1.ConfigurationBundle{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: contentRef.Namespace, Name: contentRef.Name},
+			bundle)).To(Succeed())
+		bundle.Spec.Resources = bundle.Spec.Resources[1:]
+		Expect(c.Update(context.TODO(), bundle)).To(Succeed())
+
+		contents, err = pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(2))
+		Expect(contents[0].ConfigurationGroup.Name).To(Equal(confGroup.Name))
+		Expect(contents[0].Err).ToNot(BeNil())
+		Expect(pullmode.IsDependencyNotReadyError(contents[1].Err)).To(BeTrue())
+		Expect(contents[1].Items).To(BeEmpty())
+	})
+
+	It("FetchConfigurationGroups reports ConfigurationGroups whose content cannot be validated", func() {
+		requestorName := randomString()
+		requestorFeature := randomString()
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature,
+			map[string][]unstructured.Unstructured{randomString(): getResources()}, logger)).To(Succeed())
+
+		confGroup := getConfigurationGroup(requestorName, requestorFeature)
+		contentRef := confGroup.Spec.ConfigurationItems[0].ContentRef
+
+		bundle := &libsveltosv1beta1.ConfigurationBundle{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: contentRef.Namespace, Name: contentRef.Name},
+			bundle)).To(Succeed())
+		bundle.Spec.Resources = bundle.Spec.Resources[1:]
+		Expect(c.Update(context.TODO(), bundle)).To(Succeed())
+
+		contents, err := pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).ToNot(BeNil())
+		Expect(contents[0].Items).To(BeEmpty())
+	})
+
+	It("FetchConfigurationGroups verifies signature when a verification key is set", func() {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+
+		requestorName := randomString()
+		requestorFeature := randomString()
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature,
+			map[string][]unstructured.Unstructured{randomString(): getResources()}, logger,
+			pullmode.WithSigningKey(privateKey))).To(Succeed())
+
+		contents, err := pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger,
+			pullmode.WithVerificationKey(publicKey))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).To(BeNil())
+
+		otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+		contents, err = pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger,
+			pullmode.WithVerificationKey(otherPublicKey))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(pullmode.IsSignatureVerificationError(contents[0].Err)).To(BeTrue())
+
+		// Removal must be signed as well
+		Expect(pullmode.RemoveDeployedResources(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)).To(Succeed())
+		contents, err = pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger,
+			pullmode.WithVerificationKey(publicKey))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(pullmode.IsSignatureVerificationError(contents[0].Err)).To(BeTrue())
+
+		Expect(pullmode.RemoveDeployedResources(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger, pullmode.WithSigningKey(privateKey))).To(Succeed())
+		contents, err = pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger,
+			pullmode.WithVerificationKey(publicKey))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).To(BeNil())
+		Expect(contents[0].Items).To(BeEmpty())
+	})
+
+	It("FetchConfigurationGroups returns only ConfigurationGroups addressed to the agent audience", func() {
+		requestorFeature := randomString()
+		saNamespace := randomString()
+		saName := randomString()
+		audience := pullmode.GetServiceAccountAudience(saNamespace, saName)
+
+		scopedName := randomString()
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, scopedName, requestorFeature,
+			map[string][]unstructured.Unstructured{randomString(): getResources()}, logger,
+			pullmode.WithServiceAccount(saNamespace, saName))).To(Succeed())
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, randomString(), requestorFeature,
+			map[string][]unstructured.Unstructured{randomString(): getResources()}, logger)).To(Succeed())
+
+		scopedGroup := getConfigurationGroup(scopedName, requestorFeature)
+		Expect(pullmode.GetAudience(scopedGroup)).To(Equal(audience))
+
+		// Agent with no audience only sees ConfigurationGroups not addressed to any audience
+		contents, err := pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].ConfigurationGroup.Name).ToNot(Equal(scopedGroup.Name))
+
+		contents, err = pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger,
+			pullmode.WithAgentAudience(audience))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].ConfigurationGroup.Name).To(Equal(scopedGroup.Name))
+
+		// Selector for no audience matches only the other ConfigurationGroup
+		selector, err := pullmode.GetClusterLabelSelector(clusterNamespace, clusterName, "")
+		Expect(err).To(BeNil())
+		groups := &libsveltosv1beta1.ConfigurationGroupList{}
+		Expect(c.List(context.TODO(), groups, client.InNamespace(clusterNamespace),
+			client.MatchingLabelsSelector{Selector: selector})).To(Succeed())
+		Expect(len(groups.Items)).To(Equal(1))
+		Expect(groups.Items[0].Name).ToNot(Equal(scopedGroup.Name))
+
+		// Removal is addressed to the same audience
+		Expect(pullmode.RemoveDeployedResources(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, scopedName, requestorFeature, logger)).To(Succeed())
+		Expect(pullmode.GetAudience(getConfigurationGroup(scopedName, requestorFeature))).To(Equal(audience))
+	})
+})
@@ -18,14 +18,22 @@ package pullmode
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
@@ -99,12 +107,65 @@ func IsActionNotSetToRemove(err error) bool {
 
 // GetClusterLabels returns a map of labels used to filter ConfigurationGroups for a specific cluster.
 // It takes the cluster namespace and cluster name as input.
+// Returned labels match all ConfigurationGroups for the cluster, regardless of their audience
+// (see GetClusterAudienceLabels).
 func GetClusterLabels(clusterNamespace, clusterName string) map[string]string {
 	return map[string]string{
 		clusterNameLabelKey: clusterName,
 	}
 }
 
+// GetServiceAccountAudience returns the audience of the ConfigurationGroups whose content is deployed
+// using the given ServiceAccount (see WithServiceAccount).
+// This method is made available to the agent running in the managed cluster.
+func GetServiceAccountAudience(serviceAccountNamespace, serviceAccountName string) string {
+	audience := fmt.Sprintf("%s.%s", serviceAccountNamespace, serviceAccountName)
+	if len(audience) <= validation.LabelValueMaxLength {
+		return audience
+	}
+
+	// Too long to be a label value
+	hash := sha256.Sum256([]byte(audience))
+	return "sa-" + hex.EncodeToString(hash[:])[:validation.LabelValueMaxLength-len("sa-")]
+}
+
+// GetClusterAudienceLabels returns a map of labels used to filter ConfigurationGroups for a specific
+// cluster addressed to the given audience (see WithAudience and WithServiceAccount).
+// Agents restricted to a subset of the managed cluster (for instance to a namespace) use it instead of
+// GetClusterLabels so they only watch the ConfigurationGroups addressed to them.
+func GetClusterAudienceLabels(clusterNamespace, clusterName, audience string) map[string]string {
+	labels := GetClusterLabels(clusterNamespace, clusterName)
+	labels[audienceLabelKey] = audience
+	return labels
+}
+
+// GetClusterLabelSelector returns a label selector matching the ConfigurationGroups for a specific
+// cluster addressed to the given audience. When audience is empty, it matches the ConfigurationGroups
+// not addressed to any audience.
+func GetClusterLabelSelector(clusterNamespace, clusterName, audience string) (labels.Selector, error) {
+	selector := labels.SelectorFromSet(GetClusterLabels(clusterNamespace, clusterName))
+
+	operator := selection.Equals
+	values := []string{audience}
+	if audience == "" {
+		operator = selection.DoesNotExist
+		values = nil
+	}
+
+	requirement, err := labels.NewRequirement(audienceLabelKey, operator, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return selector.Add(*requirement), nil
+}
+
+// GetAudience returns the audience a ConfigurationGroup is addressed to. Empty if the ConfigurationGroup
+// is addressed to any agent.
+func GetAudience(configurationGroup *libsveltosv1beta1.ConfigurationGroup) string {
+	return configurationGroup.Labels[audienceLabelKey]
+}
+
 // GetRequestorFeature returns a string representing the feature that caused the ConfigurationGroup
 // to be created
 func GetRequestorFeature(configurationGroup *libsveltosv1beta1.ConfigurationGroup) (string, error) {
@@ -160,6 +221,123 @@ func GetRequestorName(configurationGroup *libsveltosv1beta1.ConfigurationGroup)
 	return name, nil
 }
 
+// GetConfigurationBundleResources returns the resources stored in a ConfigurationBundle, one
+// YAML/JSON resource per element, regardless of the encoding used to store them (see
+// ConfigurationBundleSpec.Encoding).
+// This method is made available to the agent running in the managed cluster.
+// ConfigurationBundles containing encrypted resources must be decrypted first (see DecryptConfigurationBundle).
+func GetConfigurationBundleResources(bundle *libsveltosv1beta1.ConfigurationBundle) ([]string, error) {
+	if bundle == nil {
+		return nil, fmt.Errorf("nil ConfigurationBundle")
+	}
+
+	if len(bundle.Spec.EncryptedResources) > 0 {
+		return nil, fmt.Errorf("ConfigurationBundle %s/%s contains encrypted resources",
+			bundle.Namespace, bundle.Name)
+	}
+
+	return decodeConfigurationBundleResources(bundle)
+}
+
+// decodeConfigurationBundleResources returns the resources stored in a ConfigurationBundle decoding
+// them if needed. Encrypted resources are returned as they are.
+func decodeConfigurationBundleResources(bundle *libsveltosv1beta1.ConfigurationBundle) ([]string, error) {
+	switch bundle.Spec.Encoding {
+	case "", libsveltosv1beta1.BundleEncodingNone:
+		return bundle.Spec.Resources, nil
+	case libsveltosv1beta1.BundleEncodingGzipBase64:
+		resources := make([]string, 0)
+		for i := range bundle.Spec.Resources {
+			content, err := decodeResources(bundle.Spec.Resources[i])
+			if err != nil {
+				return nil, fmt.Errorf("ConfigurationBundle %s/%s: %w", bundle.Namespace, bundle.Name, err)
+			}
+			resources = append(resources, content...)
+		}
+		return resources, nil
+	default:
+		return nil, fmt.Errorf("ConfigurationBundle %s/%s: unsupported encoding %q",
+			bundle.Namespace, bundle.Name, bundle.Spec.Encoding)
+	}
+}
+
+// BundleContent is the content recorded for a single key of the resources passed to
+// RecordResourcesForDeployment/StageResourcesForDeployment.
+type BundleContent struct {
+	// Index is the key the content was recorded for
+	Index string
+
+	// BundleNames lists, in order, the ConfigurationBundles the content is stored in.
+	// Content too big to fit in a single ConfigurationBundle is split across multiple ones.
+	BundleNames []string
+
+	// Resources contains all resources, one YAML/JSON resource per element
+	Resources []string
+}
+
+// AssembleConfigurationBundles reassembles content split across multiple ConfigurationBundles.
+// bundles must be passed in the same order they are referenced by ConfigurationGroup's ConfigurationItems.
+// One BundleContent is returned per recorded key, in the same order. An error is returned if the parts
+// of a split content are missing, out of order or if the reassembled content does not match the
+// recorded hash.
+// This method is made available to the agent running in the managed cluster.
+func AssembleConfigurationBundles(bundles []libsveltosv1beta1.ConfigurationBundle) ([]BundleContent, error) {
+	result := make([]BundleContent, 0, len(bundles))
+
+	var current *BundleContent
+	var currentHash string
+	for i := range bundles {
+		bundle := &bundles[i]
+
+		resources, err := GetConfigurationBundleResources(bundle)
+		if err != nil {
+			return nil, err
+		}
+
+		part, parts, contentHash, err := getBundlePart(bundle)
+		if err != nil {
+			return nil, err
+		}
+
+		if current != nil && part == 0 {
+			return nil, fmt.Errorf("ConfigurationBundle %s/%s: previous content %s is incomplete",
+				bundle.Namespace, bundle.Name, current.Index)
+		}
+
+		if parts == 1 {
+			result = append(result, BundleContent{Index: bundle.Annotations[indexAnnotationKey],
+				BundleNames: []string{bundle.Name}, Resources: resources})
+			continue
+		}
+
+		switch {
+		case part == 0:
+			current = &BundleContent{Index: bundle.Annotations[indexAnnotationKey]}
+			currentHash = contentHash
+		case current == nil || len(current.BundleNames) != part || currentHash != contentHash:
+			return nil, fmt.Errorf("ConfigurationBundle %s/%s: unexpected part %d", bundle.Namespace,
+				bundle.Name, part)
+		}
+
+		current.BundleNames = append(current.BundleNames, bundle.Name)
+		current.Resources = append(current.Resources, resources...)
+
+		if part == parts-1 {
+			if getContentHash(current.Resources) != currentHash {
+				return nil, fmt.Errorf("content %s: hash mismatch", current.Index)
+			}
+			result = append(result, *current)
+			current = nil
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("content %s is incomplete", current.Index)
+	}
+
+	return result, nil
+}
+
 // For SveltosClusters in pull mode, this method is called by management cluster components
 // to register resources intended for deployment. The agent in the managed cluster will
 // subsequently fetch these resources.
@@ -171,29 +349,46 @@ func RecordResourcesForDeployment(ctx context.Context, c client.Client,
 	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
 	resources map[string][]unstructured.Unstructured, logger logr.Logger, setters ...Option) error {
 
-	bundles := make([]bundleData, len(resources))
-	// Create all ConfigurationBundles. There one configurationBundle per key.
+	bundleSetters := getBundleSetters(setters...)
+	sharedNamespace := getOptions(setters...).SharedBundlesNamespace
+	requestorKey := getRequestorKey(clusterNamespace, clusterName, &libsveltosv1beta1.RequestorReference{
+		RequestorKind: requestorKind, RequestorName: requestorName, RequestorFeature: requestorFeature})
+
+	// Shared ConfigurationBundles currently referenced, if any, are released once the ConfigurationGroup
+	// stops referencing them
+	currentBundles, err := getReferencedConfigurationBundles(ctx, c, clusterNamespace, clusterName,
+		requestorKind, requestorName, requestorFeature, logger)
+	if err != nil {
+		return err
+	}
+
+	bundles := make([]bundleData, 0, len(resources))
+	// Create all ConfigurationBundles. There one configurationBundle per key (more if content for
+	// a key is too big to fit in a single ConfigurationBundle).
 	// If Requestor is ClusterSummary each key represents a different ConfigMap/Secret referenced in
 	// policyRef section or a different helm chart in the helmChart section.
-	i := 0
-	for k := range resources {
-		bundle, err := reconcileConfigurationBundle(ctx, c, clusterNamespace, clusterName,
-			requestorKind, requestorName, requestorFeature, k, resources[k], false, false, logger)
-		if err != nil {
-			return err
+	// Keys are processed in sorted order so that same content always lists ConfigurationBundles in same order.
+	for _, k := range slices.Sorted(maps.Keys(resources)) {
+		keySetters := bundleSetters
+		if sharedNamespace != "" && canShareContent(resources[k], bundleSetters...) {
+			keySetters = append(slices.Clone(bundleSetters), withSharing(sharedNamespace, requestorKey))
 		}
-		hash, err := getHash(resources[k])
+
+		parts, err := reconcileConfigurationBundleParts(ctx, c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, k, resources[k], false, false, logger,
+			keySetters...)
 		if err != nil {
-			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to evaluate hash: %v", err))
+			return err
 		}
 
-		bundles[i] = bundleData{Name: bundle.Name, Hash: hash}
-		i++
+		for i := range parts {
+			bundles = append(bundles, getBundleData(parts[i], clusterNamespace))
+		}
 	}
 
 	// Now that we have created all ConfigurationBundles, creates a single ConfigurationGroup
 	// that references all bundles
-	err := reconcileConfigurationGroup(ctx, c, clusterNamespace, clusterName, requestorKind,
+	err = reconcileConfigurationGroup(ctx, c, clusterNamespace, clusterName, requestorKind,
 		requestorName, requestorFeature, bundles, logger, setters...)
 	if err != nil {
 		return err
@@ -202,8 +397,24 @@ func RecordResourcesForDeployment(ctx context.Context, c client.Client,
 	// If ConfigurationGroup is updated, we might have stale configurationBundles. Contininuing
 	// on the ClusterSummary example, previously ClusterSummary was referencing ConfigMap1 now it
 	// references ConfigMap2. So find and delete all stale ConfigurationBundles.
-	return deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+	err = deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
 		requestorName, requestorFeature, bundles, logger)
+	if err != nil {
+		return err
+	}
+
+	err = releaseSharedConfigurationBundles(ctx, c, getSharedNamespaces(sharedNamespace, currentBundles),
+		requestorKey, bundles, logger)
+	if err != nil {
+		return err
+	}
+
+	if limit := getOptions(setters...).RevisionHistoryLimit; limit > 0 {
+		return recordRevision(ctx, c, clusterNamespace, clusterName, requestorKind, requestorName,
+			requestorFeature, bundles, limit, logger)
+	}
+
+	return nil
 }
 
 // StageResourcesForDeployment is called by management cluster components to register resources
@@ -211,7 +422,7 @@ func RecordResourcesForDeployment(ctx context.Context, c client.Client,
 // Unlike RecordResourcesForDeployment, this method does not immediately make the resources
 // available to the agent in the managed cluster. Resources staged via this method
 // will be made available for deployment at a later point (after CommitStagedResourcesForDeployment
-// is called).
+// is called). Staged content is never stored in shared ConfigurationBundles (see WithSharedBundles).
 // - requestorKind, requestorName, and requestorFeature uniquely identify the component in the
 // management cluster invoking this method.
 // - resources is the list of resources to deploy.
@@ -221,7 +432,13 @@ func StageResourcesForDeployment(ctx context.Context, c client.Client,
 	resources map[string][]unstructured.Unstructured, skipTracking bool, logger logr.Logger,
 	setters ...BundleOption) error {
 
-	err := markConfigurationGroupAsPreparing(ctx, c, clusterNamespace, clusterName, requestorKind,
+	err := clearCompletedStaging(ctx, c, clusterNamespace, clusterName, requestorKind, requestorName,
+		requestorFeature)
+	if err != nil {
+		return err
+	}
+
+	err = markConfigurationGroupAsPreparing(ctx, c, clusterNamespace, clusterName, requestorKind,
 		requestorName, requestorFeature, logger)
 	if err != nil {
 		return err
@@ -229,25 +446,62 @@ func StageResourcesForDeployment(ctx context.Context, c client.Client,
 
 	manager := getStagedResourcesManager()
 
-	logger.V(logs.LogDebug).Info(fmt.Sprintf("staging %d resources for deployment",
-		len(resources)))
+	// Staged ConfigurationBundles are annotated with staging session and order, so that staging can be
+	// resumed, committed or discarded even after a restart.
+	staged, err := getStagedBundles(ctx, c, clusterNamespace, clusterName, requestorKind, requestorName,
+		requestorFeature)
+	if err != nil {
+		return err
+	}
+	session, order, err := getStagingSession(staged)
+	if err != nil {
+		return err
+	}
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("staging %d resources for deployment (session %s)",
+		len(resources), session))
 
 	// Create all ConfigurationBundles. There one configurationBundle per key.
 	// If Requestor is ClusterSummary each key represents a different ConfigMap/Secret referenced in
 	// policyRef section or a different helm chart in the helmChart section.
-	for k := range resources {
-		bundle, err := reconcileConfigurationBundle(ctx, c, clusterNamespace, clusterName, requestorKind,
-			requestorName, requestorFeature, k, resources[k], skipTracking, true, logger, setters...)
+	// Keys are processed in sorted order so that same content always lists ConfigurationBundles in same order.
+	for _, k := range slices.Sorted(maps.Keys(resources)) {
+		stagingSetters := append(slices.Clone(setters), withStaging(session, order))
+		order++
+
+		parts, err := reconcileConfigurationBundleParts(ctx, c, clusterNamespace, clusterName, requestorKind,
+			requestorName, requestorFeature, k, resources[k], skipTracking, true, logger, stagingSetters...)
 		if err != nil {
 			return err
 		}
 
-		manager.storeBundle(clusterNamespace, clusterName, requestorName, requestorFeature, bundle)
+		for i := range parts {
+			manager.storeBundle(clusterNamespace, clusterName, requestorName, requestorFeature, parts[i])
+		}
 	}
 
 	return nil
 }
 
+// HasStagedResources returns true if resources have been staged via StageResourcesForDeployment
+// and neither committed nor discarded yet. Staging state is persisted, so this also reports
+// resources staged before a process restart or a leader-election handover, letting the caller
+// decide whether to commit or discard them.
+// - requestorKind, requestorName, and requestorFeature uniquely identify the component in the
+// management cluster invoking this method.
+// - clusterNamespace/clusterName identify the target SveltosCluster.
+func HasStagedResources(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string) (bool, error) {
+
+	staged, err := getStagedBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		return false, err
+	}
+
+	return len(staged) > 0, nil
+}
+
 // DiscardStagedResourcesForDeployment removes all resources that have been temporarily stored
 // via calls to StageResourcesForDeployment for a specific component and target cluster.
 // This method is intended to be used when the process of preparing resources fails,
@@ -270,11 +524,27 @@ func DiscardStagedResourcesForDeployment(ctx context.Context, c client.Client,
 		return err
 	}
 
+	stagedBundles, err := getStagedBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		return err
+	}
+
+	err = deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature, currentBundles, logger)
+	if err != nil {
+		return err
+	}
+
+	// Staged ConfigurationBundles still referenced by the ConfigurationGroup are not staged anymore
+	if err := clearStagingAnnotations(ctx, c, stagedBundles); err != nil {
+		return err
+	}
+
 	manager := getStagedResourcesManager()
 	manager.clearBundles(clusterNamespace, clusterName, requestorName, requestorFeature)
 
-	return deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
-		requestorName, requestorFeature, currentBundles, logger)
+	return nil
 }
 
 // CommitStagedResourcesForDeployment marks the resources that have been previously staged
@@ -286,34 +556,57 @@ func DiscardStagedResourcesForDeployment(ctx context.Context, c client.Client,
 // - requestorKind, requestorName, and requestorFeature uniquely identify the component in the
 // management cluster invoking this method.
 // - clusterNamespace/clusterName identify the target SveltosCluster.
+// Staged content belonging to different staging sessions cannot be committed and must be discarded.
 func CommitStagedResourcesForDeployment(ctx context.Context, c client.Client,
 	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
 	logger logr.Logger, setters ...Option) error {
 
-	manager := getStagedResourcesManager()
-	stagedBundles := manager.getBundles(clusterNamespace, clusterName, requestorName, requestorFeature)
+	// Staged ConfigurationBundles are tracked both in memory and via annotations. Latter
+	// allows committing resources staged before a restart.
+	stagedBundles, err := getStagedBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		return err
+	}
+	if _, err := getSession(stagedBundles); err != nil {
+		return err
+	}
 
 	bundles := make([]bundleData, len(stagedBundles))
 	for i := range stagedBundles {
-		b := &stagedBundles[i]
-		bundles[i] = bundleData{Name: b.Name, Hash: b.Status.Hash}
+		bundles[i] = getBundleData(&stagedBundles[i], clusterNamespace)
 	}
 
 	// Now that we have created all ConfigurationBundles, creates a single ConfigurationGroup
 	// that references all bundles
-	err := reconcileConfigurationGroup(ctx, c, clusterNamespace, clusterName, requestorKind,
+	err = reconcileConfigurationGroup(ctx, c, clusterNamespace, clusterName, requestorKind,
 		requestorName, requestorFeature, bundles, logger, setters...)
 	if err != nil {
 		return err
 	}
 
+	if err := clearStagingAnnotations(ctx, c, stagedBundles); err != nil {
+		return err
+	}
+
+	manager := getStagedResourcesManager()
 	manager.clearBundles(clusterNamespace, clusterName, requestorName, requestorFeature)
 
 	// If ConfigurationGroup is updated, we might have stale configurationBundles. Contininuing
 	// on the ClusterSummary example, previously ClusterSummary was referencing ConfigMap1 now it
 	// references ConfigMap2. So find and delete all stale ConfigurationBundles.
-	return deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+	err = deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
 		requestorName, requestorFeature, bundles, logger)
+	if err != nil {
+		return err
+	}
+
+	if limit := getOptions(setters...).RevisionHistoryLimit; limit > 0 {
+		return recordRevision(ctx, c, clusterNamespace, clusterName, requestorKind, requestorName,
+			requestorFeature, bundles, limit, logger)
+	}
+
+	return nil
 }
 
 // RemoveDeployedResources marks resources for deletion in the managed cluster.
@@ -332,16 +625,31 @@ func RemoveDeployedResources(ctx context.Context, c client.Client,
 	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
 	logger logr.Logger, setters ...Option) error {
 
+	currentBundles, err := getReferencedConfigurationBundles(ctx, c, clusterNamespace, clusterName,
+		requestorKind, requestorName, requestorFeature, logger)
+	if err != nil {
+		return err
+	}
+
 	// Mark ConfigurationGroup for removal
-	err := markConfigurationGroupForRemoval(ctx, c, clusterNamespace, clusterName, requestorKind,
+	err = markConfigurationGroupForRemoval(ctx, c, clusterNamespace, clusterName, requestorKind,
 		requestorName, requestorFeature, logger, setters...)
 	if err != nil {
 		return err
 	}
 
 	// All bundles are now stale and can be deleted
-	return deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+	err = deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
 		requestorName, requestorFeature, nil, logger)
+	if err != nil {
+		return err
+	}
+
+	return releaseSharedConfigurationBundles(ctx, c,
+		getSharedNamespaces(getOptions(setters...).SharedBundlesNamespace, currentBundles),
+		getRequestorKey(clusterNamespace, clusterName, &libsveltosv1beta1.RequestorReference{
+			RequestorKind: requestorKind, RequestorName: requestorName, RequestorFeature: requestorFeature}),
+		nil, logger)
 }
 
 // TerminateDeploymentTracking permanently removes the internal records associated with a deployment
@@ -375,6 +683,25 @@ func TerminateDeploymentTracking(ctx context.Context, c client.Client,
 		return err
 	}
 
+	err = deleteRevisions(ctx, c, clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature)
+	if err != nil {
+		return err
+	}
+
+	currentBundles, err := getReferencedConfigurationBundles(ctx, c, clusterNamespace, clusterName,
+		requestorKind, requestorName, requestorFeature, logger)
+	if err != nil {
+		return err
+	}
+
+	err = releaseSharedConfigurationBundles(ctx, c, getSharedNamespaces("", currentBundles),
+		getRequestorKey(clusterNamespace, clusterName, &libsveltosv1beta1.RequestorReference{
+			RequestorKind: requestorKind, RequestorName: requestorName, RequestorFeature: requestorFeature}),
+		nil, logger)
+	if err != nil {
+		return err
+	}
+
 	return deleteConfigurationGroup(ctx, c, clusterNamespace, clusterName, requestorKind,
 		requestorName, requestorFeature, logger)
 }
@@ -457,6 +784,170 @@ func GetDeploymentStatus(ctx context.Context, c client.Client,
 	return &currentCG.Status, nil
 }
 
+// GetConfigurationItemStatuses returns, for each ConfigurationItem, the deployment outcome reported
+// by the agent, including the per-resource reports.
+// Statuses are returned following ConfigurationItems order. Items the agent has not reported on yet, or
+// has reported on for a different content, are skipped.
+// For SveltosClusters operating in pull mode, this method is invoked by components
+// on the management cluster.
+func GetConfigurationItemStatuses(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+	logger logr.Logger) ([]libsveltosv1beta1.ConfigurationItemStatus, error) {
+
+	currentCG, err := getConfigurationGroup(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return getCurrentItemStatuses(currentCG), nil
+}
+
+// getCurrentItemStatuses returns the item statuses matching current ConfigurationItems, following
+// ConfigurationItems order
+func getCurrentItemStatuses(currentCG *libsveltosv1beta1.ConfigurationGroup,
+) []libsveltosv1beta1.ConfigurationItemStatus {
+
+	statuses := make([]libsveltosv1beta1.ConfigurationItemStatus, 0, len(currentCG.Spec.ConfigurationItems))
+	for i := range currentCG.Spec.ConfigurationItems {
+		item := &currentCG.Spec.ConfigurationItems[i]
+		if item.ContentRef == nil {
+			continue
+		}
+
+		itemStatus := getConfigurationItemStatus(currentCG, item.ContentRef)
+		if itemStatus == nil || !reflect.DeepEqual(itemStatus.Hash, item.Hash) {
+			continue
+		}
+		statuses = append(statuses, *itemStatus)
+	}
+
+	return statuses
+}
+
+// GetDryRunReports returns the ResourceReports generated by the agent while evaluating, in DryRun mode,
+// the content of a ConfigurationGroup (see WithDryRun). In DryRun mode the agent does not apply any change
+// and instead publishes, per ConfigurationItem, what would happen to each resource (ConfigurationGroup
+// Status.ItemStatuses). ResourceReport Message contains the diff, if any, the change would cause.
+// Reports are returned following ConfigurationItems order, same as the reports generated for SveltosClusters
+// not in pull mode, followed by the reports for stale resources which would be deleted or orphaned
+// (ConfigurationGroup Status.StaleResourceReports).
+// A ProcessingMismatchError is returned if the agent has not evaluated the latest ConfigurationGroup yet.
+// For SveltosClusters operating in pull mode, this method is invoked by components
+// on the management cluster.
+func GetDryRunReports(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+	logger logr.Logger) ([]libsveltosv1beta1.ResourceReport, error) {
+
+	currentCG, err := getConfigurationGroup(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if !currentCG.Spec.DryRun {
+		return nil, fmt.Errorf("ConfigurationGroup is not in DryRun mode")
+	}
+
+	if currentCG.Status.ObservedGeneration != currentCG.Generation {
+		msg := fmt.Sprintf("ConfigurationGroup Status.ObservedGeneration (%d) does not match Generation (%d)",
+			currentCG.Status.ObservedGeneration, currentCG.Generation)
+		logger.V(logs.LogDebug).Info(msg)
+		return nil, NewProcessingMismatchError(msg)
+	}
+
+	reports := make([]libsveltosv1beta1.ResourceReport, 0)
+	statuses := getCurrentItemStatuses(currentCG)
+	for i := range statuses {
+		reports = append(reports, statuses[i].ResourceReports...)
+	}
+	reports = append(reports, currentCG.Status.StaleResourceReports...)
+
+	return reports, nil
+}
+
+// getConfigurationGroup returns the ConfigurationGroup for the requestor
+func getConfigurationGroup(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+	logger logr.Logger) (*libsveltosv1beta1.ConfigurationGroup, error) {
+
+	labels := getConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+	name, _, err := getConfigurationGroupName(ctx, c, clusterNamespace, requestorName, labels)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get ConfigurationGroup name: %v", err))
+		return nil, err
+	}
+
+	currentCG := &libsveltosv1beta1.ConfigurationGroup{}
+	err = c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: name},
+		currentCG)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get ConfigurationGroup: %v", err))
+		return nil, err
+	}
+
+	return currentCG, nil
+}
+
+// GetFailedResourceReports returns the ResourceReports, across all ConfigurationItems, of the
+// resources the agent failed to deploy (either because of an error or of a conflict).
+// For SveltosClusters operating in pull mode, this method is invoked by components
+// on the management cluster.
+func GetFailedResourceReports(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+	logger logr.Logger) ([]libsveltosv1beta1.ResourceReport, error) {
+
+	statuses, err := GetConfigurationItemStatuses(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make([]libsveltosv1beta1.ResourceReport, 0)
+	for i := range statuses {
+		for j := range statuses[i].ResourceReports {
+			report := &statuses[i].ResourceReports[j]
+			if report.Action == string(libsveltosv1beta1.ErrorResourceAction) ||
+				report.Action == string(libsveltosv1beta1.ConflictResourceAction) {
+
+				failed = append(failed, *report)
+			}
+		}
+	}
+
+	return failed, nil
+}
+
+// SetConfigurationItemStatus adds itemStatus to the ConfigurationGroup status, replacing the
+// existing status, if any, for the same ContentRef. Status is only modified in memory, caller
+// is responsible for updating the ConfigurationGroup status.
+// This method is made available to the agent running in the managed cluster.
+func SetConfigurationItemStatus(confGroup *libsveltosv1beta1.ConfigurationGroup,
+	itemStatus *libsveltosv1beta1.ConfigurationItemStatus) {
+
+	if current := getConfigurationItemStatus(confGroup, &itemStatus.ContentRef); current != nil {
+		*current = *itemStatus
+		return
+	}
+
+	confGroup.Status.ItemStatuses = append(confGroup.Status.ItemStatuses, *itemStatus)
+}
+
+// getConfigurationItemStatus returns the status for the content referenced by contentRef. Nil if
+// not found
+func getConfigurationItemStatus(confGroup *libsveltosv1beta1.ConfigurationGroup,
+	contentRef *corev1.ObjectReference) *libsveltosv1beta1.ConfigurationItemStatus {
+
+	for i := range confGroup.Status.ItemStatuses {
+		ref := &confGroup.Status.ItemStatuses[i].ContentRef
+		if ref.Kind == contentRef.Kind && ref.Namespace == contentRef.Namespace && ref.Name == contentRef.Name {
+			return &confGroup.Status.ItemStatuses[i]
+		}
+	}
+
+	return nil
+}
+
 // For SveltosClusters operating in pull mode, this method is invoked by components
 // on the management cluster to retrieve the withdrawal status of managed resources.
 func GetRemoveStatus(ctx context.Context, c client.Client,
@@ -623,3 +623,143 @@ var _ = Describe("APIs for SveltosCluster instances in pullmode", func() {
 		Expect(*sourceStatus).To(Equal(libsveltosv1beta1.SourceStatusActive))
 	})
 })
+
+var _ = Describe("ConfigurationItem statuses", func() {
+	It("GetConfigurationItemStatuses and GetFailedResourceReports return statuses reported by agent", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		requestorKind := randomString()
+		requestorName := randomString()
+		requestorFeature := randomString()
+
+		createNamespace(clusterNamespace)
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		c := k8sClient
+
+		resources := map[string][]unstructured.Unstructured{
+			randomString(): getResources(),
+			randomString(): getResources(),
+		}
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, resources, logger)).To(Succeed())
+
+		labels := pullmode.GetConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+		groups, err := pullmode.GetConfigurationGroups(context.TODO(), c, clusterNamespace, requestorName, labels)
+		Expect(err).To(BeNil())
+		Expect(len(groups.Items)).To(Equal(1))
+		group := &groups.Items[0]
+		Expect(len(group.Spec.ConfigurationItems)).To(Equal(2))
+
+		// Agent reports on first item only. A report for a different content is stale
+		failedReport := libsveltosv1beta1.ResourceReport{
+			Resource: libsveltosv1beta1.Resource{Kind: "Role", Name: randomString()},
+			Action:   string(libsveltosv1beta1.ErrorResourceAction),
+			Message:  randomString(),
+		}
+		failed := libsveltosv1beta1.FeatureStatusFailed
+		pullmode.SetConfigurationItemStatus(group, &libsveltosv1beta1.ConfigurationItemStatus{
+			ContentRef: *group.Spec.ConfigurationItems[0].ContentRef,
+			Hash:       []byte(randomString()),
+		})
+		pullmode.SetConfigurationItemStatus(group, &libsveltosv1beta1.ConfigurationItemStatus{
+			ContentRef:       *group.Spec.ConfigurationItems[0].ContentRef,
+			Hash:             group.Spec.ConfigurationItems[0].Hash,
+			DeploymentStatus: &failed,
+			ResourceReports: []libsveltosv1beta1.ResourceReport{
+				{
+					Resource: libsveltosv1beta1.Resource{Kind: "Namespace", Name: randomString()},
+					Action:   string(libsveltosv1beta1.CreateResourceAction),
+				},
+				failedReport,
+			},
+		})
+		pullmode.SetConfigurationItemStatus(group, &libsveltosv1beta1.ConfigurationItemStatus{
+			ContentRef: *group.Spec.ConfigurationItems[1].ContentRef,
+			Hash:       []byte(randomString()),
+		})
+		Expect(len(group.Status.ItemStatuses)).To(Equal(2))
+		Expect(c.Status().Update(context.TODO(), group)).To(Succeed())
+
+		statuses, err := pullmode.GetConfigurationItemStatuses(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(err).To(BeNil())
+		Expect(len(statuses)).To(Equal(1))
+		Expect(statuses[0].ContentRef.Name).To(Equal(group.Spec.ConfigurationItems[0].ContentRef.Name))
+		Expect(statuses[0].DeploymentStatus).ToNot(BeNil())
+		Expect(*statuses[0].DeploymentStatus).To(Equal(libsveltosv1beta1.FeatureStatusFailed))
+
+		reports, err := pullmode.GetFailedResourceReports(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(err).To(BeNil())
+		Expect(reports).To(Equal([]libsveltosv1beta1.ResourceReport{failedReport}))
+	})
+})
+
+var _ = Describe("DryRun reports", func() {
+	It("GetDryRunReports returns the reports published by the agent", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		requestorKind := randomString()
+		requestorName := randomString()
+		requestorFeature := randomString()
+
+		createNamespace(clusterNamespace)
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		c := k8sClient
+
+		resources := map[string][]unstructured.Unstructured{randomString(): getResources()}
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, resources, logger)).To(Succeed())
+
+		_, err := pullmode.GetDryRunReports(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(err).ToNot(BeNil())
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, resources, logger, pullmode.WithDryRun())).To(Succeed())
+
+		labels := pullmode.GetConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+		groups, err := pullmode.GetConfigurationGroups(context.TODO(), c, clusterNamespace, requestorName, labels)
+		Expect(err).To(BeNil())
+		Expect(len(groups.Items)).To(Equal(1))
+		group := &groups.Items[0]
+
+		// Agent has not processed latest ConfigurationGroup yet
+		group.Status.ObservedGeneration = group.Generation + 1
+		Expect(c.Status().Update(context.TODO(), group)).To(Succeed())
+		_, err = pullmode.GetDryRunReports(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(pullmode.IsProcessingMismatch(err)).To(BeTrue())
+
+		dryRunReports := []libsveltosv1beta1.ResourceReport{
+			{
+				Resource: libsveltosv1beta1.Resource{Kind: "Namespace", Name: randomString()},
+				Action:   string(libsveltosv1beta1.NoResourceAction),
+			},
+			{
+				Resource: libsveltosv1beta1.Resource{Kind: "Role", Name: randomString()},
+				Action:   string(libsveltosv1beta1.UpdateResourceAction),
+				Message:  randomString(),
+			},
+		}
+		group.Status.ObservedGeneration = group.Generation
+		pullmode.SetConfigurationItemStatus(group, &libsveltosv1beta1.ConfigurationItemStatus{
+			ContentRef:      *group.Spec.ConfigurationItems[0].ContentRef,
+			Hash:            group.Spec.ConfigurationItems[0].Hash,
+			ResourceReports: dryRunReports,
+		})
+		staleReport := libsveltosv1beta1.ResourceReport{
+			Resource: libsveltosv1beta1.Resource{Kind: "ConfigMap", Namespace: randomString(), Name: randomString()},
+			Action:   string(libsveltosv1beta1.DeleteResourceAction),
+		}
+		group.Status.StaleResourceReports = []libsveltosv1beta1.ResourceReport{staleReport}
+		Expect(c.Status().Update(context.TODO(), group)).To(Succeed())
+
+		reports, err := pullmode.GetDryRunReports(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(err).To(BeNil())
+		Expect(reports).To(Equal(append(dryRunReports, staleReport)))
+	})
+})
@@ -50,6 +50,12 @@ package pullmode
 // 3. (Optional) Calling DiscardStagedResourcesForDeployment if the resource preparation
 //    process fails and the staged resources should be discarded.
 //
+// Staging state is persisted as annotations on the staged ConfigurationBundles, so a workflow
+// interrupted by a restart or a leader-election handover can be resumed, committed or discarded
+// by the new instance. HasStagedResources reports whether anything is currently staged.
+// Staging sessions which are never committed nor discarded can be garbage collected by periodically
+// calling DiscardAbandonedStagedResources.
+//
 // Common Parameters:
 //
 // All methods in this package that interact with resources for a specific cluster and component
@@ -70,12 +76,22 @@ package pullmode
 // - DriftDetection: Instructs Sveltos to actively monitor for configuration drift in deployed resources.
 //
 // - DryRun:  Prevents actual deployment changes. Instead, Sveltos generates a report detailing the
-// changes that would occur on the managed clusters.
+// changes that would occur on the managed clusters, including stale resources that would be removed.
+// The report can be read back with GetDryRunReports.
 //
 // - Reloader:  Indicates whether Sveltos should automatically restart Deployments, StatefulSets, or DaemonSets
 // via a rolling upgrade when their mounted ConfigMaps or Secrets are modified. Setting this to true ensures
 // that any change in a mounted ConfigMap or Secret prompts an automatic rolling upgrade.
 //
+// - DependsOn: Records ConfigurationGroups, for the same cluster, whose content must be processed first.
+//
+// - SharedBundles: Stores content once, in a ConfigurationBundle named after its content, and lets the
+// ConfigurationGroups of all clusters receiving the same content reference it. Shared ConfigurationBundles
+// track the requestors referencing them and are deleted when the last reference goes away.
+//
+// - RevisionHistoryLimit: Keeps a copy of the last recorded contents. ListRevisions returns them and
+// RollbackToRevision points the ConfigurationGroup back at one of them, for the agent to re-apply.
+//
 //
 // Utility Functions:
 //
@@ -85,3 +101,34 @@ package pullmode
 //   It takes the cluster namespace and cluster name as input and returns a map of labels
 //   used to identify ConfigurationGroups relevant to that specific cluster.
 //
+// - GetClusterAudienceLabels/GetClusterLabelSelector: Variants of GetClusterLabels for clusters running
+//   multiple agents (for instance namespace-restricted agents owned by tenants). ConfigurationGroups can be
+//   addressed to an audience (WithAudience, or implicitly WithServiceAccount) and each agent only watches
+//   the ConfigurationGroups addressed to it.
+//
+// - SortConfigurationGroups: This method is made available to the agent running in the managed
+//   cluster. It returns the order ConfigurationGroups must be processed in, honoring DependsOn,
+//   holding back ConfigurationGroups whose dependencies are missing, and flags dependency cycles.
+//   ConfigurationGroups marked for removal come first, in reverse dependency order, so that a
+//   ConfigurationGroup is removed before the ones it depends on.
+//
+// - FetchConfigurationGroups: This method is made available to the agent running in the managed
+//   cluster. It lists the ConfigurationGroups ready for its cluster, in processing order, and for
+//   each one fetches, decrypts, validates and reassembles the referenced ConfigurationBundles,
+//   returning the resources to deploy already parsed.
+//
+// OCI Transport:
+//
+// Agents which cannot reach the management cluster API server can fetch content from an OCI registry.
+// PushConfigurationGroups exports the ConfigurationGroups for a cluster and an audience, and the content
+// they reference, as a single OCI artifact. Each ConfigurationBundle is a layer storing its content as is,
+// encrypted Secrets included. PullConfigurationGroups is the agent counterpart of FetchConfigurationGroups.
+//
+// Agent Heartbeat:
+//
+// In pull mode the agent updates SveltosCluster Status.AgentLastReportTime every AgentReportInterval.
+// EvaluateAgentHeartbeat compares it against the max age (SveltosCluster Spec.AgentHeartbeatMaxAge,
+// DefaultAgentHeartbeatMaxAge if not set) and reports whether the agent is Healthy, Late or Lost.
+// SetAgentHeartbeatCondition and ClearAgentHeartbeatCondition record the outcome as the AgentHeartbeat
+// condition on the SveltosCluster status.
+//
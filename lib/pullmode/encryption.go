@@ -0,0 +1,276 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// encryptionInfo is used to derive the key encrypting the per resource data key
+	encryptionInfo = "projectsveltos.io/pullmode/secret"
+	dataKeySize    = 32
+)
+
+// envelope is the content stored in a ConfigurationBundle for an encrypted resource.
+// Resource is encrypted with a random data key. The data key is in turn encrypted with a key
+// derived (ECDH X25519 + HKDF-SHA256) from an ephemeral key and the agent public key.
+type envelope struct {
+	EphemeralPublicKey []byte `json:"ephemeralPublicKey"`
+	WrappedKey         []byte `json:"wrappedKey"`
+	KeyNonce           []byte `json:"keyNonce"`
+	Nonce              []byte `json:"nonce"`
+	Ciphertext         []byte `json:"ciphertext"`
+}
+
+// GenerateEncryptionKey generates a new X25519 key pair. Agent keeps the private key and registers the
+// public key with RegisterEncryptionKey.
+func GenerateEncryptionKey() (privateKey, publicKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key.Bytes(), key.PublicKey().Bytes(), nil
+}
+
+// RegisterEncryptionKey stores the agent public key in the SveltosCluster status. From then on, management
+// cluster components recording resources with secret encryption enabled encrypt all Secrets with this key.
+// This method is made available to the agent running in the managed cluster.
+func RegisterEncryptionKey(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	publicKey []byte) error {
+
+	if _, err := ecdh.X25519().NewPublicKey(publicKey); err != nil {
+		return fmt.Errorf("invalid X25519 public key: %w", err)
+	}
+
+	sveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}, sveltosCluster)
+	if err != nil {
+		return err
+	}
+
+	if slices.Equal(sveltosCluster.Status.AgentEncryptionKey, publicKey) {
+		return nil
+	}
+
+	sveltosCluster.Status.AgentEncryptionKey = publicKey
+	return c.Status().Update(ctx, sveltosCluster)
+}
+
+// getClusterEncryptionKey returns the public key registered by the agent in the SveltosCluster
+func getClusterEncryptionKey(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+) ([]byte, error) {
+
+	sveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}, sveltosCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sveltosCluster.Status.AgentEncryptionKey) == 0 {
+		return nil, fmt.Errorf("SveltosCluster %s/%s: agent has not registered an encryption key",
+			clusterNamespace, clusterName)
+	}
+
+	return sveltosCluster.Status.AgentEncryptionKey, nil
+}
+
+// isSecret returns true if resource is a Secret
+func isSecret(resource *unstructured.Unstructured) bool {
+	return resource.GetAPIVersion() == "v1" && resource.GetKind() == "Secret"
+}
+
+func deriveKeyEncryptionKey(sharedSecret, ephemeralPublicKey, publicKey []byte) ([]byte, error) {
+	salt := slices.Concat(ephemeralPublicKey, publicKey)
+	return hkdf.Key(sha256.New, sharedSecret, salt, encryptionInfo, dataKeySize)
+}
+
+func sealContent(key, plaintext []byte) (nonce, ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func openContent(key, nonce, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// encryptResource encrypts content so that only the owner of the private key matching publicKey
+// can decrypt it. It returns the base64 encoded envelope.
+func encryptResource(content string, publicKey []byte) (string, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid X25519 public key: %w", err)
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	var env envelope
+	env.Nonce, env.Ciphertext, err = sealContent(dataKey, []byte(content))
+	if err != nil {
+		return "", err
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	sharedSecret, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return "", err
+	}
+
+	env.EphemeralPublicKey = ephemeral.PublicKey().Bytes()
+	kek, err := deriveKeyEncryptionKey(sharedSecret, env.EphemeralPublicKey, publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	env.KeyNonce, env.WrappedKey, err = sealContent(kek, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// decryptResource reverts encryptResource
+func decryptResource(encrypted string, privateKey []byte) (string, error) {
+	key, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid X25519 private key: %w", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to base64 decode content: %w", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return "", fmt.Errorf("failed to unmarshal envelope: %w", err)
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(env.EphemeralPublicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid ephemeral public key: %w", err)
+	}
+	sharedSecret, err := key.ECDH(ephemeral)
+	if err != nil {
+		return "", err
+	}
+
+	kek, err := deriveKeyEncryptionKey(sharedSecret, env.EphemeralPublicKey, key.PublicKey().Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := openContent(kek, env.KeyNonce, env.WrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+
+	plaintext, err := openContent(dataKey, env.Nonce, env.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt content: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// DecryptConfigurationBundle returns a copy of the ConfigurationBundle with all encrypted resources
+// decrypted using the agent private key. Content of the returned copy is not encoded (see
+// ConfigurationBundleSpec.Encoding) and can be read with GetConfigurationBundleResources.
+// This method is made available to the agent running in the managed cluster.
+func DecryptConfigurationBundle(bundle *libsveltosv1beta1.ConfigurationBundle, privateKey []byte,
+) (*libsveltosv1beta1.ConfigurationBundle, error) {
+
+	if bundle == nil {
+		return nil, fmt.Errorf("nil ConfigurationBundle")
+	}
+
+	resources, err := decodeConfigurationBundleResources(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := bundle.DeepCopy()
+	decrypted.Spec.Resources = slices.Clone(resources)
+	decrypted.Spec.Encoding = ""
+	decrypted.Spec.EncryptedResources = nil
+
+	for _, index := range bundle.Spec.EncryptedResources {
+		if index < 0 || index >= len(resources) {
+			return nil, fmt.Errorf("ConfigurationBundle %s/%s: invalid encrypted resource index %d",
+				bundle.Namespace, bundle.Name, index)
+		}
+
+		decrypted.Spec.Resources[index], err = decryptResource(resources[index], privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("ConfigurationBundle %s/%s: resource %d: %w",
+				bundle.Namespace, bundle.Name, index, err)
+		}
+	}
+
+	return decrypted, nil
+}
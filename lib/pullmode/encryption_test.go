@@ -0,0 +1,216 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/k8s_utils"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+var _ = Describe("Secret encryption", func() {
+	var logger logr.Logger
+	var privateKey, publicKey []byte
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+
+		var err error
+		privateKey, publicKey, err = pullmode.GenerateEncryptionKey()
+		Expect(err).To(BeNil())
+	})
+
+	It("prepareConfigurationBundle encrypts Secrets only", func() {
+		resources := getResourcesWithSecret()
+
+		bundle, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(), resources,
+			pullmode.WithEncryptionKey(publicKey))
+		Expect(err).To(BeNil())
+		secretIndex := len(resources) - 1
+		Expect(bundle.Spec.EncryptedResources).To(Equal([]int{secretIndex}))
+		Expect(bundle.Spec.Resources[secretIndex]).ToNot(ContainSubstring("Secret"))
+
+		_, err = pullmode.GetConfigurationBundleResources(bundle)
+		Expect(err).ToNot(BeNil())
+
+		plain, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(), resources)
+		Expect(err).To(BeNil())
+
+		decrypted, err := pullmode.DecryptConfigurationBundle(bundle, privateKey)
+		Expect(err).To(BeNil())
+		Expect(decrypted.Spec.EncryptedResources).To(BeNil())
+		content, err := pullmode.GetConfigurationBundleResources(decrypted)
+		Expect(err).To(BeNil())
+		Expect(content).To(Equal(plain.Spec.Resources))
+	})
+
+	It("DecryptConfigurationBundle handles compressed ConfigurationBundles", func() {
+		resources := getResourcesWithSecret()
+
+		bundle, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(), resources,
+			pullmode.WithEncryptionKey(publicKey), pullmode.WithCompression())
+		Expect(err).To(BeNil())
+		Expect(bundle.Spec.Encoding).To(Equal(libsveltosv1beta1.BundleEncodingGzipBase64))
+
+		plain, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(), resources)
+		Expect(err).To(BeNil())
+
+		decrypted, err := pullmode.DecryptConfigurationBundle(bundle, privateKey)
+		Expect(err).To(BeNil())
+		content, err := pullmode.GetConfigurationBundleResources(decrypted)
+		Expect(err).To(BeNil())
+		Expect(content).To(Equal(plain.Spec.Resources))
+	})
+
+	It("splitResources sizes Secrets once encrypted", func() {
+		resources := getResourcesWithSecret()
+
+		plain, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(), resources)
+		Expect(err).To(BeNil())
+		plainSize := 0
+		for i := range plain.Spec.Resources {
+			plainSize += len(plain.Spec.Resources[i])
+		}
+
+		parts, plainHash, err := pullmode.SplitResources(resources, plainSize, nil)
+		Expect(err).To(BeNil())
+		Expect(len(parts)).To(Equal(1))
+
+		// Encrypted Secret does not fit anymore
+		parts, encryptedHash, err := pullmode.SplitResources(resources, plainSize, publicKey)
+		Expect(err).To(BeNil())
+		Expect(len(parts)).To(Equal(2))
+		Expect(parts[1][0].GetKind()).To(Equal("Secret"))
+		Expect(encryptedHash).To(Equal(plainHash))
+	})
+
+	It("DecryptConfigurationBundle fails with a different key", func() {
+		bundle, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(),
+			getResourcesWithSecret(), pullmode.WithEncryptionKey(publicKey))
+		Expect(err).To(BeNil())
+
+		otherPrivateKey, _, err := pullmode.GenerateEncryptionKey()
+		Expect(err).To(BeNil())
+
+		_, err = pullmode.DecryptConfigurationBundle(bundle, otherPrivateKey)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("VerifyConfigurationGroup succeeds on decrypted ConfigurationBundles", func() {
+		_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+
+		namespace := randomString()
+		resources := getResourcesWithSecret()
+		bundle, err := pullmode.PrepareConfigurationBundle(namespace, randomString(), resources,
+			pullmode.WithEncryptionKey(publicKey))
+		Expect(err).To(BeNil())
+		hash, err := pullmode.GetHash(resources)
+		Expect(err).To(BeNil())
+
+		group, err := pullmode.PrepareConfigurationGroup(namespace, randomString(),
+			[]pullmode.BundleData{{Name: bundle.Name, Hash: hash}}, libsveltosv1beta1.ActionDeploy,
+			pullmode.WithSigningKey(signingKey))
+		Expect(err).To(BeNil())
+
+		decrypted, err := pullmode.DecryptConfigurationBundle(bundle, privateKey)
+		Expect(err).To(BeNil())
+
+		Expect(pullmode.VerifyConfigurationGroup(group, []libsveltosv1beta1.ConfigurationBundle{*decrypted},
+			signingKey.Public().(ed25519.PublicKey))).To(Succeed())
+	})
+
+	It("RecordResourcesForDeployment encrypts Secrets with the key registered by the agent", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		requestorKind := randomString()
+		requestorName := randomString()
+		requestorFeature := randomString()
+
+		createNamespace(clusterNamespace)
+
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      clusterName,
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), sveltosCluster)).To(Succeed())
+
+		resources := map[string][]unstructured.Unstructured{randomString(): getResourcesWithSecret()}
+
+		// No key registered yet
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), k8sClient, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, resources, logger,
+			pullmode.WithBundleSecretEncryption())).ToNot(Succeed())
+
+		Expect(pullmode.RegisterEncryptionKey(context.TODO(), k8sClient, clusterNamespace, clusterName,
+			publicKey)).To(Succeed())
+
+		Eventually(func() bool {
+			currentCluster := &libsveltosv1beta1.SveltosCluster{}
+			err := k8sClient.Get(context.TODO(),
+				types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}, currentCluster)
+			return err == nil && len(currentCluster.Status.AgentEncryptionKey) != 0
+		}, time.Minute, time.Second).Should(BeTrue())
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), k8sClient, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, resources, logger,
+			pullmode.WithBundleSecretEncryption())).To(Succeed())
+
+		labels := pullmode.GetConfigurationBundleLabels(clusterName, requestorKind, requestorFeature)
+		Eventually(func() bool {
+			configurationBundles := &libsveltosv1beta1.ConfigurationBundleList{}
+			err := k8sClient.List(context.TODO(), configurationBundles, client.InNamespace(clusterNamespace),
+				labels)
+			if err != nil || len(configurationBundles.Items) != 1 {
+				return false
+			}
+			return len(configurationBundles.Items[0].Spec.EncryptedResources) == 1
+		}, time.Minute, time.Second).Should(BeTrue())
+	})
+})
+
+func getResourcesWithSecret() []unstructured.Unstructured {
+	secret := `apiVersion: v1
+kind: Secret
+metadata:
+  name: example-secret
+  namespace: example-namespace
+stringData:
+  password: sveltos`
+
+	uSecret, err := k8s_utils.GetUnstructured([]byte(secret))
+	Expect(err).To(BeNil())
+
+	return append(getResources(), *uSecret)
+}
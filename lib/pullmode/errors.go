@@ -17,6 +17,8 @@ limitations under the License.
 package pullmode
 
 import (
+	"k8s.io/apimachinery/pkg/api/meta"
+
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
@@ -27,8 +29,15 @@ func (e *AgentHeartbeatTimeoutError) Error() string {
 	return "AgentLastReportTime is older than max allowed age."
 }
 
-// IsAgentTimeoutError checks if the SveltosCluster failure message matches AgentTimeoutError
+// IsAgentTimeoutError checks if the SveltosCluster AgentHeartbeat condition reports the agent as late
+// or lost (see SetAgentHeartbeatCondition). If the condition is not set, it checks whether the SveltosCluster
+// failure message matches AgentTimeoutError
 func IsAgentTimeoutError(sveltosCluster *libsveltosv1beta1.SveltosCluster) bool {
+	if meta.FindStatusCondition(sveltosCluster.Status.Conditions, AgentHeartbeatCondition) != nil {
+		state := GetAgentHeartbeatState(sveltosCluster)
+		return state == HeartbeatStateLate || state == HeartbeatStateLost
+	}
+
 	if sveltosCluster.Status.FailureMessage == nil {
 		return false
 	}
@@ -29,13 +29,37 @@ var (
 	ReconcileConfigurationGroup = reconcileConfigurationGroup
 	GetConfigurationGroups      = getConfigurationGroups
 
-	ApplyBundleSetters = applyBundleSetters
+	ApplyBundleSetters                = applyBundleSetters
+	PrepareConfigurationBundle        = prepareConfigurationBundle
+	GetConfigurationBundleAnnotations = getConfigurationBundleAnnotations
+	SplitResources                    = splitResources
+	GetPartIndex                      = getPartIndex
+	WithPart                          = withPart
+
+	PrepareConfigurationGroup = prepareConfigurationGroup
+	GetHash                   = getHash
+
+	WithEncryptionKey = withEncryptionKey
+
+	GetStagingSession = getStagingSession
+
+	ReadOCIContent = readOCIContent
 )
 
 const (
-	RequestorNameAnnotationKey = requestorNameAnnotationKey
+	RequestorNameAnnotationKey  = requestorNameAnnotationKey
+	StagingSessionAnnotationKey = stagingSessionAnnotationKey
+	StagingOrderAnnotationKey   = stagingOrderAnnotationKey
+	StagingTimeAnnotationKey    = stagingTimeAnnotationKey
+	SharedBundleLabelKey        = sharedBundleLabelKey
 )
 
 type (
-	BundleData = bundleData
+	BundleData  = bundleData
+	OCIManifest = ociManifest
 )
+
+// ResetStagedResourcesManager clears the in-memory staging state, simulating a restart
+func ResetStagedResourcesManager(clusterNamespace, clusterName, requestorName, requestorFeature string) {
+	getStagedResourcesManager().clearBundles(clusterNamespace, clusterName, requestorName, requestorFeature)
+}
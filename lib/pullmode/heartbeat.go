@@ -0,0 +1,164 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// AgentReportInterval is how often the agent in the managed cluster is expected to update
+	// SveltosCluster Status.AgentLastReportTime.
+	AgentReportInterval = time.Minute
+
+	// agentMissedReports is the number of consecutive reports the agent can miss, for instance
+	// because of a transient failure reaching the management cluster or of an agent restart,
+	// before being considered late.
+	agentMissedReports = 5
+
+	// DefaultAgentHeartbeatMaxAge is the maximum age of the agent last report used when
+	// SveltosCluster Spec.AgentHeartbeatMaxAge is not set.
+	DefaultAgentHeartbeatMaxAge = agentMissedReports * AgentReportInterval
+
+	// agentLostFactor defines when an agent late in reporting is considered lost: it happens
+	// when its last report is older than agentLostFactor times the max age.
+	agentLostFactor = 3
+
+	// AgentHeartbeatCondition is the SveltosCluster condition type reporting the agent heartbeat state.
+	AgentHeartbeatCondition = "AgentHeartbeat"
+)
+
+// HeartbeatState is the state of the agent heartbeat
+type HeartbeatState string
+
+const (
+	// HeartbeatStateHealthy indicates the agent reported within the max age
+	HeartbeatStateHealthy = HeartbeatState("Healthy")
+
+	// HeartbeatStateLate indicates the agent last report is older than the max age
+	HeartbeatStateLate = HeartbeatState("Late")
+
+	// HeartbeatStateLost indicates the agent has not reported for a long time
+	// (more than three times the max age)
+	HeartbeatStateLost = HeartbeatState("Lost")
+
+	// HeartbeatStateUnknown indicates the agent never reported
+	HeartbeatStateUnknown = HeartbeatState("Unknown")
+)
+
+// HeartbeatStatus is the outcome of the agent heartbeat evaluation
+type HeartbeatStatus struct {
+	// State is the agent heartbeat state
+	State HeartbeatState
+
+	// LastReportTime is the last time the agent reported. Nil if the agent never reported.
+	LastReportTime *metav1.Time
+
+	// MaxAge is the max age used for the evaluation
+	MaxAge time.Duration
+
+	// Overdue is how much the last report is older than MaxAge. Zero when the agent is healthy.
+	Overdue time.Duration
+}
+
+// GetAgentHeartbeatMaxAge returns the maximum age of the agent last report for a SveltosCluster
+func GetAgentHeartbeatMaxAge(sveltosCluster *libsveltosv1beta1.SveltosCluster) time.Duration {
+	if sveltosCluster.Spec.AgentHeartbeatMaxAge != nil && sveltosCluster.Spec.AgentHeartbeatMaxAge.Duration > 0 {
+		return sveltosCluster.Spec.AgentHeartbeatMaxAge.Duration
+	}
+	return DefaultAgentHeartbeatMaxAge
+}
+
+// EvaluateAgentHeartbeat evaluates, at time now, the heartbeat of the agent running in the managed
+// cluster using SveltosCluster Status.AgentLastReportTime and Spec.AgentHeartbeatMaxAge.
+func EvaluateAgentHeartbeat(sveltosCluster *libsveltosv1beta1.SveltosCluster, now time.Time) *HeartbeatStatus {
+	status := &HeartbeatStatus{
+		State:          HeartbeatStateUnknown,
+		LastReportTime: sveltosCluster.Status.AgentLastReportTime,
+		MaxAge:         GetAgentHeartbeatMaxAge(sveltosCluster),
+	}
+
+	if status.LastReportTime == nil {
+		return status
+	}
+
+	age := now.Sub(status.LastReportTime.Time)
+	switch {
+	case age <= status.MaxAge:
+		status.State = HeartbeatStateHealthy
+	case age <= agentLostFactor*status.MaxAge:
+		status.State = HeartbeatStateLate
+		status.Overdue = age - status.MaxAge
+	default:
+		status.State = HeartbeatStateLost
+		status.Overdue = age - status.MaxAge
+	}
+
+	return status
+}
+
+// SetAgentHeartbeatCondition sets the AgentHeartbeat condition on the SveltosCluster status
+// based on the heartbeat evaluation. Condition status is True only when the agent is healthy
+// and its reason is the heartbeat state. Message does not change while the state does not, so
+// re-evaluating the heartbeat does not cause any status update.
+// Caller is responsible for updating the SveltosCluster status.
+func SetAgentHeartbeatCondition(sveltosCluster *libsveltosv1beta1.SveltosCluster, status *HeartbeatStatus) {
+	condition := metav1.Condition{
+		Type:               AgentHeartbeatCondition,
+		Reason:             string(status.State),
+		ObservedGeneration: sveltosCluster.Generation,
+	}
+
+	switch status.State {
+	case HeartbeatStateHealthy:
+		condition.Status = metav1.ConditionTrue
+		condition.Message = "agent is reporting"
+	case HeartbeatStateUnknown:
+		condition.Status = metav1.ConditionUnknown
+		condition.Message = "agent never reported"
+	case HeartbeatStateLate:
+		condition.Status = metav1.ConditionFalse
+		condition.Message = fmt.Sprintf("agent has not reported for more than %s", status.MaxAge)
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Message = fmt.Sprintf("agent has not reported for more than %s", agentLostFactor*status.MaxAge)
+	}
+
+	meta.SetStatusCondition(&sveltosCluster.Status.Conditions, condition)
+}
+
+// ClearAgentHeartbeatCondition removes the AgentHeartbeat condition from the SveltosCluster status.
+// Caller is responsible for updating the SveltosCluster status.
+func ClearAgentHeartbeatCondition(sveltosCluster *libsveltosv1beta1.SveltosCluster) {
+	meta.RemoveStatusCondition(&sveltosCluster.Status.Conditions, AgentHeartbeatCondition)
+}
+
+// GetAgentHeartbeatState returns the agent heartbeat state recorded in the AgentHeartbeat
+// condition. HeartbeatStateUnknown is returned if the condition is not set.
+func GetAgentHeartbeatState(sveltosCluster *libsveltosv1beta1.SveltosCluster) HeartbeatState {
+	condition := meta.FindStatusCondition(sveltosCluster.Status.Conditions, AgentHeartbeatCondition)
+	if condition == nil {
+		return HeartbeatStateUnknown
+	}
+	return HeartbeatState(condition.Reason)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+var _ = Describe("Agent heartbeat", func() {
+	var sveltosCluster *libsveltosv1beta1.SveltosCluster
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Now()
+		sveltosCluster = &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Spec: libsveltosv1beta1.SveltosClusterSpec{
+				PullMode:             true,
+				AgentHeartbeatMaxAge: &metav1.Duration{Duration: time.Minute},
+			},
+		}
+	})
+
+	It("GetAgentHeartbeatMaxAge returns configured value or default", func() {
+		Expect(pullmode.GetAgentHeartbeatMaxAge(sveltosCluster)).To(Equal(time.Minute))
+
+		sveltosCluster.Spec.AgentHeartbeatMaxAge = nil
+		Expect(pullmode.GetAgentHeartbeatMaxAge(sveltosCluster)).To(Equal(pullmode.DefaultAgentHeartbeatMaxAge))
+	})
+
+	It("EvaluateAgentHeartbeat returns agent heartbeat state", func() {
+		status := pullmode.EvaluateAgentHeartbeat(sveltosCluster, now)
+		Expect(status.State).To(Equal(pullmode.HeartbeatStateUnknown))
+		Expect(status.LastReportTime).To(BeNil())
+
+		sveltosCluster.Status.AgentLastReportTime = &metav1.Time{Time: now.Add(-30 * time.Second)}
+		status = pullmode.EvaluateAgentHeartbeat(sveltosCluster, now)
+		Expect(status.State).To(Equal(pullmode.HeartbeatStateHealthy))
+		Expect(status.Overdue).To(BeZero())
+		Expect(status.MaxAge).To(Equal(time.Minute))
+
+		sveltosCluster.Status.AgentLastReportTime = &metav1.Time{Time: now.Add(-2 * time.Minute)}
+		status = pullmode.EvaluateAgentHeartbeat(sveltosCluster, now)
+		Expect(status.State).To(Equal(pullmode.HeartbeatStateLate))
+		Expect(status.Overdue).To(Equal(time.Minute))
+
+		sveltosCluster.Status.AgentLastReportTime = &metav1.Time{Time: now.Add(-10 * time.Minute)}
+		status = pullmode.EvaluateAgentHeartbeat(sveltosCluster, now)
+		Expect(status.State).To(Equal(pullmode.HeartbeatStateLost))
+		Expect(status.Overdue).To(Equal(9 * time.Minute))
+	})
+
+	It("SetAgentHeartbeatCondition and ClearAgentHeartbeatCondition manage the AgentHeartbeat condition", func() {
+		sveltosCluster.Status.AgentLastReportTime = &metav1.Time{Time: now.Add(-2 * time.Minute)}
+		pullmode.SetAgentHeartbeatCondition(sveltosCluster, pullmode.EvaluateAgentHeartbeat(sveltosCluster, now))
+
+		condition := meta.FindStatusCondition(sveltosCluster.Status.Conditions, pullmode.AgentHeartbeatCondition)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(string(pullmode.HeartbeatStateLate)))
+		Expect(pullmode.GetAgentHeartbeatState(sveltosCluster)).To(Equal(pullmode.HeartbeatStateLate))
+		Expect(pullmode.IsAgentTimeoutError(sveltosCluster)).To(BeTrue())
+
+		// Condition does not change while agent stays late
+		previous := *condition
+		pullmode.SetAgentHeartbeatCondition(sveltosCluster,
+			pullmode.EvaluateAgentHeartbeat(sveltosCluster, now.Add(30*time.Second)))
+		Expect(*meta.FindStatusCondition(sveltosCluster.Status.Conditions,
+			pullmode.AgentHeartbeatCondition)).To(Equal(previous))
+
+		sveltosCluster.Status.AgentLastReportTime = &metav1.Time{Time: now}
+		pullmode.SetAgentHeartbeatCondition(sveltosCluster, pullmode.EvaluateAgentHeartbeat(sveltosCluster, now))
+		Expect(len(sveltosCluster.Status.Conditions)).To(Equal(1))
+		Expect(sveltosCluster.Status.Conditions[0].Status).To(Equal(metav1.ConditionTrue))
+		Expect(pullmode.IsAgentTimeoutError(sveltosCluster)).To(BeFalse())
+
+		pullmode.ClearAgentHeartbeatCondition(sveltosCluster)
+		Expect(sveltosCluster.Status.Conditions).To(BeEmpty())
+		Expect(pullmode.GetAgentHeartbeatState(sveltosCluster)).To(Equal(pullmode.HeartbeatStateUnknown))
+	})
+
+	It("IsAgentTimeoutError falls back to failure message when condition is not set", func() {
+		Expect(pullmode.IsAgentTimeoutError(sveltosCluster)).To(BeFalse())
+
+		failureErr := pullmode.AgentHeartbeatTimeoutError{}
+		failureMsg := failureErr.Error()
+		sveltosCluster.Status.FailureMessage = &failureMsg
+		Expect(pullmode.IsAgentTimeoutError(sveltosCluster)).To(BeTrue())
+	})
+})
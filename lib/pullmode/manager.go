@@ -17,14 +17,39 @@ limitations under the License.
 package pullmode
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/libsveltos/lib/randutils"
 )
 
+const (
+	// Staged ConfigurationBundles are annotated with the staging session they belong to and
+	// the order they were staged in. This allows staging to survive process restarts and
+	// leader-election handovers: the in-memory stagedManager is rebuilt from those annotations.
+	stagingSessionAnnotationKey = "pullmode.projectsveltos.io/stagingsession"
+	stagingOrderAnnotationKey   = "pullmode.projectsveltos.io/stagingorder"
+	// stagingTimeAnnotationKey records last time resources were staged. It is set on staged
+	// ConfigurationBundles and on ConfigurationGroups moved to the Preparing phase.
+	stagingTimeAnnotationKey = "pullmode.projectsveltos.io/stagingtime"
+)
+
+// DefaultStagingTTL is the suggested amount of time after which a staging session without any
+// activity is considered abandoned (see DiscardAbandonedStagedResources).
+const DefaultStagingTTL = time.Hour
+
 type stagedManager struct {
 	mu             sync.RWMutex
 	stagedBundles  map[string][]corev1.ObjectReference
@@ -101,3 +126,321 @@ func (s *stagedManager) clearBundles(clusterNamespace, clusterName, requestorNam
 
 	delete(s.stagedBundles, key)
 }
+
+// getPersistedStagedBundles returns all ConfigurationBundles currently staged for a requestor, as
+// recorded in the ConfigurationBundle annotations.
+func getPersistedStagedBundles(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+) ([]libsveltosv1beta1.ConfigurationBundle, error) {
+
+	labels := getConfigurationBundleLabels(clusterName, requestorKind, requestorFeature)
+	configurationBundles, err := getConfigurationBundles(ctx, c, clusterNamespace, requestorName, "", labels)
+	if err != nil {
+		return nil, err
+	}
+
+	staged := make([]libsveltosv1beta1.ConfigurationBundle, 0)
+	for i := range configurationBundles.Items {
+		if _, ok := configurationBundles.Items[i].Annotations[stagingSessionAnnotationKey]; ok {
+			staged = append(staged, configurationBundles.Items[i])
+		}
+	}
+
+	return staged, nil
+}
+
+// getStagedBundles returns all ConfigurationBundles currently staged for a requestor, sorted by
+// staging order. Persisted annotations are authoritative: staging might have been committed or
+// discarded by another instance (after a leader-election handover) or by
+// DiscardAbandonedStagedResources. Bundles tracked in memory by this process are only used, in
+// place of the persisted ones (client cache might not be synced yet), if they belong to a
+// persisted staging session and still exist with that session.
+// Returned bundles can belong to different sessions (see getSession).
+func getStagedBundles(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+) ([]libsveltosv1beta1.ConfigurationBundle, error) {
+
+	persisted, err := getPersistedStagedBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleMap := make(map[string]libsveltosv1beta1.ConfigurationBundle)
+	persistedSessions := make(map[string]bool)
+	for i := range persisted {
+		bundleMap[persisted[i].Name] = persisted[i]
+		persistedSessions[persisted[i].Annotations[stagingSessionAnnotationKey]] = true
+	}
+
+	manager := getStagedResourcesManager()
+	inMemory := manager.getBundles(clusterNamespace, clusterName, requestorName, requestorFeature)
+	for i := range inMemory {
+		valid, err := isValidInMemoryStagedBundle(ctx, c, &inMemory[i], persistedSessions, bundleMap)
+		if err != nil {
+			return nil, err
+		}
+		if valid {
+			bundleMap[inMemory[i].Name] = inMemory[i]
+		}
+	}
+
+	staged := make([]libsveltosv1beta1.ConfigurationBundle, 0, len(bundleMap))
+	for k := range bundleMap {
+		staged = append(staged, bundleMap[k])
+	}
+
+	sort.SliceStable(staged, func(i, j int) bool {
+		orderI, orderJ := getStagingOrder(&staged[i]), getStagingOrder(&staged[j])
+		if orderI != orderJ {
+			return orderI < orderJ
+		}
+		partI, _, _, _ := getBundlePart(&staged[i])
+		partJ, _, _, _ := getBundlePart(&staged[j])
+		if partI != partJ {
+			return partI < partJ
+		}
+		return staged[i].Name < staged[j].Name
+	})
+
+	return staged, nil
+}
+
+// isValidInMemoryStagedBundle returns true if the ConfigurationBundle tracked in memory still
+// belongs to the current staging session.
+// If any bundle is persisted, its session must be a persisted one. In any case the bundle must
+// still exist with the same session.
+func isValidInMemoryStagedBundle(ctx context.Context, c client.Client,
+	bundle *libsveltosv1beta1.ConfigurationBundle, persistedSessions map[string]bool,
+	persisted map[string]libsveltosv1beta1.ConfigurationBundle) (bool, error) {
+
+	session := bundle.Annotations[stagingSessionAnnotationKey]
+	if len(persistedSessions) != 0 && !persistedSessions[session] {
+		return false, nil
+	}
+
+	if _, ok := persisted[bundle.Name]; ok {
+		return true, nil
+	}
+
+	current := &libsveltosv1beta1.ConfigurationBundle{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: bundle.Namespace, Name: bundle.Name}, current)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return current.Annotations[stagingSessionAnnotationKey] == session, nil
+}
+
+// getSession returns the staging session the staged bundles belong to. An error is returned
+// if they belong to different sessions: such staged content can only be discarded.
+func getSession(staged []libsveltosv1beta1.ConfigurationBundle) (string, error) {
+	session := ""
+	for i := range staged {
+		bundleSession := staged[i].Annotations[stagingSessionAnnotationKey]
+		if session == "" {
+			session = bundleSession
+			continue
+		}
+		if bundleSession != session {
+			return "", fmt.Errorf("staged ConfigurationBundles belong to different staging sessions (%s, %s)",
+				session, bundleSession)
+		}
+	}
+
+	return session, nil
+}
+
+func getStagingOrder(bundle *libsveltosv1beta1.ConfigurationBundle) int {
+	order, err := strconv.Atoi(bundle.Annotations[stagingOrderAnnotationKey])
+	if err != nil {
+		return 0
+	}
+	return order
+}
+
+// getStagingSession returns the staging session the staged bundles belong to and the order
+// to use for the next staged bundle. A new session is returned if nothing is currently staged.
+// An error is returned if staged bundles belong to different sessions.
+func getStagingSession(staged []libsveltosv1beta1.ConfigurationBundle) (session string, nextOrder int, err error) {
+	session, err = getSession(staged)
+	if err != nil {
+		return "", 0, err
+	}
+
+	for i := range staged {
+		if order := getStagingOrder(&staged[i]); order >= nextOrder {
+			nextOrder = order + 1
+		}
+	}
+
+	if session == "" {
+		const sessionLength = 10
+		session = randutils.RandomString(sessionLength)
+	}
+
+	return session, nextOrder, nil
+}
+
+// clearStagingAnnotations removes the staging annotations from the ConfigurationBundles.
+// A merge patch is used so that outcome does not depend on the client cache being synced.
+// ConfigurationBundles not existing anymore are ignored.
+func clearStagingAnnotations(ctx context.Context, c client.Client,
+	bundles []libsveltosv1beta1.ConfigurationBundle) error {
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:null,%q:null}}}`,
+		stagingSessionAnnotationKey, stagingOrderAnnotationKey, stagingTimeAnnotationKey)
+
+	for i := range bundles {
+		bundle := bundles[i].DeepCopy()
+		err := c.Patch(ctx, bundle, client.RawPatch(types.MergePatchType, []byte(patch)))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clearCompletedStaging removes staging annotations left behind by a commit or discard which did not
+// complete (for instance because of a restart). Those are detected because no staging is in progress,
+// i.e. the ConfigurationGroup exists and is not in the Preparing phase.
+func clearCompletedStaging(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string) error {
+
+	labels := getConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+	_, currentCG, err := getConfigurationGroupName(ctx, c, clusterNamespace, requestorName, labels)
+	if err != nil {
+		return err
+	}
+
+	if currentCG == nil ||
+		currentCG.(*libsveltosv1beta1.ConfigurationGroup).Spec.UpdatePhase == libsveltosv1beta1.UpdatePhasePreparing {
+
+		return nil
+	}
+
+	persisted, err := getPersistedStagedBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		return err
+	}
+
+	return clearStagingAnnotations(ctx, c, persisted)
+}
+
+// stagingRequestor identifies the requestor a staging session belongs to
+type stagingRequestor struct {
+	clusterNamespace string
+	clusterName      string
+	requestorKind    string
+	requestorName    string
+	requestorFeature string
+}
+
+// getStagingRequestor returns the requestor a ConfigurationBundle/ConfigurationGroup was created for
+func getStagingRequestor(obj client.Object) stagingRequestor {
+	return stagingRequestor{
+		clusterNamespace: obj.GetNamespace(),
+		clusterName:      obj.GetLabels()[clusterNameLabelKey],
+		requestorKind:    obj.GetLabels()[requestorKindLabelKey],
+		requestorName:    obj.GetAnnotations()[requestorNameAnnotationKey],
+		requestorFeature: obj.GetLabels()[requestorFeatureLabelKey],
+	}
+}
+
+// updateLastStagingTime records in lastActivity the staging time of obj if more recent than
+// the one currently stored for the same requestor.
+// Objects with no staging time (staged by a version not recording it) are stamped with current
+// time, so they will be considered abandoned once ttl expires.
+func updateLastStagingTime(ctx context.Context, c client.Client, lastActivity map[stagingRequestor]time.Time,
+	obj client.Object) error {
+
+	stagingTime, err := time.Parse(time.RFC3339, obj.GetAnnotations()[stagingTimeAnnotationKey])
+	if err != nil {
+		stagingTime = time.Now().UTC()
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, stagingTimeAnnotationKey,
+			stagingTime.Format(time.RFC3339))
+		err = c.Patch(ctx, obj, client.RawPatch(types.MergePatchType, []byte(patch)))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	requestor := getStagingRequestor(obj)
+	if v, ok := lastActivity[requestor]; !ok || stagingTime.After(v) {
+		lastActivity[requestor] = stagingTime
+	}
+
+	return nil
+}
+
+// DiscardAbandonedStagedResources discards all staging sessions with no activity in the last ttl.
+// A staging session is abandoned when StageResourcesForDeployment was called but neither
+// CommitStagedResourcesForDeployment nor DiscardStagedResourcesForDeployment followed (for instance
+// because the requestor was deleted while resources were being prepared).
+// For each abandoned session, staged ConfigurationBundles not referenced by the ConfigurationGroup are
+// deleted and the ConfigurationGroup, if in the Preparing phase, is moved back to Ready.
+// This method is meant to be periodically invoked by a management cluster controller. If namespace is
+// not empty, only staging sessions for SveltosClusters in that namespace are considered.
+func DiscardAbandonedStagedResources(ctx context.Context, c client.Client, namespace string,
+	ttl time.Duration, logger logr.Logger) error {
+
+	lastActivity := make(map[stagingRequestor]time.Time)
+
+	configurationBundles := &libsveltosv1beta1.ConfigurationBundleList{}
+	if err := c.List(ctx, configurationBundles, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range configurationBundles.Items {
+		bundle := &configurationBundles.Items[i]
+		if _, ok := bundle.Annotations[stagingSessionAnnotationKey]; !ok {
+			continue
+		}
+		if err := updateLastStagingTime(ctx, c, lastActivity, bundle); err != nil {
+			return err
+		}
+	}
+
+	configurationGroups := &libsveltosv1beta1.ConfigurationGroupList{}
+	if err := c.List(ctx, configurationGroups, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range configurationGroups.Items {
+		group := &configurationGroups.Items[i]
+		if group.Spec.UpdatePhase != libsveltosv1beta1.UpdatePhasePreparing {
+			continue
+		}
+		if err := updateLastStagingTime(ctx, c, lastActivity, group); err != nil {
+			return err
+		}
+	}
+
+	for requestor, last := range lastActivity {
+		if time.Since(last) < ttl {
+			continue
+		}
+
+		l := logger.WithValues("cluster", fmt.Sprintf("%s/%s", requestor.clusterNamespace, requestor.clusterName),
+			"requestor", fmt.Sprintf("%s/%s", requestor.requestorKind, requestor.requestorName),
+			"feature", requestor.requestorFeature)
+		l.V(logs.LogInfo).Info(fmt.Sprintf("discarding staging session abandoned since %s", last.Format(time.RFC3339)))
+
+		err := DiscardStagedResourcesForDeployment(ctx, c, requestor.clusterNamespace, requestor.clusterName,
+			requestor.requestorKind, requestor.requestorName, requestor.requestorFeature, l)
+		if err != nil {
+			return err
+		}
+
+		err = markConfigurationGroupAsReady(ctx, c, requestor.clusterNamespace, requestor.clusterName,
+			requestor.requestorKind, requestor.requestorName, requestor.requestorFeature, l)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
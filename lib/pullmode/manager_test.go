@@ -0,0 +1,258 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+var _ = Describe("Staging", func() {
+	var logger logr.Logger
+	var c client.Client
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+
+		c = k8sClient
+	})
+
+	It("staged resources survive a restart and can be committed", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		requestorKind := randomString()
+		requestorName := randomString()
+		requestorFeature := randomString()
+
+		createNamespace(clusterNamespace)
+
+		indexes := []string{randomString(), randomString(), randomString()}
+		for i := range indexes {
+			resources := map[string][]unstructured.Unstructured{indexes[i]: getResources()}
+			Expect(pullmode.StageResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+				requestorKind, requestorName, requestorFeature, resources, false, logger)).To(Succeed())
+		}
+
+		// Simulate a restart
+		pullmode.ResetStagedResourcesManager(clusterNamespace, clusterName, requestorName, requestorFeature)
+
+		staged, err := pullmode.HasStagedResources(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature)
+		Expect(err).To(BeNil())
+		Expect(staged).To(BeTrue())
+
+		Expect(pullmode.CommitStagedResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)).To(Succeed())
+
+		labels := pullmode.GetConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+		groups, err := pullmode.GetConfigurationGroups(context.TODO(), c, clusterNamespace, requestorName, labels)
+		Expect(err).To(BeNil())
+		Expect(len(groups.Items)).To(Equal(1))
+		Expect(groups.Items[0].Spec.UpdatePhase).To(Equal(libsveltosv1beta1.UpdatePhaseReady))
+
+		// ConfigurationItems follow staging order
+		items := groups.Items[0].Spec.ConfigurationItems
+		Expect(len(items)).To(Equal(len(indexes)))
+		for i := range indexes {
+			bundles, err := pullmode.GetConfigurationBundles(context.TODO(), c, clusterNamespace, requestorName,
+				indexes[i], labels)
+			Expect(err).To(BeNil())
+			Expect(len(bundles.Items)).To(Equal(1))
+			Expect(items[i].ContentRef.Name).To(Equal(bundles.Items[0].Name))
+			Expect(bundles.Items[0].Annotations).ToNot(HaveKey(pullmode.StagingSessionAnnotationKey))
+		}
+
+		staged, err = pullmode.HasStagedResources(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature)
+		Expect(err).To(BeNil())
+		Expect(staged).To(BeFalse())
+	})
+
+	It("staged resources survive a restart and can be discarded", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		requestorKind := randomString()
+		requestorName := randomString()
+		requestorFeature := randomString()
+
+		createNamespace(clusterNamespace)
+
+		resources := map[string][]unstructured.Unstructured{randomString(): getResources()}
+		Expect(pullmode.StageResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, resources, false, logger)).To(Succeed())
+
+		// Simulate a restart
+		pullmode.ResetStagedResourcesManager(clusterNamespace, clusterName, requestorName, requestorFeature)
+
+		Expect(pullmode.DiscardStagedResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)).To(Succeed())
+
+		labels := pullmode.GetConfigurationBundleLabels(clusterName, requestorKind, requestorFeature)
+		bundles, err := pullmode.GetConfigurationBundles(context.TODO(), c, clusterNamespace, requestorName,
+			"", labels)
+		Expect(err).To(BeNil())
+		Expect(len(bundles.Items)).To(BeZero())
+
+		staged, err := pullmode.HasStagedResources(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature)
+		Expect(err).To(BeNil())
+		Expect(staged).To(BeFalse())
+	})
+
+	It("in-memory staged resources committed or discarded by another instance are ignored", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		requestorKind := randomString()
+		requestorName := randomString()
+		requestorFeature := randomString()
+
+		createNamespace(clusterNamespace)
+
+		committedIndex := randomString()
+		discardedIndex := randomString()
+		resources := map[string][]unstructured.Unstructured{
+			committedIndex: getResources(),
+			discardedIndex: getResources(),
+		}
+		Expect(pullmode.StageResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, resources, false, logger)).To(Succeed())
+
+		labels := pullmode.GetConfigurationBundleLabels(clusterName, requestorKind, requestorFeature)
+
+		// Another instance committed one bundle (staging annotations removed)...
+		bundles, err := pullmode.GetConfigurationBundles(context.TODO(), c, clusterNamespace, requestorName,
+			committedIndex, labels)
+		Expect(err).To(BeNil())
+		Expect(len(bundles.Items)).To(Equal(1))
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:null}}}`,
+			pullmode.StagingSessionAnnotationKey, pullmode.StagingOrderAnnotationKey)
+		Expect(c.Patch(context.TODO(), &bundles.Items[0],
+			client.RawPatch(types.MergePatchType, []byte(patch)))).To(Succeed())
+
+		// ...and discarded the other one
+		bundles, err = pullmode.GetConfigurationBundles(context.TODO(), c, clusterNamespace, requestorName,
+			discardedIndex, labels)
+		Expect(err).To(BeNil())
+		Expect(len(bundles.Items)).To(Equal(1))
+		Expect(c.Delete(context.TODO(), &bundles.Items[0])).To(Succeed())
+
+		Eventually(func() bool {
+			staged, err := pullmode.HasStagedResources(context.TODO(), c, clusterNamespace, clusterName,
+				requestorKind, requestorName, requestorFeature)
+			return err == nil && !staged
+		}, time.Minute, time.Second).Should(BeTrue())
+	})
+
+	It("getStagingSession rejects staged resources belonging to different sessions", func() {
+		getBundle := func(session, order string) libsveltosv1beta1.ConfigurationBundle {
+			return libsveltosv1beta1.ConfigurationBundle{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: randomString(),
+					Annotations: map[string]string{
+						pullmode.StagingSessionAnnotationKey: session,
+						pullmode.StagingOrderAnnotationKey:   order,
+					},
+				},
+			}
+		}
+
+		session := randomString()
+		current, next, err := pullmode.GetStagingSession([]libsveltosv1beta1.ConfigurationBundle{
+			getBundle(session, "0"), getBundle(session, "1"),
+		})
+		Expect(err).To(BeNil())
+		Expect(current).To(Equal(session))
+		Expect(next).To(Equal(2))
+
+		_, _, err = pullmode.GetStagingSession([]libsveltosv1beta1.ConfigurationBundle{
+			getBundle(session, "0"), getBundle(randomString(), "1"),
+		})
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("DiscardAbandonedStagedResources discards only abandoned staging sessions", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		requestorKind := randomString()
+		requestorFeature := randomString()
+
+		createNamespace(clusterNamespace)
+
+		// abandoned requestor has deployed content and then a staging session that is never completed
+		abandoned := randomString()
+		deployed := map[string][]unstructured.Unstructured{randomString(): getResources()}
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, abandoned, requestorFeature, deployed, logger)).To(Succeed())
+		staged := map[string][]unstructured.Unstructured{randomString(): getResources()}
+		Expect(pullmode.StageResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, abandoned, requestorFeature, staged, false, logger)).To(Succeed())
+
+		// active requestor has a staging session still in progress
+		active := randomString()
+		Expect(pullmode.StageResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, active, requestorFeature, staged, false, logger)).To(Succeed())
+
+		// Age the abandoned staging session
+		old := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+		labels := pullmode.GetConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+		bundles, err := pullmode.GetConfigurationBundles(context.TODO(), c, clusterNamespace, abandoned, "", labels)
+		Expect(err).To(BeNil())
+		Expect(len(bundles.Items)).To(Equal(2))
+		for i := range bundles.Items {
+			bundles.Items[i].Annotations[pullmode.StagingTimeAnnotationKey] = old
+			Expect(c.Update(context.TODO(), &bundles.Items[i])).To(Succeed())
+		}
+		groups, err := pullmode.GetConfigurationGroups(context.TODO(), c, clusterNamespace, abandoned, labels)
+		Expect(err).To(BeNil())
+		Expect(len(groups.Items)).To(Equal(1))
+		Expect(groups.Items[0].Spec.UpdatePhase).To(Equal(libsveltosv1beta1.UpdatePhasePreparing))
+		groups.Items[0].Annotations[pullmode.StagingTimeAnnotationKey] = old
+		Expect(c.Update(context.TODO(), &groups.Items[0])).To(Succeed())
+
+		Expect(pullmode.DiscardAbandonedStagedResources(context.TODO(), c, clusterNamespace, time.Hour, logger)).To(Succeed())
+
+		// Staged bundle is gone, deployed one is still there and ConfigurationGroup is Ready again
+		groups, err = pullmode.GetConfigurationGroups(context.TODO(), c, clusterNamespace, abandoned, labels)
+		Expect(err).To(BeNil())
+		Expect(len(groups.Items)).To(Equal(1))
+		Expect(groups.Items[0].Spec.UpdatePhase).To(Equal(libsveltosv1beta1.UpdatePhaseReady))
+		bundles, err = pullmode.GetConfigurationBundles(context.TODO(), c, clusterNamespace, abandoned, "", labels)
+		Expect(err).To(BeNil())
+		Expect(len(bundles.Items)).To(Equal(1))
+		Expect(bundles.Items[0].Name).To(Equal(groups.Items[0].Spec.ConfigurationItems[0].ContentRef.Name))
+
+		// Active staging session is untouched
+		inProgress, err := pullmode.HasStagedResources(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, active, requestorFeature)
+		Expect(err).To(BeNil())
+		Expect(inProgress).To(BeTrue())
+	})
+})
@@ -0,0 +1,537 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Content for a cluster and an audience can be exported to an OCI registry as a single artifact, for
+// agents which cannot reach the management cluster API server.
+// The artifact config contains the ConfigurationGroups and the ConfigurationBundles metadata. Each
+// ConfigurationBundle content is a layer: the JSON list of its resources exactly as stored in the
+// ConfigurationBundle (so compressed and/or encrypted if they are). Layer digest is therefore not the
+// hash recorded in the ConfigurationGroup, which is evaluated on resources once decoded and decrypted:
+// latter is set in the ociContentHashAnnotation layer annotation. Agent verifies each layer against its
+// digest and, once decoded and decrypted, against the hash recorded in the ConfigurationGroup.
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociArtifactType      = "application/vnd.projectsveltos.pullmode.v1"
+	ociConfigMediaType   = "application/vnd.projectsveltos.pullmode.config.v1+json"
+	ociLayerMediaType    = "application/vnd.projectsveltos.pullmode.resources.v1+json"
+
+	// ociBundleAnnotation is set on each layer to the namespace/name of the ConfigurationBundle it stores
+	ociBundleAnnotation = "io.projectsveltos.pullmode.bundle"
+
+	// ociContentHashAnnotation is set on each layer to the hex encoded hash recorded in the
+	// ConfigurationGroup for the ConfigurationBundle it stores
+	ociContentHashAnnotation = "io.projectsveltos.pullmode.hash"
+
+	// ociContentDigestHeader is the header registries return the manifest digest in
+	ociContentDigestHeader = "Docker-Content-Digest"
+
+	// maxOCIBlobSize limits the size of blobs and manifests read from the registry
+	maxOCIBlobSize = 64 * 1024 * 1024
+)
+
+// OCIRepository identifies the repository, in an OCI registry, pull-mode content is exported to.
+type OCIRepository struct {
+	// Host is the registry host (and optional port), for instance registry.example.com:5000
+	Host string
+
+	// Repository is the repository name, for instance sveltos/clusters
+	Repository string
+
+	// PlainHTTP, when true, uses http instead of https
+	PlainHTTP bool
+
+	// Username and Password, when set, are used for basic authentication
+	Username string
+	Password string
+
+	// HTTPClient is the client used to reach the registry. http.DefaultClient is used if not set
+	HTTPClient *http.Client
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	ArtifactType  string          `json:"artifactType,omitempty"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+// ociConfig is the artifact config
+type ociConfig struct {
+	ConfigurationGroups []libsveltosv1beta1.ConfigurationGroup `json:"configurationGroups"`
+
+	// ConfigurationBundles contains ConfigurationBundles metadata. Their content is stored in the
+	// artifact layers.
+	ConfigurationBundles []libsveltosv1beta1.ConfigurationBundle `json:"configurationBundles"`
+}
+
+func getOCIDigest(data []byte) string {
+	hash := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// getBundleBlob returns the JSON list of the ConfigurationBundle resources, as stored.
+func getBundleBlob(bundle *libsveltosv1beta1.ConfigurationBundle) ([]byte, error) {
+	return json.Marshal(bundle.Spec.Resources)
+}
+
+// getBundleResourcesFromBlob returns the resources stored in a layer
+func getBundleResourcesFromBlob(blob []byte) ([]string, error) {
+	var resources []string
+	if err := json.Unmarshal(blob, &resources); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// PushConfigurationGroups exports the ConfigurationGroups ready for the cluster clusterNamespace/clusterName
+// and addressed to audience (see WithAudience), along with the content they reference, as an OCI artifact
+// tagged tag in the given repository. When audience is empty, only ConfigurationGroups not addressed to any
+// audience are exported. Content addressed to different audiences must be pushed to different tags (or
+// repositories), one per audience, so each agent only gets what is addressed to it.
+// Agents which cannot reach the management cluster fetch it using PullConfigurationGroups.
+// Encrypted resources are exported encrypted, agent decrypts them (see WithDecryptionKey).
+// It returns the digest of the pushed artifact manifest.
+func PushConfigurationGroups(ctx context.Context, c client.Client, clusterNamespace, clusterName, audience string,
+	repository *OCIRepository, tag string, logger logr.Logger) (string, error) {
+
+	selector, err := GetClusterLabelSelector(clusterNamespace, clusterName, audience)
+	if err != nil {
+		return "", err
+	}
+
+	confGroups := &libsveltosv1beta1.ConfigurationGroupList{}
+	listOptions := []client.ListOption{
+		client.InNamespace(clusterNamespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	}
+	if err := c.List(ctx, confGroups, listOptions...); err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to list ConfigurationGroups: %v", err))
+		return "", err
+	}
+
+	config := &ociConfig{
+		ConfigurationGroups:  make([]libsveltosv1beta1.ConfigurationGroup, 0, len(confGroups.Items)),
+		ConfigurationBundles: make([]libsveltosv1beta1.ConfigurationBundle, 0),
+	}
+	layers := make([]ociDescriptor, 0)
+	exported := make(map[string]bool)
+
+	for i := range confGroups.Items {
+		confGroup := &confGroups.Items[i]
+		if confGroup.Spec.UpdatePhase == libsveltosv1beta1.UpdatePhasePreparing {
+			continue
+		}
+
+		config.ConfigurationGroups = append(config.ConfigurationGroups, libsveltosv1beta1.ConfigurationGroup{
+			ObjectMeta: getExportedObjectMeta(confGroup),
+			Spec:       confGroup.Spec,
+		})
+
+		for j := range confGroup.Spec.ConfigurationItems {
+			item := &confGroup.Spec.ConfigurationItems[j]
+			if item.ContentRef == nil {
+				continue
+			}
+
+			key := fmt.Sprintf("%s/%s", item.ContentRef.Namespace, item.ContentRef.Name)
+			if exported[key] {
+				continue
+			}
+
+			layer, bundle, err := pushConfigurationBundle(ctx, c, repository, item)
+			if err != nil {
+				logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to push ConfigurationBundle %s: %v", key, err))
+				return "", err
+			}
+
+			exported[key] = true
+			layers = append(layers, *layer)
+			config.ConfigurationBundles = append(config.ConfigurationBundles, *bundle)
+		}
+	}
+
+	configBlob, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	configDigest := getOCIDigest(configBlob)
+	if err := repository.pushBlob(ctx, configDigest, configBlob); err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to push config: %v", err))
+		return "", err
+	}
+
+	manifest, err := json.Marshal(&ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  ociArtifactType,
+		Config:        ociDescriptor{MediaType: ociConfigMediaType, Digest: configDigest, Size: int64(len(configBlob))},
+		Layers:        layers,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := repository.pushManifest(ctx, tag, manifest); err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to push manifest: %v", err))
+		return "", err
+	}
+
+	digest := getOCIDigest(manifest)
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("pushed %d ConfigurationGroups to %s/%s:%s (%s)",
+		len(config.ConfigurationGroups), repository.Host, repository.Repository, tag, digest))
+	return digest, nil
+}
+
+// pushConfigurationBundle pushes the content of the ConfigurationBundle referenced by item as a blob.
+// It returns the layer descriptor and the ConfigurationBundle metadata.
+func pushConfigurationBundle(ctx context.Context, c client.Client, repository *OCIRepository,
+	item *libsveltosv1beta1.ConfigurationItem) (*ociDescriptor, *libsveltosv1beta1.ConfigurationBundle, error) {
+
+	bundle := &libsveltosv1beta1.ConfigurationBundle{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: item.ContentRef.Namespace, Name: item.ContentRef.Name},
+		bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Encrypted content can only be validated by the agent
+	if len(bundle.Spec.EncryptedResources) == 0 && len(item.Hash) != 0 {
+		hash, err := getBundleHash(bundle)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !bytes.Equal(hash, item.Hash) {
+			return nil, nil, fmt.Errorf("ConfigurationBundle %s/%s: content hash mismatch",
+				bundle.Namespace, bundle.Name)
+		}
+	}
+
+	blob, err := getBundleBlob(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digest := getOCIDigest(blob)
+	if err := repository.pushBlob(ctx, digest, blob); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := libsveltosv1beta1.ConfigurationBundle{
+		ObjectMeta: getExportedObjectMeta(bundle),
+		Spec:       bundle.Spec,
+		Status:     libsveltosv1beta1.ConfigurationBundleStatus{Hash: bundle.Status.Hash},
+	}
+	metadata.Spec.Resources = nil
+
+	layer := &ociDescriptor{
+		MediaType:   ociLayerMediaType,
+		Digest:      digest,
+		Size:        int64(len(blob)),
+		Annotations: map[string]string{ociBundleAnnotation: fmt.Sprintf("%s/%s", bundle.Namespace, bundle.Name)},
+	}
+	if len(item.Hash) != 0 {
+		layer.Annotations[ociContentHashAnnotation] = hex.EncodeToString(item.Hash)
+	}
+
+	return layer, &metadata, nil
+}
+
+// getExportedObjectMeta returns the metadata exported for an object
+func getExportedObjectMeta(obj client.Object) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:   obj.GetNamespace(),
+		Name:        obj.GetName(),
+		Generation:  obj.GetGeneration(),
+		Labels:      obj.GetLabels(),
+		Annotations: obj.GetAnnotations(),
+	}
+}
+
+// PullConfigurationGroups fetches the artifact pushed by PushConfigurationGroups and returns the
+// ConfigurationGroups it contains, along with their content, exactly as FetchConfigurationGroups does
+// for ConfigurationGroups read from the management cluster.
+// tag can also be the digest returned by PushConfigurationGroups, to pin the artifact. Manifest is
+// verified against that digest or, when tag is not a digest, against the digest returned by the registry.
+// Every layer is verified against its digest and, once decrypted (see WithDecryptionKey), against
+// the ConfigurationBundle hash recorded in the ConfigurationGroup. Use WithVerificationKey to also
+// verify ConfigurationGroup signatures.
+// This method is made available to the agent running in the managed cluster.
+func PullConfigurationGroups(ctx context.Context, repository *OCIRepository, tag string,
+	logger logr.Logger, setters ...AgentOption) ([]ConfigurationGroupContent, error) {
+
+	options := getAgentOptions(setters...)
+
+	data, err := repository.fetchManifest(ctx, tag)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to fetch manifest: %v", err))
+		return nil, err
+	}
+
+	manifest := &ociManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.ArtifactType != ociArtifactType || manifest.Config.MediaType != ociConfigMediaType {
+		return nil, fmt.Errorf("%s/%s:%s is not a pull-mode artifact", repository.Host, repository.Repository, tag)
+	}
+
+	configBlob, err := repository.fetchBlob(ctx, manifest.Config.Digest)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to fetch config: %v", err))
+		return nil, err
+	}
+	config := &ociConfig{}
+	if err := json.Unmarshal(configBlob, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	bundles := make(map[string]*libsveltosv1beta1.ConfigurationBundle, len(config.ConfigurationBundles))
+	for i := range config.ConfigurationBundles {
+		bundle := &config.ConfigurationBundles[i]
+		bundles[fmt.Sprintf("%s/%s", bundle.Namespace, bundle.Name)] = bundle
+	}
+
+	for i := range manifest.Layers {
+		layer := &manifest.Layers[i]
+		bundle, ok := bundles[layer.Annotations[ociBundleAnnotation]]
+		if !ok {
+			continue
+		}
+
+		blob, err := repository.fetchBlob(ctx, layer.Digest)
+		if err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to fetch layer %s: %v", layer.Digest, err))
+			return nil, err
+		}
+		bundle.Spec.Resources, err = getBundleResourcesFromBlob(blob)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", layer.Digest, err)
+		}
+	}
+
+	// Same as FetchConfigurationGroups, with no audience only ConfigurationGroups not addressed
+	// to any audience are considered
+	confGroups := make([]libsveltosv1beta1.ConfigurationGroup, 0, len(config.ConfigurationGroups))
+	for i := range config.ConfigurationGroups {
+		if GetAudience(&config.ConfigurationGroups[i]) == options.Audience {
+			confGroups = append(confGroups, config.ConfigurationGroups[i])
+		}
+	}
+
+	getBundle := func(ctx context.Context, ref *corev1.ObjectReference) (*libsveltosv1beta1.ConfigurationBundle, error) {
+		bundle, ok := bundles[fmt.Sprintf("%s/%s", ref.Namespace, ref.Name)]
+		if !ok {
+			return nil, fmt.Errorf("ConfigurationBundle %s/%s not found", ref.Namespace, ref.Name)
+		}
+		return bundle.DeepCopy(), nil
+	}
+
+	return getConfigurationGroupsContent(ctx, confGroups, getBundle, options, logger)
+}
+
+func (r *OCIRepository) getURL(path string) string {
+	scheme := "https"
+	if r.PlainHTTP {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, r.Host, r.Repository, path)
+}
+
+func (r *OCIRepository) do(ctx context.Context, method, target string, body []byte,
+	headers map[string]string) (*http.Response, error) {
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// getResponseError returns an error describing an unexpected registry response
+func getResponseError(resp *http.Response, operation string) error {
+	const maxMessageSize = 1024
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize))
+	return fmt.Errorf("%s: unexpected status %d: %s", operation, resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+// pushBlob uploads a blob, unless the registry already has it
+func (r *OCIRepository) pushBlob(ctx context.Context, digest string, data []byte) error {
+	resp, err := r.do(ctx, http.MethodHead, r.getURL("blobs/"+digest), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = r.do(ctx, http.MethodPost, r.getURL("blobs/uploads/"), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return getResponseError(resp, "start blob upload")
+	}
+
+	// Location might be relative to the registry
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	uploadResp, err := r.do(ctx, http.MethodPut, location.String(), data,
+		map[string]string{"Content-Type": "application/octet-stream"})
+	if err != nil {
+		return err
+	}
+	defer uploadResp.Body.Close()
+	if uploadResp.StatusCode != http.StatusCreated {
+		return getResponseError(uploadResp, "upload blob")
+	}
+
+	return nil
+}
+
+// fetchBlob downloads a blob and verifies its digest
+func (r *OCIRepository) fetchBlob(ctx context.Context, digest string) ([]byte, error) {
+	resp, err := r.do(ctx, http.MethodGet, r.getURL("blobs/"+digest), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, getResponseError(resp, "fetch blob")
+	}
+
+	data, err := readOCIContent(resp.Body, maxOCIBlobSize)
+	if err != nil {
+		return nil, fmt.Errorf("blob %s: %w", digest, err)
+	}
+
+	if getOCIDigest(data) != digest {
+		return nil, fmt.Errorf("blob %s: digest mismatch", digest)
+	}
+
+	return data, nil
+}
+
+func (r *OCIRepository) pushManifest(ctx context.Context, tag string, manifest []byte) error {
+	resp, err := r.do(ctx, http.MethodPut, r.getURL("manifests/"+url.PathEscape(tag)), manifest,
+		map[string]string{"Content-Type": ociManifestMediaType})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return getResponseError(resp, "push manifest")
+	}
+	return nil
+}
+
+func (r *OCIRepository) fetchManifest(ctx context.Context, tag string) ([]byte, error) {
+	resp, err := r.do(ctx, http.MethodGet, r.getURL("manifests/"+url.PathEscape(tag)), nil,
+		map[string]string{"Accept": ociManifestMediaType})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, getResponseError(resp, "fetch manifest")
+	}
+
+	data, err := readOCIContent(resp.Body, maxOCIBlobSize)
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", tag, err)
+	}
+
+	// A tag can be moved, a digest cannot. When pulling by tag, rely on the digest the registry returns.
+	digest := tag
+	if !strings.HasPrefix(tag, "sha256:") {
+		digest = resp.Header.Get(ociContentDigestHeader)
+		if digest == "" {
+			return nil, fmt.Errorf("manifest %s: registry returned no %s", tag, ociContentDigestHeader)
+		}
+	}
+	if getOCIDigest(data) != digest {
+		return nil, fmt.Errorf("manifest %s: digest mismatch", tag)
+	}
+
+	return data, nil
+}
+
+// readOCIContent reads content returned by the registry, failing if it is larger than limit
+func readOCIContent(reader io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("content exceeds maximum size of %d bytes", limit)
+	}
+	return data, nil
+}
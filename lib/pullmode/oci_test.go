@@ -0,0 +1,367 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+// registry is a minimal in-memory OCI registry implementing the subset of the distribution
+// API used by PushConfigurationGroups/PullConfigurationGroups
+type registry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	// digests contains the digest of the manifests when they were pushed
+	digests map[string]string
+	uploads int
+}
+
+func newRegistry() *registry {
+	return &registry{blobs: map[string][]byte{}, manifests: map[string][]byte{}, digests: map[string]string{}}
+}
+
+func (r *registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/blobs/uploads/"):
+		repository := path[:strings.Index(path, "/blobs/uploads/")]
+		switch req.Method {
+		case http.MethodPost:
+			r.uploads++
+			w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%d", repository, r.uploads))
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			hash := sha256.Sum256(data)
+			digest := "sha256:" + hex.EncodeToString(hash[:])
+			if digest != req.URL.Query().Get("digest") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			r.blobs[digest] = data
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	case strings.Contains(path, "/blobs/"):
+		data, ok := r.blobs[path[strings.Index(path, "/blobs/")+len("/blobs/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case strings.Contains(path, "/manifests/"):
+		switch req.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			hash := sha256.Sum256(data)
+			digest := "sha256:" + hex.EncodeToString(hash[:])
+			// Manifest can be fetched by tag or by digest
+			byDigest := path[:strings.Index(path, "/manifests/")+len("/manifests/")] + digest
+			r.manifests[path], r.manifests[byDigest] = data, data
+			r.digests[path], r.digests[byDigest] = digest, digest
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			data, ok := r.manifests[path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", r.digests[path])
+			_, _ = w.Write(data)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("OCI transport", func() {
+	var logger logr.Logger
+	var c client.Client
+	var server *httptest.Server
+	var reg *registry
+	var repository *pullmode.OCIRepository
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+		c = k8sClient
+
+		reg = newRegistry()
+		server = httptest.NewServer(reg)
+		repository = &pullmode.OCIRepository{
+			Host:       strings.TrimPrefix(server.URL, "http://"),
+			Repository: "sveltos/" + randomString(),
+			PlainHTTP:  true,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("PullConfigurationGroups returns content pushed by PushConfigurationGroups", func() {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		index := randomString()
+
+		createNamespace(clusterNamespace)
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			randomString(), randomString(), randomString(),
+			map[string][]unstructured.Unstructured{index: getResources()}, logger,
+			pullmode.WithBundleCompression(), pullmode.WithSigningKey(privateKey))).To(Succeed())
+
+		digest, err := pullmode.PushConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, "",
+			repository, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(digest).To(HavePrefix("sha256:"))
+
+		// Bundle content is stored as is
+		groups := &libsveltosv1beta1.ConfigurationGroupList{}
+		Expect(c.List(context.TODO(), groups, client.InNamespace(clusterNamespace))).To(Succeed())
+		Expect(len(groups.Items)).To(Equal(1))
+		layerDigest := getLayerDigest(c, clusterNamespace)
+		Expect(reg.blobs).To(HaveKey(layerDigest))
+
+		contents, err := pullmode.PullConfigurationGroups(context.TODO(), repository, clusterName, logger,
+			pullmode.WithVerificationKey(publicKey))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).To(BeNil())
+		Expect(contents[0].ConfigurationGroup.Name).To(Equal(groups.Items[0].Name))
+		Expect(len(contents[0].Items)).To(Equal(1))
+		Expect(contents[0].Items[0].Index).To(Equal(index))
+		Expect(len(contents[0].Items[0].Objects)).To(Equal(len(getResources())))
+
+		// Layer records the content hash the agent verifies content against
+		manifest := getManifest(reg, repository, clusterName)
+		Expect(len(manifest.Layers)).To(Equal(1))
+		Expect(manifest.Layers[0].Annotations).To(HaveKeyWithValue("io.projectsveltos.pullmode.hash",
+			hex.EncodeToString(groups.Items[0].Spec.ConfigurationItems[0].Hash)))
+
+		// Artifact can be pinned by digest
+		contents, err = pullmode.PullConfigurationGroups(context.TODO(), repository, digest, logger,
+			pullmode.WithVerificationKey(publicKey))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).To(BeNil())
+
+		// Tampered content is detected
+		reg.blobs[layerDigest] = []byte(`["{\"apiVersion\":\"v1\",\"kind\":\"Namespace\"}"]`)
+		_, err = pullmode.PullConfigurationGroups(context.TODO(), repository, clusterName, logger)
+		Expect(err).ToNot(BeNil())
+
+		_, err = pullmode.PullConfigurationGroups(context.TODO(), repository, randomString(), logger)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("PullConfigurationGroups verifies the manifest digest", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+
+		createNamespace(clusterNamespace)
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			randomString(), randomString(), randomString(),
+			map[string][]unstructured.Unstructured{randomString(): getResources()}, logger)).To(Succeed())
+
+		digest, err := pullmode.PushConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, "",
+			repository, clusterName, logger)
+		Expect(err).To(BeNil())
+
+		// Tamper with the manifest, both by tag and by digest
+		for _, reference := range []string{clusterName, digest} {
+			manifest := getManifest(reg, repository, reference)
+			manifest.Layers = nil
+			data, err := json.Marshal(manifest)
+			Expect(err).To(BeNil())
+			reg.manifests[repository.Repository+"/manifests/"+reference] = data
+		}
+
+		_, err = pullmode.PullConfigurationGroups(context.TODO(), repository, clusterName, logger)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("digest mismatch"))
+
+		_, err = pullmode.PullConfigurationGroups(context.TODO(), repository, digest, logger)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("digest mismatch"))
+	})
+
+	It("readOCIContent fails on content exceeding the maximum size", func() {
+		data, err := pullmode.ReadOCIContent(strings.NewReader("0123456789"), 10)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("0123456789"))
+
+		_, err = pullmode.ReadOCIContent(strings.NewReader("0123456789"), 9)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("exceeds maximum size"))
+	})
+
+	It("PushConfigurationGroups exports encrypted Secrets encrypted", func() {
+		privateKey, publicKey, err := pullmode.GenerateEncryptionKey()
+		Expect(err).To(BeNil())
+
+		clusterNamespace := randomString()
+		clusterName := randomString()
+
+		createNamespace(clusterNamespace)
+
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      clusterName,
+			},
+		}
+		Expect(c.Create(context.TODO(), sveltosCluster)).To(Succeed())
+
+		Expect(pullmode.RegisterEncryptionKey(context.TODO(), c, clusterNamespace, clusterName,
+			publicKey)).To(Succeed())
+
+		Eventually(func() bool {
+			currentCluster := &libsveltosv1beta1.SveltosCluster{}
+			err := c.Get(context.TODO(),
+				types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}, currentCluster)
+			return err == nil && len(currentCluster.Status.AgentEncryptionKey) != 0
+		}, time.Minute, time.Second).Should(BeTrue())
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			randomString(), randomString(), randomString(),
+			map[string][]unstructured.Unstructured{randomString(): getResourcesWithSecret()}, logger,
+			pullmode.WithBundleSecretEncryption())).To(Succeed())
+
+		_, err = pullmode.PushConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, "",
+			repository, clusterName, logger)
+		Expect(err).To(BeNil())
+
+		for k := range reg.blobs {
+			Expect(string(reg.blobs[k])).ToNot(ContainSubstring("example-secret"))
+		}
+
+		// Agent cannot use content without the decryption key
+		contents, err := pullmode.PullConfigurationGroups(context.TODO(), repository, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).ToNot(BeNil())
+
+		contents, err = pullmode.PullConfigurationGroups(context.TODO(), repository, clusterName, logger,
+			pullmode.WithDecryptionKey(privateKey))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).To(BeNil())
+		Expect(len(contents[0].Items)).To(Equal(1))
+		Expect(len(contents[0].Items[0].Objects)).To(Equal(len(getResourcesWithSecret())))
+	})
+
+	It("PushConfigurationGroups only exports ConfigurationGroups addressed to the audience", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		audience := randomString()
+
+		createNamespace(clusterNamespace)
+
+		scopedName := randomString()
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			randomString(), scopedName, randomString(),
+			map[string][]unstructured.Unstructured{randomString(): getResources()}, logger,
+			pullmode.WithAudience(audience))).To(Succeed())
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			randomString(), randomString(), randomString(),
+			map[string][]unstructured.Unstructured{randomString(): getResourcesWithSecret()}, logger)).To(Succeed())
+
+		_, err := pullmode.PushConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, audience,
+			repository, audience, logger)
+		Expect(err).To(BeNil())
+		_, err = pullmode.PushConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, "",
+			repository, clusterName, logger)
+		Expect(err).To(BeNil())
+
+		// Audience artifact only contains the ConfigurationGroup addressed to the audience
+		contents, err := pullmode.PullConfigurationGroups(context.TODO(), repository, audience, logger,
+			pullmode.WithAgentAudience(audience))
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).To(BeNil())
+		Expect(len(contents[0].Items[0].Objects)).To(Equal(len(getResources())))
+
+		contents, err = pullmode.PullConfigurationGroups(context.TODO(), repository, audience, logger)
+		Expect(err).To(BeNil())
+		Expect(contents).To(BeEmpty())
+
+		contents, err = pullmode.PullConfigurationGroups(context.TODO(), repository, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(len(contents[0].Items[0].Objects)).To(Equal(len(getResourcesWithSecret())))
+	})
+})
+
+// getLayerDigest returns the digest of the layer storing the only ConfigurationBundle in namespace
+func getLayerDigest(c client.Client, namespace string) string {
+	bundles := &libsveltosv1beta1.ConfigurationBundleList{}
+	Expect(c.List(context.TODO(), bundles, client.InNamespace(namespace))).To(Succeed())
+	Expect(len(bundles.Items)).To(Equal(1))
+
+	blob, err := json.Marshal(bundles.Items[0].Spec.Resources)
+	Expect(err).To(BeNil())
+	hash := sha256.Sum256(blob)
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// getManifest returns the manifest stored in the registry for reference (tag or digest)
+func getManifest(reg *registry, repository *pullmode.OCIRepository, reference string) *pullmode.OCIManifest {
+	data, ok := reg.manifests[repository.Repository+"/manifests/"+reference]
+	Expect(ok).To(BeTrue())
+	manifest := &pullmode.OCIManifest{}
+	Expect(json.Unmarshal(data, manifest)).To(Succeed())
+	return manifest
+}
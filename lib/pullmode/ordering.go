@@ -0,0 +1,240 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// DependencyCycleError represents an error when ConfigurationGroups depend on each other, directly
+// or indirectly, so no valid processing order exists for them.
+type DependencyCycleError struct {
+	Message string
+	// Groups contains the namespace/name of the ConfigurationGroups which cannot be ordered
+	Groups []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return e.Message
+}
+
+// NewDependencyCycleError creates a new DependencyCycleError
+func NewDependencyCycleError(groups []string) *DependencyCycleError {
+	return &DependencyCycleError{
+		Message: fmt.Sprintf("dependency cycle between ConfigurationGroups: %s", strings.Join(groups, ", ")),
+		Groups:  groups,
+	}
+}
+
+// IsDependencyCycleError checks if an error is a DependencyCycleError
+func IsDependencyCycleError(err error) bool {
+	var cycleErr *DependencyCycleError
+	return errors.As(err, &cycleErr)
+}
+
+// DependencyNotReadyError represents an error when ConfigurationGroups depend, directly or indirectly,
+// on ConfigurationGroups which are missing or not ready to be processed. Such ConfigurationGroups are
+// held back until their dependencies are available.
+type DependencyNotReadyError struct {
+	Message string
+	// Groups contains the namespace/name of the ConfigurationGroups held back
+	Groups []string
+}
+
+func (e *DependencyNotReadyError) Error() string {
+	return e.Message
+}
+
+// NewDependencyNotReadyError creates a new DependencyNotReadyError
+func NewDependencyNotReadyError(groups []string) *DependencyNotReadyError {
+	return &DependencyNotReadyError{
+		Message: fmt.Sprintf("ConfigurationGroups waiting for dependencies: %s", strings.Join(groups, ", ")),
+		Groups:  groups,
+	}
+}
+
+// IsDependencyNotReadyError checks if an error is a DependencyNotReadyError
+func IsDependencyNotReadyError(err error) bool {
+	var notReadyErr *DependencyNotReadyError
+	return errors.As(err, &notReadyErr)
+}
+
+// getRequestorKey returns a key identifying a requestor for a given cluster
+func getRequestorKey(clusterNamespace, clusterName string, requestor *libsveltosv1beta1.RequestorReference) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", clusterNamespace, clusterName, requestor.RequestorKind,
+		requestor.RequestorName, requestor.RequestorFeature)
+}
+
+func getConfigurationGroupRequestorKey(confGroup *libsveltosv1beta1.ConfigurationGroup) string {
+	return getRequestorKey(confGroup.Namespace, confGroup.Labels[clusterNameLabelKey],
+		&libsveltosv1beta1.RequestorReference{
+			RequestorKind:    confGroup.Labels[requestorKindLabelKey],
+			RequestorName:    confGroup.Annotations[requestorNameAnnotationKey],
+			RequestorFeature: confGroup.Labels[requestorFeatureLabelKey],
+		})
+}
+
+// SortConfigurationGroups returns the ConfigurationGroups sorted so that each ConfigurationGroup comes
+// after all the ConfigurationGroups it depends on (see WithDependsOn). ConfigurationGroups with no
+// dependency between them are sorted by namespace and name, so result is deterministic.
+// ConfigurationGroups marked for removal come first, in reverse dependency order: a ConfigurationGroup
+// is removed before the ConfigurationGroups it depends on (for instance CRs before their CRD).
+// Dependencies on ConfigurationGroups not being removed are ignored for those.
+// ConfigurationGroups to deploy depending, directly or indirectly, on ConfigurationGroups not in the list
+// are held back: they are not returned and a DependencyNotReadyError listing them is returned.
+// If some ConfigurationGroups depend on each other, they are not returned either and a DependencyCycleError
+// listing them is returned. When both happen, returned error wraps both.
+// This method is made available to the agent running in the managed cluster.
+func SortConfigurationGroups(groups []libsveltosv1beta1.ConfigurationGroup,
+) ([]libsveltosv1beta1.ConfigurationGroup, error) {
+
+	removals := make([]libsveltosv1beta1.ConfigurationGroup, 0)
+	deployments := make([]libsveltosv1beta1.ConfigurationGroup, 0, len(groups))
+	for i := range groups {
+		if groups[i].Spec.Action == libsveltosv1beta1.ActionRemove {
+			removals = append(removals, groups[i])
+		} else {
+			deployments = append(deployments, groups[i])
+		}
+	}
+
+	sortedRemovals, removalCycle, _ := sortByDependencies(removals, false)
+	slices.Reverse(sortedRemovals)
+
+	sortedDeployments, cycle, waiting := sortByDependencies(deployments, true)
+	cycle = append(removalCycle, cycle...)
+
+	var errs []error
+	if len(cycle) > 0 {
+		errs = append(errs, NewDependencyCycleError(cycle))
+	}
+	if len(waiting) > 0 {
+		errs = append(errs, NewDependencyNotReadyError(waiting))
+	}
+
+	return append(sortedRemovals, sortedDeployments...), errors.Join(errs...)
+}
+
+// sortByDependencies sorts groups so that each ConfigurationGroup comes after all the ConfigurationGroups
+// it depends on. It returns the sorted ConfigurationGroups along with the names of the ones left out
+// because part of a dependency cycle and, if holdBackMissing is set, because depending, directly or
+// indirectly, on a ConfigurationGroup not in groups. When holdBackMissing is not set, dependencies on
+// ConfigurationGroups not in groups are ignored.
+func sortByDependencies(groups []libsveltosv1beta1.ConfigurationGroup, holdBackMissing bool,
+) (result []libsveltosv1beta1.ConfigurationGroup, cycle, waiting []string) {
+
+	sorted := make([]libsveltosv1beta1.ConfigurationGroup, len(groups))
+	copy(sorted, groups)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	indexes := make(map[string]int, len(sorted))
+	for i := range sorted {
+		indexes[getConfigurationGroupRequestorKey(&sorted[i])] = i
+	}
+
+	// dependents[i] lists the ConfigurationGroups depending on ConfigurationGroup i
+	dependents := make([][]int, len(sorted))
+	pending := make([]int, len(sorted))
+	// notReady[i] is set when ConfigurationGroup i depends, directly or indirectly, on a missing one
+	notReady := make([]bool, len(sorted))
+	for i := range sorted {
+		for j := range sorted[i].Spec.DependsOn {
+			key := getRequestorKey(sorted[i].Namespace, sorted[i].Labels[clusterNameLabelKey],
+				&sorted[i].Spec.DependsOn[j])
+			dependency, ok := indexes[key]
+			if !ok {
+				if holdBackMissing {
+					// Dependency never gets processed, so this ConfigurationGroup is held back
+					notReady[i] = true
+					pending[i]++
+				}
+				continue
+			}
+			dependents[dependency] = append(dependents[dependency], i)
+			pending[i]++
+		}
+	}
+
+	result = make([]libsveltosv1beta1.ConfigurationGroup, 0, len(sorted))
+	processed := make([]bool, len(sorted))
+	for {
+		// Always pick the first ConfigurationGroup with no pending dependency
+		next := -1
+		for i := range sorted {
+			if !processed[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			break
+		}
+
+		processed[next] = true
+		result = append(result, sorted[next])
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+
+	if len(result) == len(sorted) {
+		return result, nil, nil
+	}
+
+	// Mark ConfigurationGroups depending, even indirectly, on missing ones
+	queue := make([]int, 0)
+	for i := range sorted {
+		if notReady[i] {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[current] {
+			if !notReady[dependent] {
+				notReady[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	for i := range sorted {
+		if processed[i] {
+			continue
+		}
+		name := fmt.Sprintf("%s/%s", sorted[i].Namespace, sorted[i].Name)
+		if notReady[i] {
+			waiting = append(waiting, name)
+		} else {
+			cycle = append(cycle, name)
+		}
+	}
+
+	return result, cycle, waiting
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+var _ = Describe("ConfigurationGroup ordering", func() {
+	var namespace, clusterName, requestorKind string
+
+	BeforeEach(func() {
+		namespace = randomString()
+		clusterName = randomString()
+		requestorKind = randomString()
+	})
+
+	getGroup := func(name, requestorName string, dependsOn ...string) libsveltosv1beta1.ConfigurationGroup {
+		group := libsveltosv1beta1.ConfigurationGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        name,
+				Labels:      pullmode.GetConfigurationGroupLabels(clusterName, requestorKind, "helm"),
+				Annotations: map[string]string{pullmode.RequestorNameAnnotationKey: requestorName},
+			},
+		}
+		for i := range dependsOn {
+			group.Spec.DependsOn = append(group.Spec.DependsOn, libsveltosv1beta1.RequestorReference{
+				RequestorKind:    requestorKind,
+				RequestorName:    dependsOn[i],
+				RequestorFeature: "helm",
+			})
+		}
+		return group
+	}
+
+	getNames := func(groups []libsveltosv1beta1.ConfigurationGroup) []string {
+		names := make([]string, len(groups))
+		for i := range groups {
+			names[i] = groups[i].Name
+		}
+		return names
+	}
+
+	It("SortConfigurationGroups places ConfigurationGroups after their dependencies", func() {
+		groups := []libsveltosv1beta1.ConfigurationGroup{
+			getGroup("a", "crs", "crds", "operator"),
+			getGroup("b", "operator", "crds"),
+			getGroup("c", "crds"),
+			getGroup("d", "independent"),
+		}
+
+		sorted, err := pullmode.SortConfigurationGroups(groups)
+		Expect(err).To(BeNil())
+		Expect(getNames(sorted)).To(Equal([]string{"c", "b", "a", "d"}))
+	})
+
+	It("SortConfigurationGroups holds back ConfigurationGroups whose dependencies are missing", func() {
+		groups := []libsveltosv1beta1.ConfigurationGroup{
+			getGroup("a", "crs", "operator"),
+			getGroup("b", "operator", randomString()),
+			getGroup("c", "crds"),
+		}
+
+		sorted, err := pullmode.SortConfigurationGroups(groups)
+		Expect(err).ToNot(BeNil())
+		Expect(pullmode.IsDependencyNotReadyError(err)).To(BeTrue())
+		Expect(pullmode.IsDependencyCycleError(err)).To(BeFalse())
+		Expect(getNames(sorted)).To(Equal([]string{"c"}))
+
+		var notReadyErr *pullmode.DependencyNotReadyError
+		Expect(errors.As(err, &notReadyErr)).To(BeTrue())
+		Expect(notReadyErr.Groups).To(Equal([]string{namespace + "/a", namespace + "/b"}))
+	})
+
+	It("SortConfigurationGroups flags dependency cycles", func() {
+		groups := []libsveltosv1beta1.ConfigurationGroup{
+			getGroup("a", "first", "second"),
+			getGroup("b", "second", "first"),
+			getGroup("c", "third"),
+			getGroup("d", "fourth", randomString()),
+		}
+
+		sorted, err := pullmode.SortConfigurationGroups(groups)
+		Expect(err).ToNot(BeNil())
+		Expect(pullmode.IsDependencyCycleError(err)).To(BeTrue())
+		Expect(pullmode.IsDependencyNotReadyError(err)).To(BeTrue())
+		Expect(getNames(sorted)).To(Equal([]string{"c"}))
+	})
+	It("SortConfigurationGroups removes ConfigurationGroups in reverse dependency order", func() {
+		toRemove := func(group libsveltosv1beta1.ConfigurationGroup) libsveltosv1beta1.ConfigurationGroup {
+			group.Spec.Action = libsveltosv1beta1.ActionRemove
+			return group
+		}
+
+		groups := []libsveltosv1beta1.ConfigurationGroup{
+			toRemove(getGroup("a", "crds")),
+			toRemove(getGroup("b", "operator", "crds")),
+			toRemove(getGroup("c", "crs", "crds", "operator")),
+			// Dependencies on ConfigurationGroups not being removed do not hold back removals
+			toRemove(getGroup("d", "addon", randomString())),
+			getGroup("e", "independent"),
+		}
+
+		sorted, err := pullmode.SortConfigurationGroups(groups)
+		Expect(err).To(BeNil())
+		Expect(getNames(sorted)).To(Equal([]string{"d", "c", "b", "a", "e"}))
+	})
+})
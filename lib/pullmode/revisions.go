@@ -0,0 +1,421 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// When a revision history limit is set (see WithRevisionHistoryLimit), every time new content is
+// recorded for a requestor, a copy of all referenced ConfigurationBundles is taken. Copies are immutable,
+// labelled with the revision number and annotated with revisionOfAnnotationKey (instead of
+// requestorNameAnnotationKey) so they are never mistaken for the live ConfigurationBundles.
+// ConfigurationGroup is annotated with the revision it currently deploys.
+const (
+	revisionLabelKey             = "pullmode.projectsveltos.io/revision"
+	revisionOfAnnotationKey      = "pullmode.projectsveltos.io/revisionof"
+	revisionOrderAnnotationKey   = "pullmode.projectsveltos.io/revisionorder"
+	currentRevisionAnnotationKey = "pullmode.projectsveltos.io/currentrevision"
+)
+
+// ConfigurationGroupRevision is a revision of the content recorded for a requestor
+type ConfigurationGroupRevision struct {
+	// Revision is the revision number. Revisions are numbered starting from 1.
+	Revision int64
+
+	// CreationTimestamp is the time the revision was recorded
+	CreationTimestamp metav1.Time
+
+	// ConfigurationItems references, in order, the ConfigurationBundles holding the revision content
+	ConfigurationItems []libsveltosv1beta1.ConfigurationItem
+
+	// Current is true if this is the revision the ConfigurationGroup currently deploys
+	Current bool
+}
+
+// getRevisionBundles returns all ConfigurationBundles storing revisions for a requestor, grouped by
+// revision and sorted by their order within the revision.
+func getRevisionBundles(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+) (map[int64][]libsveltosv1beta1.ConfigurationBundle, error) {
+
+	listOptions := []client.ListOption{
+		client.InNamespace(clusterNamespace),
+		getConfigurationBundleLabels(clusterName, requestorKind, requestorFeature),
+		client.HasLabels{revisionLabelKey},
+	}
+
+	bundles := &libsveltosv1beta1.ConfigurationBundleList{}
+	if err := c.List(ctx, bundles, listOptions...); err != nil {
+		return nil, err
+	}
+
+	revisions := make(map[int64][]libsveltosv1beta1.ConfigurationBundle)
+	for i := range bundles.Items {
+		bundle := &bundles.Items[i]
+		if bundle.Annotations[revisionOfAnnotationKey] != requestorName {
+			continue
+		}
+		revision, err := strconv.ParseInt(bundle.Labels[revisionLabelKey], 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions[revision] = append(revisions[revision], *bundle)
+	}
+
+	for revision := range revisions {
+		sort.SliceStable(revisions[revision], func(i, j int) bool {
+			return getRevisionOrder(&revisions[revision][i]) < getRevisionOrder(&revisions[revision][j])
+		})
+	}
+
+	return revisions, nil
+}
+
+func getRevisionOrder(bundle *libsveltosv1beta1.ConfigurationBundle) int {
+	order, err := strconv.Atoi(bundle.Annotations[revisionOrderAnnotationKey])
+	if err != nil {
+		return 0
+	}
+	return order
+}
+
+// getSortedRevisions returns revision numbers, most recent first
+func getSortedRevisions(revisions map[int64][]libsveltosv1beta1.ConfigurationBundle) []int64 {
+	sorted := make([]int64, 0, len(revisions))
+	for revision := range revisions {
+		sorted = append(sorted, revision)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+	return sorted
+}
+
+// isSameContent returns true if the revision stores the same content as the ConfigurationBundles.
+// Content is matched by index and part, so the order ConfigurationBundles are listed in does not matter.
+func isSameContent(revisionBundles []libsveltosv1beta1.ConfigurationBundle, bundles []bundleData) bool {
+	if len(revisionBundles) != len(bundles) {
+		return false
+	}
+
+	hashes := make(map[string][]byte, len(revisionBundles))
+	for i := range revisionBundles {
+		key := getContentKey(revisionBundles[i].Annotations[indexAnnotationKey],
+			revisionBundles[i].Annotations[partAnnotationKey])
+		hashes[key] = revisionBundles[i].Status.Hash
+	}
+
+	for i := range bundles {
+		hash, ok := hashes[getContentKey(bundles[i].Index, bundles[i].Part)]
+		if !ok || !bytes.Equal(hash, bundles[i].Hash) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getContentKey returns the key identifying the content stored in a ConfigurationBundle
+func getContentKey(index, part string) string {
+	return index + "/" + part
+}
+
+// getRevisionBundleName returns the name of the copy of a ConfigurationBundle for a given revision
+func getRevisionBundleName(name string, revision int64) string {
+	return fmt.Sprintf("%s-rev-%d", name, revision)
+}
+
+// createRevisionBundle stores a copy of the ConfigurationBundle for the given revision
+func createRevisionBundle(ctx context.Context, c client.Client, clusterNamespace, clusterName, requestorKind,
+	requestorName, requestorFeature string, revision int64, order int, data *bundleData,
+) error {
+
+	namespace := clusterNamespace
+	if data.Namespace != "" {
+		namespace = data.Namespace
+	}
+
+	bundle := &libsveltosv1beta1.ConfigurationBundle{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: data.Name}, bundle)
+	if err != nil {
+		return err
+	}
+
+	labels := getConfigurationBundleLabels(clusterName, requestorKind, requestorFeature)
+	labels[revisionLabelKey] = strconv.FormatInt(revision, 10)
+
+	annotations := map[string]string{
+		revisionOfAnnotationKey:    requestorName,
+		revisionOrderAnnotationKey: strconv.Itoa(order),
+	}
+	// Keep annotations agent uses to reassemble content
+	for _, key := range []string{indexAnnotationKey, partAnnotationKey, partsAnnotationKey, contentHashAnnotationKey} {
+		if v, ok := bundle.Annotations[key]; ok {
+			annotations[key] = v
+		}
+	}
+
+	revisionBundle := &libsveltosv1beta1.ConfigurationBundle{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   clusterNamespace,
+			Name:        getRevisionBundleName(data.Name, revision),
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: bundle.Spec,
+	}
+
+	if err := c.Create(ctx, revisionBundle); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		// A previous attempt created it
+		if err := c.Get(ctx, client.ObjectKeyFromObject(revisionBundle), revisionBundle); err != nil {
+			return err
+		}
+	}
+
+	revisionBundle.Status.Hash = data.Hash
+	return c.Status().Update(ctx, revisionBundle)
+}
+
+// recordRevision records the content referenced by the ConfigurationGroup as a new revision, unless it
+// matches the latest revision, and prunes revisions beyond limit.
+func recordRevision(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+	bundles []bundleData, limit int, logger logr.Logger) error {
+
+	revisions, err := getRevisionBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get revisions: %v", err))
+		return err
+	}
+
+	sorted := getSortedRevisions(revisions)
+
+	revision := int64(1)
+	if len(sorted) > 0 {
+		revision = sorted[0] + 1
+		if isSameContent(revisions[sorted[0]], bundles) {
+			revision = sorted[0]
+		}
+	}
+
+	if _, ok := revisions[revision]; !ok {
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("recording revision %d", revision))
+		for i := range bundles {
+			err = createRevisionBundle(ctx, c, clusterNamespace, clusterName, requestorKind, requestorName,
+				requestorFeature, revision, i, &bundles[i])
+			if err != nil {
+				logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to record revision %d: %v", revision, err))
+				return err
+			}
+		}
+		sorted = append([]int64{revision}, sorted...)
+	}
+
+	err = setCurrentRevision(ctx, c, clusterNamespace, clusterName, requestorKind, requestorName,
+		requestorFeature, revision)
+	if err != nil {
+		return err
+	}
+
+	// Prune oldest revisions
+	for i := limit; i < len(sorted); i++ {
+		for j := range revisions[sorted[i]] {
+			err = c.Delete(ctx, &revisions[sorted[i]][j])
+			if err != nil && !apierrors.IsNotFound(err) {
+				logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to prune revision %d: %v", sorted[i], err))
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setCurrentRevision annotates the ConfigurationGroup with the revision it currently deploys
+func setCurrentRevision(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string, revision int64) error {
+
+	labels := getConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+	_, currentCG, err := getConfigurationGroupName(ctx, c, clusterNamespace, requestorName, labels)
+	if err != nil {
+		return err
+	}
+	if currentCG == nil {
+		return nil
+	}
+
+	if currentCG.GetAnnotations()[currentRevisionAnnotationKey] == strconv.FormatInt(revision, 10) {
+		return nil
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, currentRevisionAnnotationKey,
+		strconv.FormatInt(revision, 10))
+	return c.Patch(ctx, currentCG, client.RawPatch(types.MergePatchType, []byte(patch)))
+}
+
+// deleteRevisions deletes all revisions recorded for a requestor
+func deleteRevisions(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string) error {
+
+	revisions, err := getRevisionBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		return err
+	}
+
+	for revision := range revisions {
+		for i := range revisions[revision] {
+			err = c.Delete(ctx, &revisions[revision][i])
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListRevisions returns the revisions recorded for a requestor, oldest first. Revisions are recorded
+// only when a revision history limit is set (see WithRevisionHistoryLimit).
+// - requestorKind, requestorName, and requestorFeature uniquely identify the component in the
+// management cluster invoking this method.
+// - clusterNamespace/clusterName identify the target SveltosCluster.
+func ListRevisions(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+	logger logr.Logger) ([]ConfigurationGroupRevision, error) {
+
+	revisions, err := getRevisionBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get revisions: %v", err))
+		return nil, err
+	}
+
+	labels := getConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+	_, currentCG, err := getConfigurationGroupName(ctx, c, clusterNamespace, requestorName, labels)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get ConfigurationGroup: %v", err))
+		return nil, err
+	}
+	currentRevision := ""
+	if currentCG != nil {
+		currentRevision = currentCG.GetAnnotations()[currentRevisionAnnotationKey]
+	}
+
+	sorted := getSortedRevisions(revisions)
+	result := make([]ConfigurationGroupRevision, len(sorted))
+	for i := range sorted {
+		revision := sorted[len(sorted)-1-i]
+		bundles := revisions[revision]
+		result[i] = ConfigurationGroupRevision{
+			Revision:           revision,
+			CreationTimestamp:  bundles[0].CreationTimestamp,
+			ConfigurationItems: make([]libsveltosv1beta1.ConfigurationItem, len(bundles)),
+			Current:            currentRevision == strconv.FormatInt(revision, 10),
+		}
+		for j := range bundles {
+			result[i].ConfigurationItems[j] = libsveltosv1beta1.ConfigurationItem{
+				ContentRef: &corev1.ObjectReference{
+					APIVersion: libsveltosv1beta1.GroupVersion.String(),
+					Kind:       libsveltosv1beta1.ConfigurationBundleKind,
+					Namespace:  bundles[j].Namespace,
+					Name:       bundles[j].Name,
+				},
+				Hash: bundles[j].Status.Hash,
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// RollbackToRevision points the ConfigurationGroup of a requestor at the content of a previously
+// recorded revision (see ListRevisions). The agent in the managed cluster will re-apply that content.
+// setters are applied to the ConfigurationGroup as in RecordResourcesForDeployment.
+// The live ConfigurationBundles are deleted; next call to RecordResourcesForDeployment recreates them.
+// - requestorKind, requestorName, and requestorFeature uniquely identify the component in the
+// management cluster invoking this method.
+// - clusterNamespace/clusterName identify the target SveltosCluster.
+func RollbackToRevision(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+	revision int64, logger logr.Logger, setters ...Option) error {
+
+	revisions, err := getRevisionBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get revisions: %v", err))
+		return err
+	}
+
+	revisionBundles, ok := revisions[revision]
+	if !ok {
+		return fmt.Errorf("revision %d not found", revision)
+	}
+
+	currentBundles, err := getReferencedConfigurationBundles(ctx, c, clusterNamespace, clusterName,
+		requestorKind, requestorName, requestorFeature, logger)
+	if err != nil {
+		return err
+	}
+
+	bundles := make([]bundleData, len(revisionBundles))
+	for i := range revisionBundles {
+		bundles[i] = getBundleData(&revisionBundles[i], clusterNamespace)
+	}
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("rolling back to revision %d", revision))
+	err = reconcileConfigurationGroup(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature, bundles, logger, setters...)
+	if err != nil {
+		return err
+	}
+
+	err = setCurrentRevision(ctx, c, clusterNamespace, clusterName, requestorKind, requestorName,
+		requestorFeature, revision)
+	if err != nil {
+		return err
+	}
+
+	// Live ConfigurationBundles are not referenced anymore
+	err = deleteStaleConfigurationBundles(ctx, c, clusterNamespace, clusterName, requestorKind,
+		requestorName, requestorFeature, bundles, logger)
+	if err != nil {
+		return err
+	}
+
+	return releaseSharedConfigurationBundles(ctx, c, getSharedNamespaces("", currentBundles),
+		getRequestorKey(clusterNamespace, clusterName, &libsveltosv1beta1.RequestorReference{
+			RequestorKind: requestorKind, RequestorName: requestorName, RequestorFeature: requestorFeature}),
+		nil, logger)
+}
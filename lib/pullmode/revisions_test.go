@@ -0,0 +1,139 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+var _ = Describe("Revisions", func() {
+	var logger logr.Logger
+	var c client.Client
+	var clusterNamespace, clusterName string
+	var requestorKind, requestorName, requestorFeature string
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+
+		c = k8sClient
+
+		clusterNamespace = randomString()
+		clusterName = randomString()
+		requestorKind = randomString()
+		requestorName = randomString()
+		requestorFeature = randomString()
+
+		createNamespace(clusterNamespace)
+	})
+
+	record := func(resources []unstructured.Unstructured) {
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature,
+			map[string][]unstructured.Unstructured{"index": resources}, logger,
+			pullmode.WithRevisionHistoryLimit(2))).To(Succeed())
+	}
+
+	getResourcesVersion := func(version int) []unstructured.Unstructured {
+		resources := getResources()
+		resources[0].SetLabels(map[string]string{"version": fmt.Sprintf("%d", version)})
+		return resources
+	}
+
+	It("ListRevisions returns the last revisions and RollbackToRevision restores one of them", func() {
+		record(getResourcesVersion(1))
+		// Recording the same content does not create a new revision
+		record(getResourcesVersion(1))
+
+		revisions, err := pullmode.ListRevisions(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(err).To(BeNil())
+		Expect(len(revisions)).To(Equal(1))
+		Expect(revisions[0].Revision).To(Equal(int64(1)))
+		Expect(revisions[0].Current).To(BeTrue())
+
+		record(getResourcesVersion(2))
+		record(getResourcesVersion(3))
+
+		// Only last two revisions are kept
+		revisions, err = pullmode.ListRevisions(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(err).To(BeNil())
+		Expect(len(revisions)).To(Equal(2))
+		Expect(revisions[0].Revision).To(Equal(int64(2)))
+		Expect(revisions[0].Current).To(BeFalse())
+		Expect(revisions[1].Revision).To(Equal(int64(3)))
+		Expect(revisions[1].Current).To(BeTrue())
+
+		Expect(pullmode.RollbackToRevision(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, 1, logger)).ToNot(Succeed())
+		Expect(pullmode.RollbackToRevision(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, 2, logger)).To(Succeed())
+
+		contents, err := pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespace, clusterName, logger)
+		Expect(err).To(BeNil())
+		Expect(len(contents)).To(Equal(1))
+		Expect(contents[0].Err).To(BeNil())
+		Expect(contents[0].ConfigurationGroup.Spec.ConfigurationItems).To(Equal(revisions[0].ConfigurationItems))
+		Expect(len(contents[0].Items)).To(Equal(1))
+		Expect(contents[0].Items[0].Index).To(Equal("index"))
+		Expect(contents[0].Items[0].Objects[0].GetLabels()).To(HaveKeyWithValue("version", "2"))
+
+		revisions, err = pullmode.ListRevisions(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(err).To(BeNil())
+		Expect(revisions[0].Current).To(BeTrue())
+		Expect(revisions[1].Current).To(BeFalse())
+
+		// Revisions are removed when deployment tracking is terminated
+		Expect(pullmode.TerminateDeploymentTracking(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)).To(Succeed())
+		bundles := &libsveltosv1beta1.ConfigurationBundleList{}
+		Expect(c.List(context.TODO(), bundles, client.InNamespace(clusterNamespace))).To(Succeed())
+		Expect(bundles.Items).To(BeEmpty())
+	})
+	It("Recording same content with multiple keys does not create new revisions", func() {
+		resources := map[string][]unstructured.Unstructured{}
+		for i := 0; i < 5; i++ {
+			resources[randomString()] = getResourcesVersion(i)
+		}
+
+		for i := 0; i < 5; i++ {
+			Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+				requestorKind, requestorName, requestorFeature, resources, logger,
+				pullmode.WithRevisionHistoryLimit(2))).To(Succeed())
+		}
+
+		revisions, err := pullmode.ListRevisions(context.TODO(), c, clusterNamespace, clusterName,
+			requestorKind, requestorName, requestorFeature, logger)
+		Expect(err).To(BeNil())
+		Expect(len(revisions)).To(Equal(1))
+		Expect(revisions[0].Revision).To(Equal(int64(1)))
+		Expect(revisions[0].Current).To(BeTrue())
+	})
+})
@@ -17,6 +17,8 @@ limitations under the License.
 package pullmode
 
 import (
+	"crypto/ed25519"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -48,6 +50,13 @@ type Options struct {
 	Annotations            map[string]string
 	RequestorHash          []byte
 	ServiceAccount         types.NamespacedName
+	CompressBundles        bool
+	EncryptBundleSecrets   bool
+	SigningKey             ed25519.PrivateKey
+	DependsOn              []libsveltosv1beta1.RequestorReference
+	SharedBundlesNamespace string
+	RevisionHistoryLimit   int
+	Audience               string
 }
 
 type Option func(*Options)
@@ -192,6 +201,9 @@ func WithAnnotations(annotations map[string]string) Option {
 	}
 }
 
+// WithServiceAccount sets the ServiceAccount used to deploy the content in the managed cluster.
+// Unless WithAudience is also used, the ConfigurationGroup is addressed to the agent running with that
+// ServiceAccount (see GetServiceAccountAudience).
 func WithServiceAccount(namespace, name string) Option {
 	return func(args *Options) {
 		args.ServiceAccount = types.NamespacedName{
@@ -201,6 +213,94 @@ func WithServiceAccount(namespace, name string) Option {
 	}
 }
 
+// WithAudience addresses the ConfigurationGroup to the agents watching for the given audience
+// (see GetClusterAudienceLabels). Audience must be a valid label value.
+func WithAudience(audience string) Option {
+	return func(args *Options) {
+		args.Audience = audience
+	}
+}
+
+// WithBundleCompression stores the content of every ConfigurationBundle created by
+// RecordResourcesForDeployment gzip compressed and base64 encoded (see WithCompression).
+func WithBundleCompression() Option {
+	return func(args *Options) {
+		args.CompressBundles = true
+	}
+}
+
+// WithBundleSecretEncryption encrypts all Secrets stored in the ConfigurationBundles created by
+// RecordResourcesForDeployment (see WithSecretEncryption).
+func WithBundleSecretEncryption() Option {
+	return func(args *Options) {
+		args.EncryptBundleSecrets = true
+	}
+}
+
+// WithSigningKey signs the ConfigurationGroup (and, through their hashes, all referenced
+// ConfigurationBundles) with the provided Ed25519 key. Agent verifies content using
+// VerifyConfigurationGroup and the matching public key.
+func WithSigningKey(key ed25519.PrivateKey) Option {
+	return func(args *Options) {
+		args.SigningKey = key
+	}
+}
+
+// WithDependsOn records that content of the ConfigurationGroup must be processed only after content
+// of the ConfigurationGroups created, for the same cluster, by the given requestors.
+// Agent computes the processing order using SortConfigurationGroups.
+func WithDependsOn(dependencies []libsveltosv1beta1.RequestorReference) Option {
+	return func(args *Options) {
+		args.DependsOn = dependencies
+	}
+}
+
+// WithSharedBundles stores content in ConfigurationBundles shared across clusters, created in the
+// given namespace and named after their content. Clusters receiving identical content (for instance
+// because matching the same ClusterProfile) reference the same ConfigurationBundle instead of each
+// getting its own copy. Shared ConfigurationBundles track the requestors referencing them and are
+// deleted when the last one stops doing so.
+// Content containing Secrets to encrypt (see WithBundleSecretEncryption) is never shared.
+// Only content recorded with RecordResourcesForDeployment is shared. Content staged with
+// StageResourcesForDeployment is always stored in per-cluster ConfigurationBundles.
+// Agents must be allowed to read ConfigurationBundles in the given namespace.
+func WithSharedBundles(namespace string) Option {
+	return func(args *Options) {
+		args.SharedBundlesNamespace = namespace
+	}
+}
+
+// WithRevisionHistoryLimit keeps the last limit revisions of the content recorded for a requestor,
+// so that the ConfigurationGroup can later be rolled back (see ListRevisions and RollbackToRevision).
+func WithRevisionHistoryLimit(limit int) Option {
+	return func(args *Options) {
+		args.RevisionHistoryLimit = limit
+	}
+}
+
+func getOptions(setters ...Option) *Options {
+	c := &Options{}
+	for _, setter := range setters {
+		setter(c)
+	}
+	return c
+}
+
+// getBundleSetters returns the BundleOptions implied by the ConfigurationGroup options.
+func getBundleSetters(setters ...Option) []BundleOption {
+	c := getOptions(setters...)
+
+	bundleSetters := []BundleOption{}
+	if c.CompressBundles {
+		bundleSetters = append(bundleSetters, WithCompression())
+	}
+	if c.EncryptBundleSecrets {
+		bundleSetters = append(bundleSetters, WithSecretEncryption())
+	}
+
+	return bundleSetters
+}
+
 func applySetters(confGroup *libsveltosv1beta1.ConfigurationGroup, setters ...Option,
 ) *libsveltosv1beta1.ConfigurationGroup {
 
@@ -252,6 +352,8 @@ func applySetters(confGroup *libsveltosv1beta1.ConfigurationGroup, setters ...Op
 	confGroup.Spec.ServiceAccountNamespace = c.ServiceAccount.Namespace
 	confGroup.Spec.ServiceAccountName = c.ServiceAccount.Name
 
+	confGroup.Spec.DependsOn = c.DependsOn
+
 	return confGroup
 }
 
@@ -270,6 +372,24 @@ type BundleOptions struct {
 	ReferencedTier            int32
 	SkipNamespaceCreation     bool
 	Force                     bool
+	Compress                  bool
+	EncryptSecrets            bool
+
+	// set when content for an index is split across multiple ConfigurationBundles
+	part        int
+	parts       int
+	contentHash string
+
+	// public key registered by the agent, used to encrypt Secrets
+	encryptionKey []byte
+
+	// set when ConfigurationBundle is staged
+	stagingSession string
+	stagingOrder   int
+
+	// set when ConfigurationBundle is shared across clusters
+	sharedNamespace string
+	requestorKey    string
 }
 
 type BundleOption func(*BundleOptions)
@@ -307,6 +427,63 @@ func WithResourceInfo(kind, namespace, name string,
 	}
 }
 
+// WithCompression stores the ConfigurationBundle content gzip compressed and base64 encoded.
+// Use it for content that would otherwise exceed the maximum object size accepted by the API
+// server. Agent reads content using GetConfigurationBundleResources, which handles both encodings.
+func WithCompression() BundleOption {
+	return func(args *BundleOptions) {
+		args.Compress = true
+	}
+}
+
+// WithSecretEncryption encrypts every Secret stored in the ConfigurationBundle with the public key
+// registered by the agent in the target SveltosCluster (see RegisterEncryptionKey). Only the agent can
+// decrypt them (see DecryptConfigurationBundle). Recording resources fails if no key has been registered.
+func WithSecretEncryption() BundleOption {
+	return func(args *BundleOptions) {
+		args.EncryptSecrets = true
+	}
+}
+
+func withEncryptionKey(key []byte) BundleOption {
+	return func(args *BundleOptions) {
+		args.encryptionKey = key
+	}
+}
+
+// withStaging marks the ConfigurationBundle as staged within the given staging session
+func withStaging(session string, order int) BundleOption {
+	return func(args *BundleOptions) {
+		args.stagingSession = session
+		args.stagingOrder = order
+	}
+}
+
+// withSharing stores the ConfigurationBundle as a shared one in namespace, referenced by requestorKey
+func withSharing(namespace, requestorKey string) BundleOption {
+	return func(args *BundleOptions) {
+		args.sharedNamespace = namespace
+		args.requestorKey = requestorKey
+	}
+}
+
+// withPart marks the ConfigurationBundle as one of the parts content was split in
+func withPart(part, parts int, contentHash string) BundleOption {
+	return func(args *BundleOptions) {
+		args.part = part
+		args.parts = parts
+		args.contentHash = contentHash
+	}
+}
+
+func getBundleOptions(setters ...BundleOption) *BundleOptions {
+	c := &BundleOptions{}
+	for _, setter := range setters {
+		setter(c)
+	}
+	return c
+}
+
 func applyBundleSetters(confBundle *libsveltosv1beta1.ConfigurationBundle, setters ...BundleOption,
 ) *libsveltosv1beta1.ConfigurationBundle {
 
@@ -315,10 +492,7 @@ func applyBundleSetters(confBundle *libsveltosv1beta1.ConfigurationBundle, sette
 		return confBundle
 	}
 
-	c := &BundleOptions{}
-	for _, setter := range setters {
-		setter(c)
-	}
+	c := getBundleOptions(setters...)
 
 	confBundle.Spec.Timeout = c.Timeout
 	confBundle.Spec.HelmReleaseNamespace = c.ReleaseNamespace
@@ -336,5 +510,49 @@ func applyBundleSetters(confBundle *libsveltosv1beta1.ConfigurationBundle, sette
 	confBundle.Spec.SkipNamespaceCreation = c.SkipNamespaceCreation
 	confBundle.Spec.Force = c.Force
 
+	if c.Compress {
+		confBundle.Spec.Encoding = libsveltosv1beta1.BundleEncodingGzipBase64
+	}
+
 	return confBundle
 }
+
+type AgentOptions struct {
+	DecryptionKey   []byte
+	VerificationKey ed25519.PublicKey
+	Audience        string
+}
+
+type AgentOption func(*AgentOptions)
+
+// WithDecryptionKey sets the agent private key (see GenerateEncryptionKey) used to decrypt
+// the Secrets stored in ConfigurationBundles.
+func WithDecryptionKey(key []byte) AgentOption {
+	return func(args *AgentOptions) {
+		args.DecryptionKey = key
+	}
+}
+
+// WithVerificationKey requires every ConfigurationGroup to be signed with the private key
+// matching the provided Ed25519 public key (see WithSigningKey).
+func WithVerificationKey(key ed25519.PublicKey) AgentOption {
+	return func(args *AgentOptions) {
+		args.VerificationKey = key
+	}
+}
+
+// WithAgentAudience restricts the ConfigurationGroups fetched by the agent to the ones addressed
+// to the given audience (see WithAudience and WithServiceAccount).
+func WithAgentAudience(audience string) AgentOption {
+	return func(args *AgentOptions) {
+		args.Audience = audience
+	}
+}
+
+func getAgentOptions(setters ...AgentOption) *AgentOptions {
+	c := &AgentOptions{}
+	for _, setter := range setters {
+		setter(c)
+	}
+	return c
+}
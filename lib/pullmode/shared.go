@@ -0,0 +1,268 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Shared ConfigurationBundles store content once for all clusters receiving it (see WithSharedBundles).
+// They are named after their content and carry no cluster or requestor label. Status.ReferencedBy
+// lists the requestors (see getRequestorKey) whose ConfigurationGroups reference them.
+// Adding/removing a reference relies on optimistic concurrency, and a shared ConfigurationBundle is
+// deleted only if no reference was added since the last one was removed.
+const (
+	sharedBundleLabelKey = "pullmode.projectsveltos.io/shared"
+	sharedBundlePrefix   = "shared-"
+)
+
+// canShareContent returns true if content can be stored in a shared ConfigurationBundle.
+// Content with Secrets encrypted with a cluster specific key cannot.
+func canShareContent(resources []unstructured.Unstructured, setters ...BundleOption) bool {
+	if !getBundleOptions(setters...).EncryptSecrets {
+		return true
+	}
+
+	for i := range resources {
+		if isSecret(&resources[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// getSharedConfigurationBundleName returns the name of the shared ConfigurationBundle, which is
+// derived from its content: index, resources, encoding and, for content split in parts, the part
+// annotations. Index is considered since the agent reports it along with the content, so requestors
+// deploying identical content under different indexes do not share the same ConfigurationBundle.
+func getSharedConfigurationBundleName(bundle *libsveltosv1beta1.ConfigurationBundle) (string, error) {
+	hasher := sha256.New()
+
+	content, err := json.Marshal(struct {
+		Index     string                           `json:"index"`
+		Resources []string                         `json:"resources"`
+		Encoding  libsveltosv1beta1.BundleEncoding `json:"encoding"`
+		Part      string                           `json:"part,omitempty"`
+		Parts     string                           `json:"parts,omitempty"`
+		Hash      string                           `json:"hash,omitempty"`
+	}{
+		Index:     bundle.Annotations[indexAnnotationKey],
+		Resources: bundle.Spec.Resources,
+		Encoding:  bundle.Spec.Encoding,
+		Part:      bundle.Annotations[partAnnotationKey],
+		Parts:     bundle.Annotations[partsAnnotationKey],
+		Hash:      bundle.Annotations[contentHashAnnotationKey],
+	})
+	if err != nil {
+		return "", err
+	}
+	hasher.Write(content)
+
+	return sharedBundlePrefix + hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// reconcileSharedConfigurationBundle makes sure a shared ConfigurationBundle with given content exists
+// in namespace and that it is referenced by requestorKey.
+func reconcileSharedConfigurationBundle(ctx context.Context, c client.Client, namespace, requestorKey, index string,
+	resources []unstructured.Unstructured, logger logr.Logger, setters ...BundleOption,
+) (*libsveltosv1beta1.ConfigurationBundle, error) {
+
+	bundle, err := prepareConfigurationBundle(namespace, "", resources, setters...)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to prepare configurationBundle: %v", err))
+		return nil, err
+	}
+
+	bundle.Annotations = getConfigurationBundleAnnotations("", index, setters...)
+	delete(bundle.Annotations, requestorNameAnnotationKey)
+	bundle.Labels = map[string]string{sharedBundleLabelKey: "true"}
+	bundle.Name, err = getSharedConfigurationBundleName(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := getHash(resources)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to evaluate hash: %v", err))
+		return nil, err
+	}
+
+	currentBundle := &libsveltosv1beta1.ConfigurationBundle{}
+	err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: bundle.Name}, currentBundle)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("creating shared configurationBundle %s/%s",
+			namespace, bundle.Name))
+		err = c.Create(ctx, bundle)
+		switch {
+		case err == nil:
+			currentBundle = bundle
+		case apierrors.IsAlreadyExists(err):
+			// Created in the meantime for another requestor. Reference it.
+			err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: bundle.Name}, currentBundle)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+	}
+
+	// If ConfigurationBundle is marked for deletion, return an error. A new one will be created
+	// once it is gone.
+	if !currentBundle.GetDeletionTimestamp().IsZero() {
+		msgError := "shared ConfigurationBundle is currently existing but marked for deletion"
+		logger.V(logs.LogInfo).Info(msgError)
+		return nil, errors.New(msgError)
+	}
+
+	if slices.Contains(currentBundle.Status.ReferencedBy, requestorKey) &&
+		bytes.Equal(currentBundle.Status.Hash, hash) {
+
+		return currentBundle, nil
+	}
+
+	currentBundle.Status.Hash = hash
+	if !slices.Contains(currentBundle.Status.ReferencedBy, requestorKey) {
+		currentBundle.Status.ReferencedBy = append(currentBundle.Status.ReferencedBy, requestorKey)
+	}
+	err = c.Status().Update(ctx, currentBundle)
+	return currentBundle, err
+}
+
+// getSharedNamespaces returns the namespaces containing shared ConfigurationBundles which might be
+// referenced by a requestor: the namespace currently used for shared ConfigurationBundles, if any,
+// and the namespaces of shared ConfigurationBundles referenced so far.
+func getSharedNamespaces(sharedNamespace string, referencedBundles []bundleData) []string {
+	namespaces := make([]string, 0)
+	if sharedNamespace != "" {
+		namespaces = append(namespaces, sharedNamespace)
+	}
+
+	for i := range referencedBundles {
+		if referencedBundles[i].Namespace != "" && !slices.Contains(namespaces, referencedBundles[i].Namespace) {
+			namespaces = append(namespaces, referencedBundles[i].Namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// releaseSharedConfigurationBundles removes requestorKey from all shared ConfigurationBundles in
+// namespaces, but the ones in referencedBundles. Shared ConfigurationBundles not referenced anymore
+// are deleted. So are shared ConfigurationBundles referenced by no requestor at all, which are left
+// behind when a requestor fails between creating a shared ConfigurationBundle and referencing it.
+func releaseSharedConfigurationBundles(ctx context.Context, c client.Client, namespaces []string,
+	requestorKey string, referencedBundles []bundleData, logger logr.Logger) error {
+
+	referenced := make(map[types.NamespacedName]bool, len(referencedBundles))
+	for i := range referencedBundles {
+		referenced[types.NamespacedName{Namespace: referencedBundles[i].Namespace,
+			Name: referencedBundles[i].Name}] = true
+	}
+
+	for _, namespace := range namespaces {
+		bundles := &libsveltosv1beta1.ConfigurationBundleList{}
+		listOptions := []client.ListOption{
+			client.InNamespace(namespace),
+			client.MatchingLabels{sharedBundleLabelKey: "true"},
+		}
+		if err := c.List(ctx, bundles, listOptions...); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to list shared configurationBundles: %v", err))
+			return err
+		}
+
+		for i := range bundles.Items {
+			bundle := &bundles.Items[i]
+			if referenced[types.NamespacedName{Namespace: bundle.Namespace, Name: bundle.Name}] {
+				continue
+			}
+
+			if len(bundle.Status.ReferencedBy) == 0 {
+				if err := deleteSharedConfigurationBundle(ctx, c, bundle, logger); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if !slices.Contains(bundle.Status.ReferencedBy, requestorKey) {
+				continue
+			}
+
+			if err := releaseSharedConfigurationBundle(ctx, c, bundle, requestorKey, logger); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// releaseSharedConfigurationBundle removes requestorKey from the shared ConfigurationBundle references.
+// If no reference is left, the shared ConfigurationBundle is deleted.
+func releaseSharedConfigurationBundle(ctx context.Context, c client.Client,
+	bundle *libsveltosv1beta1.ConfigurationBundle, requestorKey string, logger logr.Logger) error {
+
+	bundle.Status.ReferencedBy = slices.DeleteFunc(slices.Clone(bundle.Status.ReferencedBy),
+		func(key string) bool { return key == requestorKey })
+	if err := c.Status().Update(ctx, bundle); err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to update shared configurationBundle: %v", err))
+		return err
+	}
+
+	if len(bundle.Status.ReferencedBy) > 0 {
+		return nil
+	}
+
+	return deleteSharedConfigurationBundle(ctx, c, bundle, logger)
+}
+
+// deleteSharedConfigurationBundle deletes a shared ConfigurationBundle not referenced anymore, only if
+// no reference was added in the meantime. A requestor creating the shared ConfigurationBundle right
+// before fails to reference it and creates it again.
+func deleteSharedConfigurationBundle(ctx context.Context, c client.Client,
+	bundle *libsveltosv1beta1.ConfigurationBundle, logger logr.Logger) error {
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("deleting shared configurationBundle %s/%s",
+		bundle.Namespace, bundle.Name))
+	resourceVersion := bundle.ResourceVersion
+	err := c.Delete(ctx, bundle, client.Preconditions{ResourceVersion: &resourceVersion})
+	if apierrors.IsConflict(err) {
+		// Referenced in the meantime
+		return nil
+	}
+	return client.IgnoreNotFound(err)
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+var _ = Describe("Shared ConfigurationBundles", func() {
+	var logger logr.Logger
+	var c client.Client
+	var sharedNamespace string
+
+	BeforeEach(func() {
+		logger = textlogger.NewLogger(textlogger.NewConfig())
+
+		c = k8sClient
+
+		sharedNamespace = randomString()
+		createNamespace(sharedNamespace)
+	})
+
+	getSharedBundles := func() []libsveltosv1beta1.ConfigurationBundle {
+		bundles := &libsveltosv1beta1.ConfigurationBundleList{}
+		Expect(c.List(context.TODO(), bundles, client.InNamespace(sharedNamespace))).To(Succeed())
+		return bundles.Items
+	}
+
+	It("identical content is stored once and deleted when no longer referenced", func() {
+		requestorKind := randomString()
+		requestorFeature := randomString()
+		index := randomString()
+		clusterNamespaces := []string{randomString(), randomString()}
+		clusterName := randomString()
+		requestorName := randomString()
+
+		for i := range clusterNamespaces {
+			createNamespace(clusterNamespaces[i])
+			Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespaces[i], clusterName,
+				requestorKind, requestorName, requestorFeature,
+				map[string][]unstructured.Unstructured{index: getResources()}, logger,
+				pullmode.WithSharedBundles(sharedNamespace))).To(Succeed())
+		}
+
+		bundles := getSharedBundles()
+		Expect(len(bundles)).To(Equal(1))
+		Expect(len(bundles[0].Status.ReferencedBy)).To(Equal(2))
+		Expect(bundles[0].Status.Hash).ToNot(BeEmpty())
+
+		for i := range clusterNamespaces {
+			// No per-cluster copy
+			perCluster := &libsveltosv1beta1.ConfigurationBundleList{}
+			Expect(c.List(context.TODO(), perCluster, client.InNamespace(clusterNamespaces[i]))).To(Succeed())
+			Expect(perCluster.Items).To(BeEmpty())
+
+			contents, err := pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespaces[i],
+				clusterName, logger)
+			Expect(err).To(BeNil())
+			Expect(len(contents)).To(Equal(1))
+			Expect(contents[0].Err).To(BeNil())
+			Expect(contents[0].ConfigurationGroup.Spec.ConfigurationItems[0].ContentRef.Namespace).To(
+				Equal(sharedNamespace))
+			Expect(len(contents[0].Items)).To(Equal(1))
+			Expect(contents[0].Items[0].Index).To(Equal(index))
+			Expect(len(contents[0].Items[0].Objects)).To(Equal(len(getResources())))
+		}
+
+		// First cluster gets different content: old shared ConfigurationBundle is still referenced by
+		// second cluster, a new one is created
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespaces[0], clusterName,
+			requestorKind, requestorName, requestorFeature,
+			map[string][]unstructured.Unstructured{index: getResources()[1:]}, logger,
+			pullmode.WithSharedBundles(sharedNamespace))).To(Succeed())
+
+		bundles = getSharedBundles()
+		Expect(len(bundles)).To(Equal(2))
+		for i := range bundles {
+			Expect(len(bundles[i].Status.ReferencedBy)).To(Equal(1))
+		}
+
+		// Last reference goes away
+		Expect(pullmode.TerminateDeploymentTracking(context.TODO(), c, clusterNamespaces[1], clusterName,
+			requestorKind, requestorName, requestorFeature, logger)).To(Succeed())
+		Expect(len(getSharedBundles())).To(Equal(1))
+
+		Expect(pullmode.RemoveDeployedResources(context.TODO(), c, clusterNamespaces[0], clusterName,
+			requestorKind, requestorName, requestorFeature, logger)).To(Succeed())
+		Expect(getSharedBundles()).To(BeEmpty())
+	})
+
+	It("identical content under different indexes is not shared", func() {
+		clusterName := randomString()
+		clusterNamespaces := []string{randomString(), randomString()}
+		indexes := []string{randomString(), randomString()}
+		for i := range clusterNamespaces {
+			createNamespace(clusterNamespaces[i])
+			Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespaces[i], clusterName,
+				randomString(), randomString(), randomString(),
+				map[string][]unstructured.Unstructured{indexes[i]: getResources()}, logger,
+				pullmode.WithSharedBundles(sharedNamespace))).To(Succeed())
+		}
+
+		bundles := getSharedBundles()
+		Expect(len(bundles)).To(Equal(2))
+		for i := range bundles {
+			Expect(len(bundles[i].Status.ReferencedBy)).To(Equal(1))
+		}
+
+		// Each cluster gets content with its own index
+		for i := range clusterNamespaces {
+			contents, err := pullmode.FetchConfigurationGroups(context.TODO(), c, clusterNamespaces[i],
+				clusterName, logger)
+			Expect(err).To(BeNil())
+			Expect(len(contents)).To(Equal(1))
+			Expect(contents[0].Err).To(BeNil())
+			Expect(len(contents[0].Items)).To(Equal(1))
+			Expect(contents[0].Items[0].Index).To(Equal(indexes[i]))
+		}
+	})
+
+	It("shared ConfigurationBundles referenced by no requestor are deleted", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		createNamespace(clusterNamespace)
+
+		// Left behind by a requestor which failed before referencing it
+		orphan := &libsveltosv1beta1.ConfigurationBundle{}
+		orphan.Namespace = sharedNamespace
+		orphan.Name = randomString()
+		orphan.Labels = map[string]string{pullmode.SharedBundleLabelKey: "true"}
+		Expect(c.Create(context.TODO(), orphan)).To(Succeed())
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			randomString(), randomString(), randomString(),
+			map[string][]unstructured.Unstructured{randomString(): getResources()}, logger,
+			pullmode.WithSharedBundles(sharedNamespace))).To(Succeed())
+
+		bundles := getSharedBundles()
+		Expect(len(bundles)).To(Equal(1))
+		Expect(bundles[0].Name).ToNot(Equal(orphan.Name))
+		Expect(len(bundles[0].Status.ReferencedBy)).To(Equal(1))
+	})
+
+	It("shared ConfigurationBundle created concurrently is referenced", func() {
+		clusterName := randomString()
+		clusterNamespaces := []string{randomString(), randomString()}
+		for i := range clusterNamespaces {
+			createNamespace(clusterNamespaces[i])
+		}
+		index := randomString()
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespaces[0], clusterName,
+			randomString(), randomString(), randomString(),
+			map[string][]unstructured.Unstructured{index: getResources()}, logger,
+			pullmode.WithSharedBundles(sharedNamespace))).To(Succeed())
+
+		// Shared ConfigurationBundle is not found at first, so creation fails with AlreadyExists
+		racingClient := &notFoundOnceClient{Client: c, namespace: sharedNamespace}
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), racingClient, clusterNamespaces[1], clusterName,
+			randomString(), randomString(), randomString(),
+			map[string][]unstructured.Unstructured{index: getResources()}, logger,
+			pullmode.WithSharedBundles(sharedNamespace))).To(Succeed())
+		Expect(racingClient.done).To(BeTrue())
+
+		bundles := getSharedBundles()
+		Expect(len(bundles)).To(Equal(1))
+		Expect(len(bundles[0].Status.ReferencedBy)).To(Equal(2))
+	})
+
+	It("content with Secrets to encrypt is not shared", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+
+		createNamespace(clusterNamespace)
+
+		_, publicKey, err := pullmode.GenerateEncryptionKey()
+		Expect(err).To(BeNil())
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{}
+		sveltosCluster.Namespace = clusterNamespace
+		sveltosCluster.Name = clusterName
+		Expect(c.Create(context.TODO(), sveltosCluster)).To(Succeed())
+		Expect(pullmode.RegisterEncryptionKey(context.TODO(), c, clusterNamespace, clusterName,
+			publicKey)).To(Succeed())
+
+		Expect(pullmode.RecordResourcesForDeployment(context.TODO(), c, clusterNamespace, clusterName,
+			randomString(), randomString(), randomString(),
+			map[string][]unstructured.Unstructured{
+				randomString(): getResourcesWithSecret(),
+				randomString(): getResources(),
+			}, logger, pullmode.WithSharedBundles(sharedNamespace),
+			pullmode.WithBundleSecretEncryption())).To(Succeed())
+
+		Expect(len(getSharedBundles())).To(Equal(1))
+
+		perCluster := &libsveltosv1beta1.ConfigurationBundleList{}
+		Expect(c.List(context.TODO(), perCluster, client.InNamespace(clusterNamespace))).To(Succeed())
+		Expect(len(perCluster.Items)).To(Equal(1))
+		Expect(perCluster.Items[0].Spec.EncryptedResources).ToNot(BeEmpty())
+	})
+})
+
+// notFoundOnceClient reports the first ConfigurationBundle fetched in namespace as not found
+type notFoundOnceClient struct {
+	client.Client
+	namespace string
+	done      bool
+}
+
+func (c *notFoundOnceClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object,
+	opts ...client.GetOption) error {
+
+	if _, ok := obj.(*libsveltosv1beta1.ConfigurationBundle); ok && key.Namespace == c.namespace && !c.done {
+		c.done = true
+		return apierrors.NewNotFound(libsveltosv1beta1.GroupVersion.WithResource("configurationbundles").GroupResource(),
+			key.Name)
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// SignatureVerificationError represents an error when a ConfigurationGroup signature, or the
+// content of one of the ConfigurationBundles it references, cannot be verified.
+type SignatureVerificationError struct {
+	Message string
+}
+
+func (e *SignatureVerificationError) Error() string {
+	return e.Message
+}
+
+// NewSignatureVerificationError creates a new SignatureVerificationError
+func NewSignatureVerificationError(msg string) *SignatureVerificationError {
+	return &SignatureVerificationError{
+		Message: msg,
+	}
+}
+
+// IsSignatureVerificationError checks if an error is a SignatureVerificationError
+func IsSignatureVerificationError(err error) bool {
+	var sigErr *SignatureVerificationError
+	return errors.As(err, &sigErr)
+}
+
+// signedConfigurationItem is the portion of a ConfigurationItem covered by the signature.
+type signedConfigurationItem struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Hash      []byte `json:"hash,omitempty"`
+}
+
+// signedConfigurationGroup is the canonical portion of a ConfigurationGroupSpec covered by the
+// signature. It only contains fields which drive what the agent deploys and how. Fields defaulted
+// by the API server (Tier, SourceStatus) and fields changing during the ConfigurationGroup
+// lifecycle (UpdatePhase) are intentionally excluded, so the payload is the same whether it is
+// evaluated before the ConfigurationGroup is created or after it is read back.
+type signedConfigurationGroup struct {
+	Remove                   bool                                   `json:"remove"`
+	ConfigurationItems       []signedConfigurationItem              `json:"configurationItems,omitempty"`
+	DependsOn                []libsveltosv1beta1.RequestorReference `json:"dependsOn,omitempty"`
+	RequestorHash            []byte                                 `json:"requestorHash,omitempty"`
+	DryRun                   bool                                   `json:"dryRun,omitempty"`
+	Reloader                 bool                                   `json:"reloader,omitempty"`
+	DriftDetection           bool                                   `json:"driftDetection,omitempty"`
+	DriftExclusions          []libsveltosv1beta1.DriftExclusion     `json:"driftExclusions,omitempty"`
+	ContinueOnConflict       bool                                   `json:"continueOnConflict,omitempty"`
+	ContinueOnError          bool                                   `json:"continueOnError,omitempty"`
+	MaxConsecutiveFailures   *uint                                  `json:"maxConsecutiveFailures,omitempty"`
+	LeavePolicies            bool                                   `json:"leavePolicies,omitempty"`
+	PreDeployChecks          []libsveltosv1beta1.ValidateHealth     `json:"preDeployChecks,omitempty"`
+	PreDeployCheckJobs       map[string]string                      `json:"preDeployCheckJobs,omitempty"`
+	ValidateHealths          []libsveltosv1beta1.ValidateHealth     `json:"validateHealths,omitempty"`
+	ValidateHealthJobs       map[string]string                      `json:"validateHealthJobs,omitempty"`
+	PreDeleteChecks          []libsveltosv1beta1.ValidateHealth     `json:"preDeleteChecks,omitempty"`
+	PreDeleteCheckJobs       map[string]string                      `json:"preDeleteCheckJobs,omitempty"`
+	PostDeleteChecks         []libsveltosv1beta1.ValidateHealth     `json:"postDeleteChecks,omitempty"`
+	PostDeleteCheckJobs      map[string]string                      `json:"postDeleteCheckJobs,omitempty"`
+	DeployedGroupVersionKind []string                               `json:"deployedGroupVersionKind,omitempty"`
+	ServiceAccountName       string                                 `json:"serviceAccountName,omitempty"`
+	ServiceAccountNamespace  string                                 `json:"serviceAccountNamespace,omitempty"`
+}
+
+// getSigningPayload returns the content covered by the ConfigurationGroup signature: the canonical
+// subset of the spec (see signedConfigurationGroup), which includes the ordered hashes of the
+// referenced ConfigurationBundles.
+func getSigningPayload(spec *libsveltosv1beta1.ConfigurationGroupSpec) ([]byte, error) {
+	signed := signedConfigurationGroup{
+		Remove:                   spec.Action == libsveltosv1beta1.ActionRemove,
+		DependsOn:                spec.DependsOn,
+		RequestorHash:            spec.RequestorHash,
+		DryRun:                   spec.DryRun,
+		Reloader:                 spec.Reloader,
+		DriftDetection:           spec.DriftDetection,
+		DriftExclusions:          spec.DriftExclusions,
+		ContinueOnConflict:       spec.ContinueOnConflict,
+		ContinueOnError:          spec.ContinueOnError,
+		MaxConsecutiveFailures:   spec.MaxConsecutiveFailures,
+		LeavePolicies:            spec.LeavePolicies,
+		PreDeployChecks:          spec.PreDeployChecks,
+		PreDeployCheckJobs:       spec.PreDeployCheckJobs,
+		ValidateHealths:          spec.ValidateHealths,
+		ValidateHealthJobs:       spec.ValidateHealthJobs,
+		PreDeleteChecks:          spec.PreDeleteChecks,
+		PreDeleteCheckJobs:       spec.PreDeleteCheckJobs,
+		PostDeleteChecks:         spec.PostDeleteChecks,
+		PostDeleteCheckJobs:      spec.PostDeleteCheckJobs,
+		DeployedGroupVersionKind: spec.DeployedGroupVersionKind,
+		ServiceAccountName:       spec.ServiceAccountName,
+		ServiceAccountNamespace:  spec.ServiceAccountNamespace,
+	}
+
+	signed.ConfigurationItems = make([]signedConfigurationItem, len(spec.ConfigurationItems))
+	for i := range spec.ConfigurationItems {
+		item := &spec.ConfigurationItems[i]
+		signed.ConfigurationItems[i].Hash = item.Hash
+		if item.ContentRef != nil {
+			signed.ConfigurationItems[i].Namespace = item.ContentRef.Namespace
+			signed.ConfigurationItems[i].Name = item.ContentRef.Name
+		}
+	}
+
+	return json.Marshal(signed)
+}
+
+// signConfigurationGroup sets the ConfigurationGroup signature using the provided key
+func signConfigurationGroup(confGroup *libsveltosv1beta1.ConfigurationGroup, key ed25519.PrivateKey) error {
+	if len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid ed25519 private key size %d", len(key))
+	}
+
+	payload, err := getSigningPayload(&confGroup.Spec)
+	if err != nil {
+		return err
+	}
+
+	confGroup.Spec.Signature = ed25519.Sign(key, payload)
+	return nil
+}
+
+// getBundleHash evaluates the hash of the resources stored in a ConfigurationBundle. Returned value
+// matches the hash evaluated when the ConfigurationBundle was created (see getHash).
+func getBundleHash(bundle *libsveltosv1beta1.ConfigurationBundle) ([]byte, error) {
+	content, err := GetConfigurationBundleResources(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]unstructured.Unstructured, len(content))
+	for i := range content {
+		if err := yaml.Unmarshal([]byte(content[i]), &resources[i].Object); err != nil {
+			return nil, fmt.Errorf("ConfigurationBundle %s/%s: failed to parse resource %d: %w",
+				bundle.Namespace, bundle.Name, i, err)
+		}
+	}
+
+	return getHash(resources)
+}
+
+// VerifyConfigurationGroup verifies the ConfigurationGroup was signed by the management cluster components
+// owning the private key matching publicKey, and that the content of each referenced ConfigurationBundle
+// matches the signed hash.
+// bundles must contain all ConfigurationBundles referenced by the ConfigurationGroup.
+// A SignatureVerificationError is returned if the ConfigurationGroup is not signed, signature is not valid
+// or content of any ConfigurationBundle was modified.
+// This method is made available to the agent running in the managed cluster, which is expected to call it
+// before deploying any content.
+func VerifyConfigurationGroup(confGroup *libsveltosv1beta1.ConfigurationGroup,
+	bundles []libsveltosv1beta1.ConfigurationBundle, publicKey ed25519.PublicKey) error {
+
+	if confGroup == nil {
+		return fmt.Errorf("nil ConfigurationGroup")
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid ed25519 public key size %d", len(publicKey))
+	}
+
+	if len(confGroup.Spec.Signature) == 0 {
+		return NewSignatureVerificationError(fmt.Sprintf("ConfigurationGroup %s/%s is not signed",
+			confGroup.Namespace, confGroup.Name))
+	}
+
+	payload, err := getSigningPayload(&confGroup.Spec)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, payload, confGroup.Spec.Signature) {
+		return NewSignatureVerificationError(fmt.Sprintf("ConfigurationGroup %s/%s: invalid signature",
+			confGroup.Namespace, confGroup.Name))
+	}
+
+	bundleMap := make(map[string]*libsveltosv1beta1.ConfigurationBundle, len(bundles))
+	for i := range bundles {
+		bundleMap[fmt.Sprintf("%s/%s", bundles[i].Namespace, bundles[i].Name)] = &bundles[i]
+	}
+
+	for i := range confGroup.Spec.ConfigurationItems {
+		item := &confGroup.Spec.ConfigurationItems[i]
+		if item.ContentRef == nil {
+			continue
+		}
+
+		bundle, ok := bundleMap[fmt.Sprintf("%s/%s", item.ContentRef.Namespace, item.ContentRef.Name)]
+		if !ok {
+			return fmt.Errorf("ConfigurationBundle %s/%s not found", item.ContentRef.Namespace,
+				item.ContentRef.Name)
+		}
+
+		hash, err := getBundleHash(bundle)
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(hash, item.Hash) {
+			return NewSignatureVerificationError(fmt.Sprintf("ConfigurationBundle %s/%s: content hash mismatch",
+				bundle.Namespace, bundle.Name))
+		}
+	}
+
+	return nil
+}
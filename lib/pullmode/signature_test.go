@@ -0,0 +1,127 @@
+/*
+Copyright 2026. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullmode_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/pullmode"
+)
+
+var _ = Describe("ConfigurationGroup signature", func() {
+	var publicKey ed25519.PublicKey
+	var privateKey ed25519.PrivateKey
+	var namespace string
+
+	BeforeEach(func() {
+		var err error
+		publicKey, privateKey, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+		namespace = randomString()
+	})
+
+	prepareSignedGroup := func(setters ...pullmode.Option) (*libsveltosv1beta1.ConfigurationGroup,
+		[]libsveltosv1beta1.ConfigurationBundle) {
+
+		resources := getResources()
+		bundle, err := pullmode.PrepareConfigurationBundle(namespace, randomString(), resources)
+		Expect(err).To(BeNil())
+		hash, err := pullmode.GetHash(resources)
+		Expect(err).To(BeNil())
+
+		bundles := []pullmode.BundleData{{Name: bundle.Name, Hash: hash}}
+		group, err := pullmode.PrepareConfigurationGroup(namespace, randomString(), bundles,
+			libsveltosv1beta1.ActionDeploy, setters...)
+		Expect(err).To(BeNil())
+
+		return group, []libsveltosv1beta1.ConfigurationBundle{*bundle}
+	}
+
+	It("VerifyConfigurationGroup succeeds for signed and unmodified content", func() {
+		group, bundles := prepareSignedGroup(pullmode.WithSigningKey(privateKey))
+		Expect(group.Spec.Signature).ToNot(BeEmpty())
+
+		Expect(pullmode.VerifyConfigurationGroup(group, bundles, publicKey)).To(Succeed())
+
+		// Fields defaulted by the API server or changing during the lifecycle are not signed
+		group.Spec.Tier = 100
+		group.Spec.SourceStatus = libsveltosv1beta1.SourceStatusActive
+		group.Spec.UpdatePhase = libsveltosv1beta1.UpdatePhasePreparing
+		Expect(pullmode.VerifyConfigurationGroup(group, bundles, publicKey)).To(Succeed())
+	})
+
+	It("VerifyConfigurationGroup fails for unsigned ConfigurationGroup", func() {
+		group, bundles := prepareSignedGroup()
+		Expect(group.Spec.Signature).To(BeEmpty())
+
+		err := pullmode.VerifyConfigurationGroup(group, bundles, publicKey)
+		Expect(pullmode.IsSignatureVerificationError(err)).To(BeTrue())
+	})
+
+	It("VerifyConfigurationGroup fails when ConfigurationGroup is modified or key does not match", func() {
+		group, bundles := prepareSignedGroup(pullmode.WithSigningKey(privateKey))
+
+		otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+		err = pullmode.VerifyConfigurationGroup(group, bundles, otherPublicKey)
+		Expect(pullmode.IsSignatureVerificationError(err)).To(BeTrue())
+
+		group.Spec.DryRun = true
+		err = pullmode.VerifyConfigurationGroup(group, bundles, publicKey)
+		Expect(pullmode.IsSignatureVerificationError(err)).To(BeTrue())
+		group.Spec.DryRun = false
+
+		group.Spec.Action = libsveltosv1beta1.ActionRemove
+		err = pullmode.VerifyConfigurationGroup(group, bundles, publicKey)
+		Expect(pullmode.IsSignatureVerificationError(err)).To(BeTrue())
+		group.Spec.Action = libsveltosv1beta1.ActionDeploy
+
+		group.Spec.ConfigurationItems[0].ContentRef.Name = randomString()
+		err = pullmode.VerifyConfigurationGroup(group, bundles, publicKey)
+		Expect(pullmode.IsSignatureVerificationError(err)).To(BeTrue())
+	})
+
+	It("VerifyConfigurationGroup fails when ConfigurationBundle content is modified", func() {
+		group, bundles := prepareSignedGroup(pullmode.WithSigningKey(privateKey))
+
+		bundles[0].Spec.Resources = bundles[0].Spec.Resources[1:]
+		err := pullmode.VerifyConfigurationGroup(group, bundles, publicKey)
+		Expect(pullmode.IsSignatureVerificationError(err)).To(BeTrue())
+	})
+
+	It("VerifyConfigurationGroup verifies compressed ConfigurationBundles", func() {
+		resources := getResources()
+		bundle, err := pullmode.PrepareConfigurationBundle(namespace, randomString(), resources,
+			pullmode.WithCompression())
+		Expect(err).To(BeNil())
+		hash, err := pullmode.GetHash(resources)
+		Expect(err).To(BeNil())
+
+		group, err := pullmode.PrepareConfigurationGroup(namespace, randomString(),
+			[]pullmode.BundleData{{Name: bundle.Name, Hash: hash}}, libsveltosv1beta1.ActionDeploy,
+			pullmode.WithSigningKey(privateKey))
+		Expect(err).To(BeNil())
+
+		Expect(pullmode.VerifyConfigurationGroup(group, []libsveltosv1beta1.ConfigurationBundle{*bundle},
+			publicKey)).To(Succeed())
+	})
+})
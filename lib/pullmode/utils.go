@@ -17,11 +17,19 @@ limitations under the License.
 package pullmode
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -44,12 +52,47 @@ const (
 
 	requestorNameAnnotationKey = "pullmode.projectsveltos.io/requestorname"
 
+	// audienceLabelKey is set on ConfigurationGroups addressed to a specific agent audience
+	audienceLabelKey = "pullmode.projectsveltos.io/audience"
+
 	indexAnnotationKey = "pullmode.projectsveltos.io/index"
+
+	// When the content for a single index is too big to fit in one ConfigurationBundle, content
+	// is split across multiple ConfigurationBundles. Each of those is annotated with its part number,
+	// total number of parts and the hash of the whole content so agent can reassemble it.
+	partAnnotationKey        = "pullmode.projectsveltos.io/part"
+	partsAnnotationKey       = "pullmode.projectsveltos.io/parts"
+	contentHashAnnotationKey = "pullmode.projectsveltos.io/contenthash"
+
+	// maxBundleSize is the maximum size (in bytes) of the serialized resources stored in a
+	// single ConfigurationBundle. It is kept well below the etcd object size limit (1.5MiB)
+	// to leave room for metadata and status.
+	maxBundleSize = 512 * 1024
 )
 
 type bundleData struct {
-	Name string
-	Hash []byte
+	// Namespace is set only when ConfigurationBundle is not in the cluster namespace (shared bundles)
+	Namespace string
+	Name      string
+	Hash      []byte
+	// Index and Part identify the content stored in the ConfigurationBundle, regardless of the order
+	// ConfigurationBundles are referenced in
+	Index string
+	Part  string
+}
+
+// getBundleData returns the bundleData for a ConfigurationBundle
+func getBundleData(bundle *libsveltosv1beta1.ConfigurationBundle, clusterNamespace string) bundleData {
+	data := bundleData{
+		Name:  bundle.Name,
+		Hash:  bundle.Status.Hash,
+		Index: bundle.Annotations[indexAnnotationKey],
+		Part:  bundle.Annotations[partAnnotationKey],
+	}
+	if bundle.Namespace != clusterNamespace {
+		data.Namespace = bundle.Namespace
+	}
+	return data
 }
 
 // In Sveltos pull mode, management cluster components define configurations to deploy in managed clusters.
@@ -85,6 +128,11 @@ func reconcileConfigurationBundle(ctx context.Context, c client.Client,
 	resources []unstructured.Unstructured, skipTracking, isStaged bool, logger logr.Logger,
 	setters ...BundleOption) (*libsveltosv1beta1.ConfigurationBundle, error) {
 
+	if options := getBundleOptions(setters...); options.sharedNamespace != "" {
+		return reconcileSharedConfigurationBundle(ctx, c, options.sharedNamespace, options.requestorKey,
+			index, resources, logger, setters...)
+	}
+
 	labels := getConfigurationBundleLabels(clusterName, requestorKind, requestorFeature)
 
 	name, currentBundle, err := getConfigurationBundleName(ctx, c, clusterNamespace, requestorName, index, labels)
@@ -110,6 +158,183 @@ func reconcileConfigurationBundle(ctx context.Context, c client.Client,
 		resources, skipTracking, isStaged, logger, setters...)
 }
 
+// reconcileConfigurationBundleParts splits resources in as many parts as needed for each part to
+// fit in a ConfigurationBundle (see maxBundleSize) and creates/updates one ConfigurationBundle per part.
+// Returned ConfigurationBundles are ordered by part number.
+func reconcileConfigurationBundleParts(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature, index string,
+	resources []unstructured.Unstructured, skipTracking, isStaged bool, logger logr.Logger,
+	setters ...BundleOption) ([]*libsveltosv1beta1.ConfigurationBundle, error) {
+
+	if getBundleOptions(setters...).EncryptSecrets {
+		key, err := getClusterEncryptionKey(ctx, c, clusterNamespace, clusterName)
+		if err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get encryption key: %v", err))
+			return nil, err
+		}
+		setters = append(slices.Clone(setters), withEncryptionKey(key))
+	}
+
+	parts, contentHash, err := splitResources(resources, maxBundleSize, getBundleOptions(setters...).encryptionKey)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to split resources: %v", err))
+		return nil, err
+	}
+
+	if len(parts) == 1 {
+		bundle, err := reconcileConfigurationBundle(ctx, c, clusterNamespace, clusterName, requestorKind,
+			requestorName, requestorFeature, index, resources, skipTracking, isStaged, logger, setters...)
+		if err != nil {
+			return nil, err
+		}
+		return []*libsveltosv1beta1.ConfigurationBundle{bundle}, nil
+	}
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("content for index %s split in %d ConfigurationBundles",
+		index, len(parts)))
+
+	bundles := make([]*libsveltosv1beta1.ConfigurationBundle, len(parts))
+	for i := range parts {
+		partSetters := append([]BundleOption{withPart(i, len(parts), contentHash)}, setters...)
+		bundles[i], err = reconcileConfigurationBundle(ctx, c, clusterNamespace, clusterName, requestorKind,
+			requestorName, requestorFeature, getPartIndex(index, i), parts[i], skipTracking, isStaged, logger,
+			partSetters...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bundles, nil
+}
+
+// getPartIndex returns the index used for the ConfigurationBundle containing the given part.
+// First part keeps the original index.
+func getPartIndex(index string, part int) string {
+	if part == 0 {
+		return index
+	}
+	return fmt.Sprintf("%s-part-%d", index, part)
+}
+
+// splitResources splits resources, preserving their order, in parts whose stored size does
+// not exceed maxSize. When encryptionKey is set, Secrets are sized once encrypted, as this is
+// how they are stored in a ConfigurationBundle. A resource bigger than maxSize gets a part on its own.
+// It also returns the hash of the whole serialized (not encrypted) content (see getContentHash).
+func splitResources(resources []unstructured.Unstructured, maxSize int, encryptionKey []byte,
+) (parts [][]unstructured.Unstructured, contentHash string, err error) {
+
+	content := make([]string, len(resources))
+	parts = make([][]unstructured.Unstructured, 0)
+	current := make([]unstructured.Unstructured, 0)
+	currentSize := 0
+	for i := range resources {
+		content[i], err = serializeResource(&resources[i])
+		if err != nil {
+			return nil, "", err
+		}
+
+		size, err := getStoredSize(&resources[i], content[i], encryptionKey)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if len(current) > 0 && currentSize+size > maxSize {
+			parts = append(parts, current)
+			current = make([]unstructured.Unstructured, 0)
+			currentSize = 0
+		}
+		current = append(current, resources[i])
+		currentSize += size
+	}
+	parts = append(parts, current)
+
+	return parts, getContentHash(content), nil
+}
+
+// getStoredSize returns the size content of resource takes once stored in a ConfigurationBundle.
+func getStoredSize(resource *unstructured.Unstructured, content string, encryptionKey []byte) (int, error) {
+	if encryptionKey == nil || !isSecret(resource) {
+		return len(content), nil
+	}
+
+	// Encrypted content size only depends on plaintext size
+	encrypted, err := encryptResource(content, encryptionKey)
+	if err != nil {
+		return 0, err
+	}
+	return len(encrypted), nil
+}
+
+// getContentHash returns the hex encoded sha256 of the serialized resources
+func getContentHash(content []string) string {
+	hasher := sha256.New()
+	for i := range content {
+		hasher.Write([]byte(content[i]))
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// serializeResource returns the YAML representation of a resource as stored in a ConfigurationBundle
+func serializeResource(resource *unstructured.Unstructured) (string, error) {
+	// Reset managed fields
+	resource.SetManagedFields(nil)
+	// Reset resourceVersion
+	resource.SetResourceVersion("")
+	resource.SetUID("")
+	data, err := yaml.Marshal(resource.UnstructuredContent())
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func getConfigurationBundleAnnotations(requestorName, index string, setters ...BundleOption) map[string]string {
+	annotations := map[string]string{
+		requestorNameAnnotationKey: requestorName,
+		indexAnnotationKey:         index,
+	}
+
+	c := getBundleOptions(setters...)
+	if c.parts > 1 {
+		annotations[partAnnotationKey] = strconv.Itoa(c.part)
+		annotations[partsAnnotationKey] = strconv.Itoa(c.parts)
+		annotations[contentHashAnnotationKey] = c.contentHash
+	}
+
+	if c.stagingSession != "" {
+		annotations[stagingSessionAnnotationKey] = c.stagingSession
+		annotations[stagingOrderAnnotationKey] = strconv.Itoa(c.stagingOrder)
+		annotations[stagingTimeAnnotationKey] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	return annotations
+}
+
+// getBundlePart returns the part number, the total number of parts and the content hash
+// of a ConfigurationBundle. A ConfigurationBundle containing the whole content is part 0 of 1.
+func getBundlePart(bundle *libsveltosv1beta1.ConfigurationBundle) (part, parts int, contentHash string, err error) {
+	value, ok := bundle.Annotations[partsAnnotationKey]
+	if !ok {
+		return 0, 1, "", nil
+	}
+
+	parts, err = strconv.Atoi(value)
+	if err != nil || parts < 1 {
+		return 0, 0, "", fmt.Errorf("ConfigurationBundle %s/%s: invalid %s annotation %q",
+			bundle.Namespace, bundle.Name, partsAnnotationKey, value)
+	}
+
+	value = bundle.Annotations[partAnnotationKey]
+	part, err = strconv.Atoi(value)
+	if err != nil || part < 0 || part >= parts {
+		return 0, 0, "", fmt.Errorf("ConfigurationBundle %s/%s: invalid %s annotation %q",
+			bundle.Namespace, bundle.Name, partAnnotationKey, value)
+	}
+
+	return part, parts, bundle.Annotations[contentHashAnnotationKey], nil
+}
+
 func prepareConfigurationBundle(namespace, name string, resources []unstructured.Unstructured,
 	setters ...BundleOption) (*libsveltosv1beta1.ConfigurationBundle, error) {
 
@@ -123,25 +348,87 @@ func prepareConfigurationBundle(namespace, name string, resources []unstructured
 	content := make([]string, len(resources))
 
 	for i := range resources {
-		// Reset managed fields
-		resources[i].SetManagedFields(nil)
-		// Reset resourceVersion
-		resources[i].SetResourceVersion("")
-		resources[i].SetUID("")
-		data, err := yaml.Marshal(resources[i].UnstructuredContent())
+		data, err := serializeResource(&resources[i])
 		if err != nil {
 			return nil, err
 		}
 
-		content[i] = string(data)
+		content[i] = data
 	}
 
 	confBundle.Spec.Resources = content
 	confBundle = applyBundleSetters(confBundle, setters...)
 
+	if key := getBundleOptions(setters...).encryptionKey; key != nil {
+		for i := range resources {
+			if !isSecret(&resources[i]) {
+				continue
+			}
+			encrypted, err := encryptResource(content[i], key)
+			if err != nil {
+				return nil, err
+			}
+			content[i] = encrypted
+			confBundle.Spec.EncryptedResources = append(confBundle.Spec.EncryptedResources, i)
+		}
+	}
+
+	if confBundle.Spec.Encoding == libsveltosv1beta1.BundleEncodingGzipBase64 {
+		encoded, err := encodeResources(content)
+		if err != nil {
+			return nil, err
+		}
+		confBundle.Spec.Resources = []string{encoded}
+	}
+
 	return confBundle, nil
 }
 
+// encodeResources returns the JSON list of content, gzip compressed and base64 encoded.
+func encodeResources(content []string) (string, error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeResources reverts encodeResources.
+func decodeResources(encoded string) ([]string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64 decode content: %w", err)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress content: %w", err)
+	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress content: %w", err)
+	}
+
+	var content []string
+	if err := json.Unmarshal(decompressed, &content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal content: %w", err)
+	}
+
+	return content, nil
+}
+
 func createConfigurationBundle(ctx context.Context, c client.Client, namespace, name, requestorName, index string,
 	resources []unstructured.Unstructured, labels client.MatchingLabels, skipTracking, isStaged bool,
 	logger logr.Logger, setters ...BundleOption) (*libsveltosv1beta1.ConfigurationBundle, error) {
@@ -152,10 +439,7 @@ func createConfigurationBundle(ctx context.Context, c client.Client, namespace,
 		return nil, err
 	}
 
-	bundle.Annotations = map[string]string{
-		requestorNameAnnotationKey: requestorName,
-		indexAnnotationKey:         index,
-	}
+	bundle.Annotations = getConfigurationBundleAnnotations(requestorName, index, setters...)
 	bundle.Labels = labels
 	bundle.Spec.NotTracked = skipTracking
 
@@ -197,10 +481,7 @@ func updateConfigurationBundle(ctx context.Context, c client.Client, namespace,
 		return nil, err
 	}
 
-	currentBundle.Annotations = map[string]string{
-		requestorNameAnnotationKey: requestorName,
-		indexAnnotationKey:         index,
-	}
+	currentBundle.Annotations = getConfigurationBundleAnnotations(requestorName, index, setters...)
 
 	currentBundle.Spec = bundle.Spec
 	currentBundle.Spec.NotTracked = skipTracking
@@ -298,12 +579,42 @@ func markConfigurationGroupAsPreparing(ctx context.Context, c client.Client,
 	if currentCG != nil {
 		cg := currentCG.(*libsveltosv1beta1.ConfigurationGroup)
 		cg.Spec.UpdatePhase = libsveltosv1beta1.UpdatePhasePreparing
+		if cg.Annotations == nil {
+			cg.Annotations = map[string]string{}
+		}
+		cg.Annotations[stagingTimeAnnotationKey] = time.Now().UTC().Format(time.RFC3339)
 		return c.Update(ctx, cg)
 	}
 
 	return nil
 }
 
+// markConfigurationGroupAsReady moves a ConfigurationGroup in the Preparing phase back to Ready.
+func markConfigurationGroupAsReady(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
+	logger logr.Logger) error {
+
+	labels := getConfigurationGroupLabels(clusterName, requestorKind, requestorFeature)
+	_, currentCG, err := getConfigurationGroupName(ctx, c, clusterNamespace, requestorName, labels)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get ConfigurationGroup name: %v", err))
+		return err
+	}
+
+	if currentCG == nil {
+		return nil
+	}
+
+	cg := currentCG.(*libsveltosv1beta1.ConfigurationGroup)
+	if cg.Spec.UpdatePhase != libsveltosv1beta1.UpdatePhasePreparing {
+		return nil
+	}
+
+	cg.Spec.UpdatePhase = libsveltosv1beta1.UpdatePhaseReady
+	delete(cg.Annotations, stagingTimeAnnotationKey)
+	return c.Update(ctx, cg)
+}
+
 func reconcileConfigurationGroup(ctx context.Context, c client.Client,
 	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
 	bundles []bundleData, logger logr.Logger, setters ...Option) error {
@@ -381,7 +692,7 @@ func deleteConfigurationGroup(ctx context.Context, c client.Client,
 }
 
 func prepareConfigurationGroup(namespace, name string, bundles []bundleData,
-	action libsveltosv1beta1.Action, setters ...Option) *libsveltosv1beta1.ConfigurationGroup {
+	action libsveltosv1beta1.Action, setters ...Option) (*libsveltosv1beta1.ConfigurationGroup, error) {
 
 	confGroup := &libsveltosv1beta1.ConfigurationGroup{
 		ObjectMeta: metav1.ObjectMeta{
@@ -394,12 +705,16 @@ func prepareConfigurationGroup(namespace, name string, bundles []bundleData,
 	confGroup.Spec.UpdatePhase = libsveltosv1beta1.UpdatePhaseReady
 	confGroup.Spec.ConfigurationItems = make([]libsveltosv1beta1.ConfigurationItem, len(bundles))
 	for i := range bundles {
+		bundleNamespace := namespace
+		if bundles[i].Namespace != "" {
+			bundleNamespace = bundles[i].Namespace
+		}
 		confGroup.Spec.ConfigurationItems[i] = libsveltosv1beta1.ConfigurationItem{
 			ContentRef: &corev1.ObjectReference{
 				APIVersion: libsveltosv1beta1.GroupVersion.String(),
 				Kind:       libsveltosv1beta1.ConfigurationBundleKind,
 				Name:       bundles[i].Name,
-				Namespace:  namespace,
+				Namespace:  bundleNamespace,
 			},
 			Hash: bundles[i].Hash,
 		}
@@ -408,15 +723,32 @@ func prepareConfigurationGroup(namespace, name string, bundles []bundleData,
 	confGroup.Spec.Action = action
 
 	confGroup = applySetters(confGroup, setters...)
-	return confGroup
+
+	c := getOptions(setters...)
+	if c.SigningKey != nil {
+		if err := signConfigurationGroup(confGroup, c.SigningKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return confGroup, nil
 }
 
 func createConfigurationGroup(ctx context.Context, c client.Client, namespace, name, requestorName string,
 	bundles []bundleData, labels client.MatchingLabels, action libsveltosv1beta1.Action, setters ...Option) error {
 
-	group := prepareConfigurationGroup(namespace, name, bundles, action, setters...)
+	group, err := prepareConfigurationGroup(namespace, name, bundles, action, setters...)
+	if err != nil {
+		return err
+	}
 
-	group.Labels = labels
+	group.Labels = make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		group.Labels[k] = v
+	}
+	if audience := getConfigurationGroupAudience(setters...); audience != "" {
+		group.Labels[audienceLabelKey] = audience
+	}
 	group.Annotations = map[string]string{
 		requestorNameAnnotationKey: requestorName,
 	}
@@ -427,19 +759,50 @@ func createConfigurationGroup(ctx context.Context, c client.Client, namespace, n
 func updateConfigurationGroup(ctx context.Context, c client.Client, namespace, name string,
 	bundles []bundleData, action libsveltosv1beta1.Action, logger logr.Logger, setters ...Option) error {
 
-	group := prepareConfigurationGroup(namespace, name, bundles, action, setters...)
+	group, err := prepareConfigurationGroup(namespace, name, bundles, action, setters...)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to prepare configurationGroup: %v", err))
+		return err
+	}
 
 	currentGroup := &libsveltosv1beta1.ConfigurationGroup{}
-	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, currentGroup)
+	err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, currentGroup)
 	if err != nil {
 		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get configurationGroup: %v", err))
 		return err
 	}
 
 	currentGroup.Spec = group.Spec
+
+	// A ConfigurationGroup marked for removal stays addressed to the same audience, so the agent which
+	// deployed the content is the one removing it.
+	audience := getConfigurationGroupAudience(setters...)
+	switch {
+	case audience != "":
+		if currentGroup.Labels == nil {
+			currentGroup.Labels = map[string]string{}
+		}
+		currentGroup.Labels[audienceLabelKey] = audience
+	case action != libsveltosv1beta1.ActionRemove:
+		delete(currentGroup.Labels, audienceLabelKey)
+	}
+
 	return c.Update(ctx, currentGroup)
 }
 
+// getConfigurationGroupAudience returns the audience the ConfigurationGroup is addressed to.
+// Audience is either explicitly set or derived from the ServiceAccount used to deploy the content.
+func getConfigurationGroupAudience(setters ...Option) string {
+	c := getOptions(setters...)
+	if c.Audience != "" {
+		return c.Audience
+	}
+	if c.ServiceAccount.Name != "" {
+		return GetServiceAccountAudience(c.ServiceAccount.Namespace, c.ServiceAccount.Name)
+	}
+	return ""
+}
+
 func deleteStaleConfigurationBundles(ctx context.Context, c client.Client,
 	clusterNamespace, clusterName, requestorKind, requestorName, requestorFeature string,
 	referencedBundles []bundleData, logger logr.Logger) error {
@@ -491,7 +854,11 @@ func getReferencedConfigurationBundles(ctx context.Context, c client.Client,
 		currentBundles = make([]bundleData, len(currentCG.Spec.ConfigurationItems))
 
 		for i := range currentCG.Spec.ConfigurationItems {
-			currentBundles[i] = bundleData{Name: currentCG.Spec.ConfigurationItems[i].ContentRef.Name}
+			contentRef := currentCG.Spec.ConfigurationItems[i].ContentRef
+			currentBundles[i] = bundleData{Name: contentRef.Name}
+			if contentRef.Namespace != clusterNamespace {
+				currentBundles[i].Namespace = contentRef.Namespace
+			}
 		}
 	}
 
@@ -405,8 +405,168 @@ var _ = Describe("applyBundleSetters", func() {
 
 		Expect(bundle.Spec.Force).To(BeFalse())
 	})
+
+	It("WithCompression sets GzipBase64 encoding", func() {
+		bundle := &libsveltosv1beta1.ConfigurationBundle{}
+
+		pullmode.ApplyBundleSetters(bundle, pullmode.WithCompression())
+
+		Expect(bundle.Spec.Encoding).To(Equal(libsveltosv1beta1.BundleEncodingGzipBase64))
+	})
+})
+
+var _ = Describe("ConfigurationBundle encoding", func() {
+	It("prepareConfigurationBundle stores resources uncompressed by default", func() {
+		resources := getResources()
+
+		bundle, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(), resources)
+		Expect(err).To(BeNil())
+		Expect(bundle.Spec.Encoding).To(BeEmpty())
+		Expect(len(bundle.Spec.Resources)).To(Equal(len(resources)))
+
+		content, err := pullmode.GetConfigurationBundleResources(bundle)
+		Expect(err).To(BeNil())
+		Expect(content).To(Equal(bundle.Spec.Resources))
+	})
+
+	It("prepareConfigurationBundle with WithCompression stores compressed resources", func() {
+		resources := getResources()
+
+		plain, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(), resources)
+		Expect(err).To(BeNil())
+
+		bundle, err := pullmode.PrepareConfigurationBundle(randomString(), randomString(), resources,
+			pullmode.WithCompression())
+		Expect(err).To(BeNil())
+		Expect(bundle.Spec.Encoding).To(Equal(libsveltosv1beta1.BundleEncodingGzipBase64))
+		Expect(len(bundle.Spec.Resources)).To(Equal(1))
+
+		content, err := pullmode.GetConfigurationBundleResources(bundle)
+		Expect(err).To(BeNil())
+		Expect(content).To(Equal(plain.Spec.Resources))
+	})
+
+	It("GetConfigurationBundleResources returns an error for invalid content", func() {
+		bundle := &libsveltosv1beta1.ConfigurationBundle{
+			Spec: libsveltosv1beta1.ConfigurationBundleSpec{
+				Resources: []string{randomString()},
+				Encoding:  libsveltosv1beta1.BundleEncodingGzipBase64,
+			},
+		}
+
+		_, err := pullmode.GetConfigurationBundleResources(bundle)
+		Expect(err).ToNot(BeNil())
+
+		bundle.Spec.Encoding = libsveltosv1beta1.BundleEncoding(randomString())
+		_, err = pullmode.GetConfigurationBundleResources(bundle)
+		Expect(err).ToNot(BeNil())
+	})
+})
+
+var _ = Describe("ConfigurationBundle splitting", func() {
+	It("splitResources keeps content in a single part when it fits", func() {
+		resources := getResources()
+
+		parts, contentHash, err := pullmode.SplitResources(resources, 1024*1024, nil)
+		Expect(err).To(BeNil())
+		Expect(len(parts)).To(Equal(1))
+		Expect(parts[0]).To(Equal(resources))
+		Expect(contentHash).ToNot(BeEmpty())
+	})
+
+	It("splitResources splits content preserving order", func() {
+		resources := getResources()
+
+		// Each resource bigger than max size ends up in its own part
+		parts, contentHash, err := pullmode.SplitResources(resources, 1, nil)
+		Expect(err).To(BeNil())
+		Expect(len(parts)).To(Equal(len(resources)))
+		for i := range parts {
+			Expect(len(parts[i])).To(Equal(1))
+			Expect(parts[i][0].GetName()).To(Equal(resources[i].GetName()))
+			Expect(parts[i][0].GetKind()).To(Equal(resources[i].GetKind()))
+		}
+
+		_, currentHash, err := pullmode.SplitResources(resources, 1024*1024, nil)
+		Expect(err).To(BeNil())
+		Expect(currentHash).To(Equal(contentHash))
+	})
+
+	It("AssembleConfigurationBundles reassembles split content", func() {
+		resources := getResources()
+		namespace := randomString()
+		index := randomString()
+
+		parts, contentHash, err := pullmode.SplitResources(resources, 1, nil)
+		Expect(err).To(BeNil())
+
+		bundles := getSplitBundles(namespace, index, parts, contentHash)
+
+		single, err := pullmode.PrepareConfigurationBundle(namespace, randomString(), resources)
+		Expect(err).To(BeNil())
+		singleIndex := randomString()
+		single.Annotations = pullmode.GetConfigurationBundleAnnotations(randomString(), singleIndex)
+
+		content, err := pullmode.AssembleConfigurationBundles(append([]libsveltosv1beta1.ConfigurationBundle{*single},
+			bundles...))
+		Expect(err).To(BeNil())
+		Expect(len(content)).To(Equal(2))
+		Expect(content[0].Index).To(Equal(singleIndex))
+		Expect(content[0].BundleNames).To(Equal([]string{single.Name}))
+		Expect(content[0].Resources).To(Equal(single.Spec.Resources))
+		Expect(content[1].Index).To(Equal(index))
+		Expect(len(content[1].BundleNames)).To(Equal(len(bundles)))
+		Expect(content[1].Resources).To(Equal(single.Spec.Resources))
+	})
+
+	It("AssembleConfigurationBundles fails when parts are missing or out of order", func() {
+		resources := getResources()
+		namespace := randomString()
+
+		parts, contentHash, err := pullmode.SplitResources(resources, 1, nil)
+		Expect(err).To(BeNil())
+
+		bundles := getSplitBundles(namespace, randomString(), parts, contentHash)
+
+		_, err = pullmode.AssembleConfigurationBundles(bundles[:len(bundles)-1])
+		Expect(err).ToNot(BeNil())
+
+		_, err = pullmode.AssembleConfigurationBundles(
+			[]libsveltosv1beta1.ConfigurationBundle{bundles[1], bundles[0], bundles[2]})
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("AssembleConfigurationBundles fails on hash mismatch", func() {
+		resources := getResources()
+
+		parts, _, err := pullmode.SplitResources(resources, 1, nil)
+		Expect(err).To(BeNil())
+
+		bundles := getSplitBundles(randomString(), randomString(), parts, randomString())
+
+		_, err = pullmode.AssembleConfigurationBundles(bundles)
+		Expect(err).ToNot(BeNil())
+	})
 })
 
+// getSplitBundles returns the ConfigurationBundles containing the given parts
+func getSplitBundles(namespace, index string, parts [][]unstructured.Unstructured, contentHash string,
+) []libsveltosv1beta1.ConfigurationBundle {
+
+	requestorName := randomString()
+	bundles := make([]libsveltosv1beta1.ConfigurationBundle, len(parts))
+	for i := range parts {
+		setter := pullmode.WithPart(i, len(parts), contentHash)
+		bundle, err := pullmode.PrepareConfigurationBundle(namespace, randomString(), parts[i], setter)
+		Expect(err).To(BeNil())
+		bundle.Annotations = pullmode.GetConfigurationBundleAnnotations(requestorName,
+			pullmode.GetPartIndex(index, i), setter)
+		bundles[i] = *bundle
+	}
+
+	return bundles
+}
+
 func getResources() []unstructured.Unstructured {
 	namespace := `  apiVersion: v1
   kind: Namespace